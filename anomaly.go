@@ -0,0 +1,126 @@
+package validator
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+)
+
+const (
+	// DefaultAnomalyMinTextSize is the size from which CheckAnomaly
+	// measures the entropy of character data unless configured otherwise
+	DefaultAnomalyMinTextSize = 4096
+	// DefaultAnomalyMaxEntropy is the entropy above which CheckAnomaly
+	// reports large character data unless configured otherwise; base64
+	// encoded binary data has an entropy close to 6 bits per byte, and
+	// prose and most markup payloads stay well under 5
+	DefaultAnomalyMaxEntropy = 5.5
+	// DefaultAnomalyMinDocumentSize is the size from which CheckAnomaly
+	// monitors the share of character data unless configured otherwise
+	DefaultAnomalyMinDocumentSize = 64 * 1024
+	// DefaultAnomalyMaxTextRatio is the share of character data above
+	// which CheckAnomaly reports documents unless configured otherwise
+	DefaultAnomalyMaxTextRatio = 0.95
+)
+
+// AnomalyConfig configures CheckAnomaly; zero values use the defaults, so
+// profiles only need to set the thresholds they tune
+type AnomalyConfig struct {
+	CheckConfig
+	// MinTextSize is the size in bytes, as written in the document, from
+	// which runs of character data have their entropy measured; zero or
+	// less uses DefaultAnomalyMinTextSize
+	MinTextSize int
+	// MaxEntropy is the Shannon entropy, in bits per byte, above which
+	// large runs of character data are reported; zero or less uses
+	// DefaultAnomalyMaxEntropy
+	MaxEntropy float64
+	// MinDocumentSize is the number of bytes to read before the share of
+	// character data in the document is monitored; zero or less uses
+	// DefaultAnomalyMinDocumentSize
+	MinDocumentSize int
+	// MaxTextRatio is the share of the bytes read, between 0 and 1, that
+	// character data may make up; zero or less uses
+	// DefaultAnomalyMaxTextRatio
+	MaxTextRatio float64
+}
+
+// WithAnomalyCheck enables and configures CheckAnomaly
+func WithAnomalyCheck(cfg AnomalyConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckAnomaly] = cfg.CheckConfig
+		v.anomaly = cfg
+	}
+}
+
+// newAnomalyCheck creates the per-document state of CheckAnomaly. Runs of
+// character data are measured as a whole, so splitting a blob into CDATA
+// sections doesn't hide it; each run and the document's share of
+// character data are reported at most once.
+func newAnomalyCheck(v *Validator) tokenCheck {
+	cfg := v.anomaly
+	if cfg.MinTextSize <= 0 {
+		cfg.MinTextSize = DefaultAnomalyMinTextSize
+	}
+	if cfg.MaxEntropy <= 0 {
+		cfg.MaxEntropy = DefaultAnomalyMaxEntropy
+	}
+	if cfg.MinDocumentSize <= 0 {
+		cfg.MinDocumentSize = DefaultAnomalyMinDocumentSize
+	}
+	if cfg.MaxTextRatio <= 0 {
+		cfg.MaxTextRatio = DefaultAnomalyMaxTextRatio
+	}
+	// counts holds the number of occurrences of every byte in the current
+	// run of character data, which spans runSize bytes of the document
+	var counts [256]int
+	runSize, runReported := 0, false
+	textSize, ratioReported := 0, false
+	return func(d *document, token xml.Token) error {
+		data, ok := token.(xml.CharData)
+		if !ok {
+			counts = [256]int{}
+			runSize, runReported = 0, false
+			return nil
+		}
+		size := len(d.raw())
+		runSize += size
+		textSize += size
+		for _, b := range data {
+			counts[b]++
+		}
+		read := d.decoder.InputOffset()
+		if !ratioReported && read >= int64(cfg.MinDocumentSize) {
+			if ratio := float64(textSize) / float64(read); ratio > cfg.MaxTextRatio {
+				ratioReported = true
+				return fmt.Errorf("character data makes up %.1f%% of the first %d bytes, more than %.1f%%",
+					100*ratio, read, 100*cfg.MaxTextRatio)
+			}
+		}
+		if !runReported && runSize >= cfg.MinTextSize {
+			if entropy := byteEntropy(&counts); entropy > cfg.MaxEntropy {
+				runReported = true
+				return fmt.Errorf("character data of %d bytes has an entropy of %.2f bits per byte, more than %.2f",
+					runSize, entropy, cfg.MaxEntropy)
+			}
+		}
+		return nil
+	}
+}
+
+// byteEntropy returns the Shannon entropy, in bits per byte, of data with
+// the given byte counts
+func byteEntropy(counts *[256]int) float64 {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	entropy := 0.0
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
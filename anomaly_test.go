@@ -0,0 +1,51 @@
+package validator
+
+import (
+	"encoding/base64"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnomaly(t *testing.T) {
+	random := make([]byte, 6000)
+	rand.New(rand.NewSource(1)).Read(random)
+	blob := base64.StdEncoding.EncodeToString(random)
+	prose := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 200)
+	v := New(WithAnomalyCheck(AnomalyConfig{}))
+
+	errs := v.ValidateAll(strings.NewReader(`<Root><Data>` + blob + `</Data></Root>`))
+	require.Len(t, errs, 1, "Should report large character data with high entropy")
+	require.Equal(t, CheckAnomaly, errs[0].(XMLValidationError).Check)
+	require.Equal(t, SeverityWarning, errs[0].(XMLValidationError).Severity)
+	require.Contains(t, errs[0].Error(), "bits per byte")
+
+	sections := &strings.Builder{}
+	for i := 0; i < len(blob); i += 1000 {
+		end := i + 1000
+		if end > len(blob) {
+			end = len(blob)
+		}
+		sections.WriteString("<![CDATA[" + blob[i:end] + "]]>")
+	}
+	errs = v.ValidateAll(strings.NewReader(`<Root><Data>` + sections.String() + `</Data></Root>`))
+	require.Len(t, errs, 1, "Should measure runs of character data split into sections as a whole, once")
+
+	require.Empty(t, v.ValidateAll(strings.NewReader(`<Root><Data>`+prose+`</Data></Root>`)), "Should let prose through")
+	require.Empty(t, v.ValidateAll(strings.NewReader(`<Root><Data>`+blob[:2000]+`</Data><Data>`+blob[2000:4000]+`</Data></Root>`)),
+		"Should let small runs of character data through")
+	require.Empty(t, New(WithAnomalyCheck(AnomalyConfig{MaxEntropy: 6.5})).ValidateAll(strings.NewReader(`<Root>`+blob+`</Root>`)),
+		"Should use the configured entropy threshold")
+
+	text := strings.Repeat("a", DefaultAnomalyMinDocumentSize)
+	errs = v.ValidateAll(strings.NewReader(`<Root>` + text + `</Root>`))
+	require.Len(t, errs, 1, "Should report documents made up of character data")
+	require.Contains(t, errs[0].Error(), "character data makes up")
+
+	markup := strings.Repeat(`<Item kind="x">a</Item>`, DefaultAnomalyMinDocumentSize/10)
+	require.Empty(t, v.ValidateAll(strings.NewReader(`<Root>`+markup+`</Root>`)), "Should let markup through")
+	require.Empty(t, New(WithAnomalyCheck(AnomalyConfig{MinDocumentSize: 1 << 30})).ValidateAll(strings.NewReader(`<Root>`+text+`</Root>`)),
+		"Should use the configured document size")
+}
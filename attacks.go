@@ -0,0 +1,159 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AttackPattern describes a published attack against XML processing that
+// CheckKnownAttacks detects
+type AttackPattern struct {
+	// ID is a stable identifier, e.g. a CVE ID
+	ID string
+	// Name is a short human-readable description of the pattern
+	Name string
+	// References links to descriptions of the attack
+	References []string
+}
+
+// Known attack patterns detected by CheckKnownAttacks
+var (
+	PatternUnstableAttributes = AttackPattern{
+		ID:   "CVE-2020-29509",
+		Name: "attribute namespace prefix instability",
+		References: []string{
+			"https://nvd.nist.gov/vuln/detail/CVE-2020-29509",
+			"https://github.com/mattermost/xml-roundtrip-validator/blob/master/advisories/unstable-attributes.md",
+		},
+	}
+	PatternUnstableDirectives = AttackPattern{
+		ID:   "CVE-2020-29510",
+		Name: "directive comment instability",
+		References: []string{
+			"https://nvd.nist.gov/vuln/detail/CVE-2020-29510",
+			"https://github.com/mattermost/xml-roundtrip-validator/blob/master/advisories/unstable-directives.md",
+		},
+	}
+	PatternUnstableElements = AttackPattern{
+		ID:   "CVE-2020-29511",
+		Name: "element namespace prefix instability",
+		References: []string{
+			"https://nvd.nist.gov/vuln/detail/CVE-2020-29511",
+			"https://github.com/mattermost/xml-roundtrip-validator/blob/master/advisories/unstable-elements.md",
+		},
+	}
+	PatternExternalEntity = AttackPattern{
+		ID:   "XXE-EXTERNAL-ENTITY",
+		Name: "external entity declaration",
+		References: []string{
+			"https://owasp.org/www-community/vulnerabilities/XML_External_Entity_(XXE)_Processing",
+		},
+	}
+	PatternExternalDTD = AttackPattern{
+		ID:   "XXE-EXTERNAL-DTD",
+		Name: "external document type definition",
+		References: []string{
+			"https://owasp.org/www-community/vulnerabilities/XML_External_Entity_(XXE)_Processing",
+		},
+	}
+	PatternDuplicateID = AttackPattern{
+		ID:   "XSW-DUPLICATE-ID",
+		Name: "XML signature wrapping through duplicate IDs",
+		References: []string{
+			"https://www.usenix.org/conference/usenixsecurity12/technical-sessions/presentation/somorovsky",
+		},
+	}
+)
+
+// KnownAttackPatterns returns every pattern detected by CheckKnownAttacks
+func KnownAttackPatterns() []AttackPattern {
+	return []AttackPattern{
+		PatternUnstableAttributes,
+		PatternUnstableDirectives,
+		PatternUnstableElements,
+		PatternExternalEntity,
+		PatternExternalDTD,
+		PatternDuplicateID,
+	}
+}
+
+// XMLAttackPatternError is returned when a token matches a known attack pattern
+type XMLAttackPatternError struct {
+	Pattern AttackPattern
+	// Detail describes the part of the token that matched
+	Detail string
+}
+
+func (err XMLAttackPatternError) Error() string {
+	return fmt.Sprintf("matches known attack pattern %s (%s): %s", err.Pattern.ID, err.Pattern.Name, err.Detail)
+}
+
+var (
+	externalEntityPattern = regexp.MustCompile(`<!ENTITY\s+(%\s+)?[^\s>]+\s+(SYSTEM|PUBLIC)\s`)
+	externalDTDPattern    = regexp.MustCompile(`^DOCTYPE\s+[^\s\[>]+\s+(SYSTEM|PUBLIC)\s`)
+	commentPattern        = regexp.MustCompile(`(?s)<!--.*?-->`)
+)
+
+// idAttributes lists the attribute names signature implementations
+// commonly use to reference elements
+var idAttributes = map[string]bool{
+	"ID":          true,
+	"Id":          true,
+	"id":          true,
+	"AssertionID": true,
+	"ResponseID":  true,
+}
+
+// newKnownAttacksCheck creates the per-document state of CheckKnownAttacks
+func newKnownAttacksCheck(v *Validator) tokenCheck {
+	ids := map[string]bool{}
+	return func(d *document, token xml.Token) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			if unstableName(t.Name) {
+				return XMLAttackPatternError{PatternUnstableElements, fmt.Sprintf("element name %q", qualifiedName(t.Name))}
+			}
+			for _, attr := range t.Attr {
+				if unstableName(attr.Name) {
+					return XMLAttackPatternError{PatternUnstableAttributes, fmt.Sprintf("attribute name %q", qualifiedName(attr.Name))}
+				}
+			}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "" && idAttributes[attr.Name.Local] {
+					if ids[attr.Value] {
+						return XMLAttackPatternError{PatternDuplicateID, fmt.Sprintf("%s %q is used more than once", attr.Name.Local, d.redact(attr.Value))}
+					}
+					ids[attr.Value] = true
+				}
+			}
+		case xml.EndElement:
+			if unstableName(t.Name) {
+				return XMLAttackPatternError{PatternUnstableElements, fmt.Sprintf("element name %q", qualifiedName(t.Name))}
+			}
+		case xml.Directive:
+			// comments are dropped from directives when tokenizing, so
+			// look at the raw bytes instead
+			raw := d.raw()
+			stripped := commentPattern.ReplaceAll(raw[2:len(raw)-1], nil)
+			if bytes.Contains(stripped, []byte("<!--")) || bytes.Contains(stripped, []byte("-->")) {
+				return XMLAttackPatternError{PatternUnstableDirectives, "removing comments from the directive creates new comment delimiters"}
+			}
+			if match := externalEntityPattern.Find(t); match != nil {
+				return XMLAttackPatternError{PatternExternalEntity, fmt.Sprintf("declaration %q", match)}
+			}
+			if match := externalDTDPattern.Find(t); match != nil {
+				return XMLAttackPatternError{PatternExternalDTD, fmt.Sprintf("declaration %q", match)}
+			}
+		}
+		return nil
+	}
+}
+
+// unstableName reports whether a name has the shape of the namespace prefix
+// instability attacks: colons in the local name, or an empty prefix or local name
+func unstableName(name xml.Name) bool {
+	return name.Local == "" || strings.Contains(name.Local, ":") || strings.Contains(name.Space, ":")
+}
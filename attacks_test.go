@@ -0,0 +1,53 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireAttackPattern validates the document and requires a finding from
+// CheckKnownAttacks matching the given pattern
+func requireAttackPattern(t *testing.T, doc string, pattern AttackPattern) {
+	t.Helper()
+	for _, err := range New().ValidateAll(strings.NewReader(doc)) {
+		attackError := XMLAttackPatternError{}
+		if errors.As(err, &attackError) && attackError.Pattern.ID == pattern.ID {
+			require.Equal(t, CheckKnownAttacks, err.(XMLValidationError).Check, "Finding should be reported by the known attacks check")
+			require.Equal(t, SeverityWarning, SeverityOf(err), "Known attack patterns should be warnings by default")
+			return
+		}
+	}
+	require.Fail(t, "Should detect known attack pattern", "%s in %s", pattern.ID, doc)
+}
+
+func TestKnownAttacks(t *testing.T) {
+	if el := tokenize(t, `<Root :="value"/>`).(xml.StartElement); el.Attr[0].Name.Local == `:` {
+		// go1.17+ tokenizes these names without mutating them
+		requireAttackPattern(t, `<Root :="value"/>`, PatternUnstableAttributes)
+		requireAttackPattern(t, `<Root x:="value"/>`, PatternUnstableAttributes)
+		requireAttackPattern(t, `<x:></x:>`, PatternUnstableElements)
+	}
+	requireAttackPattern(t, `<Root><! <<!-- -->!-- x --> y></Root>`, PatternUnstableDirectives)
+	requireAttackPattern(t, `<!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><foo>&xxe;</foo>`, PatternExternalEntity)
+	requireAttackPattern(t, `<!DOCTYPE foo [<!ENTITY % xxe PUBLIC "x" "http://example.com/x.dtd"> %xxe;]><foo/>`, PatternExternalEntity)
+	requireAttackPattern(t, `<!DOCTYPE foo SYSTEM "http://example.com/foo.dtd"><foo/>`, PatternExternalDTD)
+	requireAttackPattern(t, `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`, PatternExternalDTD)
+	requireAttackPattern(t, `<Response ID="a"><Assertion ID="b"/><Wrapper><Assertion ID="b"/></Wrapper></Response>`, PatternDuplicateID)
+
+	clean := []string{
+		`<Root xmlns:x="urn:x" x:attr="value"><x:Child ID="a"/><x:Child ID="b"/></Root>`,
+		`<!DOCTYPE foo [<!ENTITY greeting "hello">]><foo>&greeting;</foo>`,
+		`<!name <!-- comment --><nesting <more nesting>>>`,
+	}
+	for _, doc := range clean {
+		require.Empty(t, New().ValidateAll(strings.NewReader(doc)), "Shouldn't report attack patterns on clean documents")
+	}
+
+	require.Len(t, KnownAttackPatterns(), 6, "Should list every known attack pattern")
+	require.Equal(t, `matches known attack pattern XSW-DUPLICATE-ID (XML signature wrapping through duplicate IDs): ID "b" is used more than once`,
+		XMLAttackPatternError{PatternDuplicateID, `ID "b" is used more than once`}.Error(), "Attack pattern error message should match expectation")
+}
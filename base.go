@@ -0,0 +1,115 @@
+package validator
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+)
+
+// XMLBaseConfig configures CheckXMLBase
+type XMLBaseConfig struct {
+	CheckConfig
+	// FlagAll reports every xml:base attribute; by default only xml:base
+	// attributes that aren't valid URI references, or that move the base
+	// URI to a different scheme or host, are reported
+	FlagAll bool
+}
+
+// WithXMLBaseCheck enables and configures CheckXMLBase
+func WithXMLBaseCheck(cfg XMLBaseConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckXMLBase] = cfg.CheckConfig
+		v.xmlBase = cfg
+	}
+}
+
+// pushBase computes the effective base URI of a start element
+func (d *document) pushBase(start xml.StartElement) {
+	base := d.base()
+	for _, attr := range start.Attr {
+		if attr.Name.Space == xmlPrefix && attr.Name.Local == "base" {
+			if resolved, err := resolveBase(base, attr.Value); err == nil {
+				base = resolved
+			}
+		}
+	}
+	d.bases = append(d.bases, base)
+}
+
+// popBase restores the base URI of the enclosing element
+func (d *document) popBase() {
+	if len(d.bases) > 0 {
+		d.bases = d.bases[:len(d.bases)-1]
+	}
+}
+
+// base returns the effective base URI of the innermost open element, or
+// an empty string if no xml:base attribute is in scope
+func (d *document) base() string {
+	if len(d.bases) == 0 {
+		return ""
+	}
+	return d.bases[len(d.bases)-1]
+}
+
+// resolveBase resolves an xml:base attribute against the inherited base URI
+func resolveBase(base, ref string) (string, error) {
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if base == "" {
+		return refURL.String(), nil
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	return baseURL.ResolveReference(refURL).String(), nil
+}
+
+// newXMLBaseCheck creates the per-document state of CheckXMLBase
+func newXMLBaseCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		// the base of the start element itself has already been pushed
+		inherited := ""
+		if len(d.bases) > 1 {
+			inherited = d.bases[len(d.bases)-2]
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Space != xmlPrefix || attr.Name.Local != "base" {
+				continue
+			}
+			resolved, err := resolveBase(inherited, attr.Value)
+			if err != nil && v.redaction {
+				return fmt.Errorf("xml:base %q is not a valid URI reference", redacted)
+			} else if err != nil {
+				return fmt.Errorf("xml:base %q is not a valid URI reference: %w", attr.Value, err)
+			}
+			if v.xmlBase.FlagAll {
+				return fmt.Errorf("xml:base %q changes the base URI to %q", d.redact(attr.Value), d.redact(resolved))
+			}
+			if inherited != "" && !sameOrigin(inherited, resolved) {
+				return fmt.Errorf("xml:base %q moves the base URI from %q to %q", d.redact(attr.Value), d.redact(inherited), d.redact(resolved))
+			}
+		}
+		return nil
+	}
+}
+
+// sameOrigin reports whether two URIs share their scheme and host
+func sameOrigin(a, b string) bool {
+	aURL, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	bURL, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return aURL.Scheme == bURL.Scheme && aURL.Host == bURL.Host
+}
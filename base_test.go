@@ -0,0 +1,54 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMLBaseTracking(t *testing.T) {
+	doc, err := New().Parse(strings.NewReader(
+		`<Root xml:base="http://example.com/docs/"><Child xml:base="sub/"><Leaf/></Child><Other/></Root>`))
+	require.NoError(t, err, "Should parse documents using xml:base")
+
+	require.Equal(t, "http://example.com/docs/", doc.Find("/Root")[0].Base, "xml:base should set the base URI")
+	require.Equal(t, "http://example.com/docs/sub/", doc.Find("//Child")[0].Base, "Relative xml:base should be resolved against the inherited base")
+	require.Equal(t, "http://example.com/docs/sub/", doc.Find("//Leaf")[0].Base, "Base URIs should be inherited")
+	require.Equal(t, "http://example.com/docs/", doc.Find("//Other")[0].Base, "Base URIs should be restored after closing elements")
+
+	tokens, err := New().Tokens(strings.NewReader(`<Root xml:base="http://example.com/">text</Root><Other/>`))
+	require.NoError(t, err, "Should tokenize documents using xml:base")
+	require.Equal(t, "http://example.com/", tokens[1].Base, "Tokens should carry the base URI in scope")
+	require.Equal(t, "", tokens[3].Base, "Tokens outside of xml:base scope shouldn't have a base URI")
+}
+
+func TestXMLBaseCheck(t *testing.T) {
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Root xml:base="http://evil.example.com/"/>`)),
+		"xml:base should be allowed by default")
+
+	v := New(WithXMLBaseCheck(XMLBaseConfig{}))
+	valid := []string{
+		`<Root xml:base="http://example.com/docs/"><Child xml:base="sub/"/><Child xml:base="/other/"/></Root>`,
+		`<Root><Child xml:base="http://example.com/"/></Root>`,
+	}
+	for _, doc := range valid {
+		require.Empty(t, v.ValidateAll(strings.NewReader(doc)), "Should pass on xml:base staying on the same origin: %s", doc)
+	}
+
+	invalid := map[string]string{
+		`<Root xml:base="http://example.com/"><Child xml:base="https://example.com/"/></Root>`: `xml:base "https://example.com/" moves the base URI from "http://example.com/" to "https://example.com/"`,
+		`<Root xml:base="http://example.com/"><Child xml:base="//evil.example.com/"/></Root>`:  `xml:base "//evil.example.com/" moves the base URI from "http://example.com/" to "http://evil.example.com/"`,
+		`<Root xml:base="http://[::1"/>`: `xml:base "http://[::1" is not a valid URI reference: parse "http://[::1": missing ']' in host`,
+	}
+	for doc, expected := range invalid {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report exactly one error in %s", doc)
+		require.Equal(t, CheckXMLBase, errs[0].(XMLValidationError).Check, "Finding should be reported by the xml:base check")
+		require.EqualError(t, errs[0].(XMLValidationError).Unwrap(), expected, "Error should describe the problem in %s", doc)
+	}
+
+	v = New(WithXMLBaseCheck(XMLBaseConfig{FlagAll: true}))
+	errs := v.ValidateAll(strings.NewReader(valid[0]))
+	require.Len(t, errs, 3, "Should report every xml:base attribute when flagging all of them")
+}
@@ -0,0 +1,130 @@
+package validator
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// modulePath is the import path of this module, used to find its version
+// in the build information of the running binary
+const modulePath = "github.com/mattermost/xml-roundtrip-validator"
+
+// Capabilities describes the validation guarantees a Validator provides in
+// the running binary, for embedding applications to log or expose at runtime
+type Capabilities struct {
+	// Version is the version of this module the binary was built with, or
+	// "(devel)" when it isn't known, e.g. in tests or replaced modules
+	Version string
+	// GoVersion is the version of the Go runtime, whose encoding/xml the
+	// validator inherits the tokenization of
+	GoVersion string
+	// Semantics is the tokenization semantics of encoding/xml, see
+	// StdlibSemantics
+	Semantics Semantics
+	// Emulation is the semantics the Validator emulates, see Emulate, or
+	// zero if it assumes those of encoding/xml
+	Emulation Semantics
+	// RejectsColons is set if encoding/xml rejects names with several
+	// colons as syntax errors, as it does since Go 1.20, in which case
+	// ErrCodeColonInName findings are only reported for empty prefixes
+	RejectsColons bool
+	// FailOn is the minimum severity of findings failing validation
+	FailOn Severity
+	// Checks holds the checks enabled on the Validator, in the order they run
+	Checks []CheckCapability
+}
+
+// CheckCapability describes a check enabled on a Validator
+type CheckCapability struct {
+	ID       CheckID
+	Category Category
+	Severity Severity
+	// Codes holds the error codes of the findings the check reports
+	Codes []ErrorCode
+	// Paths holds the patterns the check is limited to, if any
+	Paths []string
+}
+
+// roundtripCodes holds the error codes of the findings of CheckRoundtrip
+var roundtripCodes = []ErrorCode{
+	ErrCodeColonInName,
+	ErrCodeNameMutation,
+	ErrCodeDirectiveMutation,
+	ErrCodeCommentMutation,
+	ErrCodeCharDataMutation,
+	ErrCodeProcInstMutation,
+	ErrCodeOverflow,
+	ErrCodeUnencodable,
+}
+
+// Capabilities returns the module version, the encoding/xml semantics in
+// effect and the checks enabled on the Validator
+func (v *Validator) Capabilities() Capabilities {
+	caps := Capabilities{
+		Version:       moduleVersion(),
+		GoVersion:     runtime.Version(),
+		Semantics:     StdlibSemantics(),
+		Emulation:     v.emulation,
+		RejectsColons: StdlibSemantics() == SemanticsGo120,
+		FailOn:        v.failOn,
+		Checks:        []CheckCapability{},
+	}
+	for _, def := range builtinChecks {
+		cfg, configured := v.checks[def.id]
+		if cfg.Disabled || (def.optional && !configured) {
+			continue
+		}
+		if def.id == CheckCharset && v.charset == "" {
+			continue
+		}
+		severity := def.severity
+		if cfg.Severity != 0 {
+			severity = cfg.Severity
+		}
+		codes := []ErrorCode{ErrorCode(def.id)}
+		switch def.id {
+		case CheckRoundtrip:
+			codes = append([]ErrorCode{}, roundtripCodes...)
+		case CheckDoctype:
+			codes = []ErrorCode{ErrCodeDoctype, ErrCodeExternalDoctype, ErrCodeExternalEntity, ErrCodeExternalParameterEntity}
+		case CheckCDATA:
+			codes = []ErrorCode{ErrorCode(CheckCDATA), ErrCodeCDATASection}
+		case CheckComments:
+			codes = []ErrorCode{ErrCodeComment}
+		case CheckProcInsts:
+			codes = []ErrorCode{ErrCodeProcInst}
+		case CheckEntityExpansion:
+			codes = []ErrorCode{ErrCodeEntityRecursion, ErrCodeEntityExpansion}
+		}
+		caps.Checks = append(caps.Checks, CheckCapability{
+			ID:       def.id,
+			Category: def.category,
+			Severity: severity,
+			Codes:    codes,
+			Paths:    cfg.Paths,
+		})
+	}
+	return caps
+}
+
+// moduleVersion returns the version of this module found in the build
+// information of the running binary
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil && dep.Replace.Version != "" {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+	return "(devel)"
+}
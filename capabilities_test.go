@@ -0,0 +1,49 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	caps := New().Capabilities()
+	require.Equal(t, "(devel)", caps.Version, "Tests should run without a module version")
+	require.Equal(t, runtime.Version(), caps.GoVersion)
+	syntaxError := &xml.SyntaxError{}
+	require.Equal(t, errors.As(Validate(strings.NewReader(`<a:b:c/>`)), &syntaxError), caps.RejectsColons,
+		"Names with several colons should be rejected as syntax errors exactly when reported so")
+	require.Equal(t, SeverityError, caps.FailOn)
+
+	ids := []CheckID{}
+	for _, check := range caps.Checks {
+		ids = append(ids, check.ID)
+	}
+	require.Equal(t, []CheckID{CheckRoundtrip, CheckKnownAttacks, CheckXMLDeclaration, CheckNamespaceURI}, ids,
+		"Only checks enabled by default should be listed")
+	require.Equal(t, roundtripCodes, caps.Checks[0].Codes)
+	require.Equal(t, []ErrorCode{ErrorCode(CheckKnownAttacks)}, caps.Checks[1].Codes)
+
+	caps = New(
+		WithFailOn(SeverityWarning),
+		WithCheck(CheckKnownAttacks, CheckConfig{Disabled: true}),
+		WithNameCaseCheck(NameCaseConfig{CheckConfig: CheckConfig{Severity: SeverityError, Paths: []string{"//x"}}}),
+	).ExpectCharset("utf-8").Capabilities()
+	require.Equal(t, SeverityWarning, caps.FailOn)
+	ids = []CheckID{}
+	for _, check := range caps.Checks {
+		ids = append(ids, check.ID)
+		if check.ID == CheckNameCase {
+			require.Equal(t, SeverityError, check.Severity, "Configured severities should be reported")
+			require.Equal(t, []string{"//x"}, check.Paths)
+			require.Equal(t, CategoryStructure, check.Category)
+		}
+	}
+	require.Contains(t, ids, CheckNameCase)
+	require.Contains(t, ids, CheckCharset)
+	require.NotContains(t, ids, CheckKnownAttacks)
+}
@@ -0,0 +1,511 @@
+package validator
+
+import (
+	"encoding/xml"
+)
+
+// CheckID identifies one of the validator's built-in checks
+type CheckID string
+
+const (
+	// CheckRoundtrip verifies that every token survives a round trip
+	// through encoding/xml without mutations
+	CheckRoundtrip CheckID = "roundtrip"
+	// CheckKnownAttacks reports tokens matching the shape of published
+	// attacks against XML processing; see KnownAttackPatterns
+	CheckKnownAttacks CheckID = "known-attacks"
+	// CheckXMLDeclaration reports XML declarations anywhere but at the very
+	// start of the document, and processing instructions using any other
+	// spelling of the reserved "xml" target
+	CheckXMLDeclaration CheckID = "xml-declaration"
+	// CheckTrailingContent reports elements and character data following
+	// the root element, which some consumers ignore and others reject or
+	// read as part of the document; it is only enabled if configured
+	CheckTrailingContent CheckID = "trailing-content"
+	// CheckEmptyNames reports element and attribute names with an empty
+	// namespace prefix or local name, such as <x:> or :="v", which Go 1.16
+	// and older mutate while newer versions let them through unchanged;
+	// it reports them regardless of the Go version, and is only enabled
+	// if configured
+	CheckEmptyNames CheckID = "empty-names"
+	// CheckNameColons reports element and attribute names with several
+	// colons, such as <x::Root>, which Go 1.16 and older mutate, Go 1.17
+	// to 1.19 tokenize as local names and newer versions reject as syntax
+	// errors; it is only enabled if configured, e.g. by StrictLegacy
+	CheckNameColons CheckID = "name-colons"
+	// CheckUndeclaredPrefix reports namespace prefixes used without being
+	// declared in scope, and declarations abusing the reserved xml and
+	// xmlns prefixes; it is only enabled if configured
+	CheckUndeclaredPrefix CheckID = "undeclared-prefix"
+	// CheckNamespaceURI reports namespace declarations whose URI only
+	// differs from another one in the document by case, percent-encoding
+	// or a trailing slash, which some consumers consider equal and others don't
+	CheckNamespaceURI CheckID = "namespace-uri"
+	// CheckDuplicateDeclarations reports start elements declaring the same
+	// namespace prefix, or the default namespace, more than once, since
+	// parsers disagree on which declaration wins; it is only enabled if
+	// configured
+	CheckDuplicateDeclarations CheckID = "duplicate-declarations"
+	// CheckDefaultScopeAttributes reports unprefixed attributes, such as
+	// ID attributes, on elements in a default namespace: unlike the
+	// element, they are in no namespace, which trips up consumers matching
+	// them by namespace; it is only enabled if configured
+	CheckDefaultScopeAttributes CheckID = "default-scope-attributes"
+	// CheckXMLBase reports xml:base attributes, which change how downstream
+	// processors resolve relative references; it is only enabled if configured
+	CheckXMLBase CheckID = "xml-base"
+	// CheckAttributeOrder reports start elements whose attributes aren't in
+	// the order canonicalization puts them in, which matters to signature
+	// implementations that don't canonicalize; it is only enabled if configured
+	CheckAttributeOrder CheckID = "attribute-order"
+	// CheckDuplicateAttributes reports start elements holding an attribute
+	// more than once, written with the same name or with prefixes bound to
+	// the same namespace URI, namespace declarations being left to
+	// CheckDuplicateDeclarations; such documents aren't well-formed, but
+	// encoding/xml accepts them, and parsers disagree on whether the first
+	// or last occurrence wins; it is only enabled if configured
+	CheckDuplicateAttributes CheckID = "duplicate-attributes"
+	// CheckSkippedBytes reports tokens whose raw bytes contain content the
+	// tokenizer silently discarded, such as comments inside directives; it
+	// is only enabled if configured
+	CheckSkippedBytes CheckID = "skipped-bytes"
+	// CheckCDATA reports CDATA sections that can't be converted to escaped
+	// character data without changing their representation; it is only
+	// enabled if configured
+	CheckCDATA CheckID = "cdata"
+	// CheckCharset reports documents whose XML declaration names a different
+	// encoding than the charset they were delivered with, e.g. in an HTTP
+	// Content-Type header; it only runs on Validators returned by
+	// Validator.ExpectCharset
+	CheckCharset CheckID = "charset"
+	// CheckNameCase reports end tags and sibling elements whose names
+	// differ from those of their start tag or earlier siblings only by
+	// case, which consumers disagreeing on case sensitivity read
+	// differently; it is only enabled if configured
+	CheckNameCase CheckID = "name-case"
+	// CheckRoot reports documents whose root element isn't allowed, and
+	// stops validating them right away; it is only enabled by
+	// WithAllowedRoots
+	CheckRoot CheckID = "root"
+	// CheckTokenKinds reports tokens of kinds a document isn't expected to
+	// contain, e.g. comments and processing instructions in API payloads
+	// that never legitimately contain them; it is only enabled if configured
+	CheckTokenKinds CheckID = "token-kinds"
+	// CheckNamespaceDeclarations reports start elements declaring more
+	// namespaces than a limit, since flooding elements with declarations
+	// blows up the cost of canonicalization and signature verification
+	// downstream; it is only enabled if configured
+	CheckNamespaceDeclarations CheckID = "namespace-declarations"
+	// CheckChildren reports elements with more child elements than a
+	// limit, catching documents that exhaust resources with a flat fanout
+	// rather than deep nesting; it is only enabled if configured
+	CheckChildren CheckID = "children"
+	// CheckDepth reports elements nested deeper than a limit, since deep
+	// nesting blows up the stack and CPU use of recursive processing
+	// downstream, such as canonicalization and unmarshaling; it is only
+	// enabled if configured
+	CheckDepth CheckID = "depth"
+	// CheckAttributes reports start elements with more attributes than a
+	// limit, since flooding elements with attributes is a cheap way to
+	// blow up the cost of canonicalization and signature verification;
+	// it is only enabled if configured
+	CheckAttributes CheckID = "attributes"
+	// CheckAttributeLength reports attribute values longer than a limit,
+	// which downstream processing copies and normalizes as a whole; it is
+	// only enabled if configured
+	CheckAttributeLength CheckID = "attribute-length"
+	// CheckDifferential reports tokens a second parser reads differently
+	// than encoding/xml, catching parser differentials the round trip
+	// through encoding/xml alone can't see; it is only enabled if
+	// configured
+	CheckDifferential CheckID = "differential"
+	// CheckAnomaly reports large runs of character data with the entropy
+	// of binary data and documents made up almost entirely of character
+	// data, the shape of blobs wrapped in XML to exfiltrate data or abuse
+	// parsers; it is only enabled if configured
+	CheckAnomaly CheckID = "anomaly"
+	// CheckDoctype reports document type declarations, or only those
+	// referring to external resources, in documents that shouldn't contain
+	// any; it is only enabled if configured
+	CheckDoctype CheckID = "doctype"
+	// CheckEntityExpansion reports internal DTD subsets declaring recursive
+	// entities or entities expanding beyond a limit, such as the billion
+	// laughs attack, and documents referencing entities often enough to
+	// blow up once expanded; encoding/xml never expands entities, but the
+	// processors documents are handed to may. It is only enabled if
+	// configured.
+	CheckEntityExpansion CheckID = "entity-expansion"
+	// CheckComments reports comments, which SAML comment injection attacks
+	// use to truncate values; it is only enabled by DisallowComments or if
+	// configured
+	CheckComments CheckID = "comments"
+	// CheckProcInsts reports processing instructions other than the XML
+	// declaration, or those with targets outside an allowlist, such as
+	// xml-stylesheet instructions, which have no business in machine to
+	// machine documents; it is only enabled if configured
+	CheckProcInsts CheckID = "procinsts"
+	// CheckSyntax reports documents encoding/xml fails to parse; it is
+	// always enabled and only used to identify syntax errors in statistics
+	CheckSyntax CheckID = "syntax"
+	// CheckPrescan reports suspicious constructs found by the byte-level
+	// prescan run by QuickValidate; it isn't part of regular validation
+	CheckPrescan CheckID = "prescan"
+)
+
+// Category groups checks that detect related kinds of problems
+type Category string
+
+const (
+	// CategoryRoundtrip groups checks detecting tokens that mutate when
+	// re-encoded
+	CategoryRoundtrip Category = "roundtrip"
+	// CategorySyntax groups documents that can't be parsed at all
+	CategorySyntax Category = "syntax"
+	// CategoryStructure groups checks detecting constructs that are
+	// well-formed, but that parsers disagree on how to handle
+	CategoryStructure Category = "structure"
+	// CategoryNamespace groups checks detecting namespace constructs that
+	// parsers disagree on
+	CategoryNamespace Category = "namespace"
+	// CategoryAttack groups checks detecting known attack patterns
+	CategoryAttack Category = "attack"
+	// CategoryLimit groups checks enforcing limits on the size and shape of
+	// documents, which protect downstream processing from resource exhaustion
+	CategoryLimit Category = "limit"
+)
+
+// Category returns the category the check belongs to
+func (id CheckID) Category() Category {
+	switch id {
+	case CheckSyntax:
+		return CategorySyntax
+	case CheckPrescan:
+		return CategoryStructure
+	}
+	for _, def := range builtinChecks {
+		if def.id == id {
+			return def.category
+		}
+	}
+	return ""
+}
+
+// CheckConfig holds the settings shared by every built-in check
+type CheckConfig struct {
+	// Disabled turns the check off
+	Disabled bool
+	// Severity overrides the check's default severity when set
+	Severity Severity
+	// Paths limits the check to the subtrees of elements matching any of
+	// these patterns, e.g. "/samlp:Response/saml:Assertion" or "//ds:Signature";
+	// an empty list applies the check to the whole document
+	Paths []string
+}
+
+// RoundtripConfig configures CheckRoundtrip
+type RoundtripConfig struct {
+	CheckConfig
+	// IgnorePrefixes treats tokens as equal when their element and
+	// attribute names resolve to the same namespace URI and local name,
+	// even if they are written with different prefixes, for pipelines that
+	// canonicalize prefixes anyway. By default tokens must come back with
+	// the names they were written with, since consumers matching names by
+	// prefix, such as XPath expressions in signature references, would
+	// otherwise read them differently.
+	IgnorePrefixes bool
+	// ResolveNamespaces also runs tokens through the namespace resolution
+	// of xml.Decoder.Token, checking that start and end elements with
+	// their names resolved into namespace URIs survive being encoded and
+	// resolved again, for consumers reading documents with Token rather
+	// than RawToken; mutations in how encoding/xml declares and resolves
+	// namespaces only show up this way.
+	ResolveNamespaces bool
+}
+
+// WithCheck configures the shared settings of a built-in check; optional
+// checks are enabled by configuring them without setting Disabled
+func WithCheck(id CheckID, cfg CheckConfig) Option {
+	return func(v *Validator) {
+		v.checks[id] = cfg
+	}
+}
+
+// WithRoundtripCheck configures CheckRoundtrip
+func WithRoundtripCheck(cfg RoundtripConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckRoundtrip] = cfg.CheckConfig
+		v.roundtrip = cfg
+	}
+}
+
+// tokenCheck inspects a single token, returning an error describing
+// the problem if the token should be reported
+type tokenCheck func(d *document, token xml.Token) error
+
+// checkDefinition describes a built-in check
+type checkDefinition struct {
+	id       CheckID
+	category Category
+	severity Severity
+	// optional checks only run if they are configured
+	optional bool
+	// expensive checks are skipped on documents left out by sampling
+	expensive bool
+	// newCheck creates the check's per-document state
+	newCheck func(v *Validator) tokenCheck
+}
+
+// builtinChecks lists every built-in check in the order they are run
+var builtinChecks = []checkDefinition{
+	{
+		id:        CheckRoundtrip,
+		category:  CategoryRoundtrip,
+		severity:  SeverityError,
+		expensive: true,
+		newCheck: func(v *Validator) tokenCheck {
+			return func(d *document, token xml.Token) error {
+				err := CheckToken(token)
+				if roundtripError, ok := err.(XMLRoundtripError); ok && v.roundtrip.IgnorePrefixes &&
+					len(roundtripError.Overflow) == 0 && d.equivalent(roundtripError.Expected, roundtripError.Observed) {
+					return nil
+				}
+				if err == nil && v.roundtrip.ResolveNamespaces {
+					return checkResolvedToken(d.resolveToken(token))
+				}
+				return err
+			}
+		},
+	},
+	{
+		id:       CheckSkippedBytes,
+		category: CategoryRoundtrip,
+		severity: SeverityWarning,
+		optional: true,
+		newCheck: newSkippedBytesCheck,
+	},
+	{
+		id:       CheckEmptyNames,
+		category: CategoryRoundtrip,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newEmptyNamesCheck,
+	},
+	{
+		id:       CheckNameColons,
+		category: CategoryRoundtrip,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newNameColonsCheck,
+	},
+	{
+		id:        CheckDifferential,
+		category:  CategoryStructure,
+		severity:  SeverityError,
+		optional:  true,
+		expensive: true,
+		newCheck:  newDifferentialCheck,
+	},
+	{
+		id:       CheckKnownAttacks,
+		category: CategoryAttack,
+		severity: SeverityWarning,
+		newCheck: newKnownAttacksCheck,
+	},
+	{
+		id:       CheckAnomaly,
+		category: CategoryAttack,
+		severity: SeverityWarning,
+		optional: true,
+		newCheck: newAnomalyCheck,
+	},
+	{
+		id:       CheckXMLDeclaration,
+		category: CategoryStructure,
+		severity: SeverityWarning,
+		newCheck: newXMLDeclarationCheck,
+	},
+	{
+		id:       CheckTrailingContent,
+		category: CategoryStructure,
+		severity: SeverityWarning,
+		optional: true,
+		newCheck: newTrailingContentCheck,
+	},
+	{
+		id:       CheckUndeclaredPrefix,
+		category: CategoryNamespace,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newUndeclaredPrefixCheck,
+	},
+	{
+		id:       CheckNamespaceURI,
+		category: CategoryNamespace,
+		severity: SeverityWarning,
+		newCheck: newNamespaceURICheck,
+	},
+	{
+		id:       CheckDuplicateDeclarations,
+		category: CategoryNamespace,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newDuplicateDeclarationsCheck,
+	},
+	{
+		id:       CheckDefaultScopeAttributes,
+		category: CategoryNamespace,
+		severity: SeverityWarning,
+		optional: true,
+		newCheck: newDefaultScopeAttributesCheck,
+	},
+	{
+		id:       CheckXMLBase,
+		category: CategoryStructure,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newXMLBaseCheck,
+	},
+	{
+		id:       CheckDuplicateAttributes,
+		category: CategoryStructure,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newDuplicateAttributesCheck,
+	},
+	{
+		id:       CheckAttributeOrder,
+		category: CategoryStructure,
+		severity: SeverityWarning,
+		optional: true,
+		newCheck: newAttributeOrderCheck,
+	},
+	{
+		id:       CheckCDATA,
+		category: CategoryStructure,
+		severity: SeverityWarning,
+		optional: true,
+		newCheck: newCDATACheck,
+	},
+	{
+		id:       CheckCharset,
+		category: CategoryStructure,
+		severity: SeverityWarning,
+		newCheck: newCharsetCheck,
+	},
+	{
+		id:       CheckDoctype,
+		category: CategoryStructure,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newDoctypeCheck,
+	},
+	{
+		id:       CheckEntityExpansion,
+		category: CategoryAttack,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newEntityExpansionCheck,
+	},
+	{
+		id:       CheckComments,
+		category: CategoryStructure,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newCommentsCheck,
+	},
+	{
+		id:       CheckProcInsts,
+		category: CategoryStructure,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newProcInstsCheck,
+	},
+	{
+		id:       CheckTokenKinds,
+		category: CategoryStructure,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newTokenKindsCheck,
+	},
+	{
+		id:       CheckRoot,
+		category: CategoryStructure,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newRootCheck,
+	},
+	{
+		id:       CheckNameCase,
+		category: CategoryStructure,
+		severity: SeverityWarning,
+		optional: true,
+		newCheck: newNameCaseCheck,
+	},
+	{
+		id:       CheckNamespaceDeclarations,
+		category: CategoryLimit,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newNamespaceDeclarationsCheck,
+	},
+	{
+		id:       CheckChildren,
+		category: CategoryLimit,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newChildrenCheck,
+	},
+	{
+		id:       CheckDepth,
+		category: CategoryLimit,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newDepthCheck,
+	},
+	{
+		id:       CheckAttributes,
+		category: CategoryLimit,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newAttributesCheck,
+	},
+	{
+		id:       CheckAttributeLength,
+		category: CategoryLimit,
+		severity: SeverityError,
+		optional: true,
+		newCheck: newAttributeLengthCheck,
+	},
+}
+
+// activeCheck is a check enabled for a single document
+type activeCheck struct {
+	id        CheckID
+	severity  Severity
+	paths     []string
+	expensive bool
+	check     tokenCheck
+}
+
+// activeChecks instantiates the checks enabled on the Validator
+func (v *Validator) activeChecks() []activeCheck {
+	checks := make([]activeCheck, 0, len(builtinChecks))
+	for _, def := range builtinChecks {
+		cfg, configured := v.checks[def.id]
+		if cfg.Disabled || (def.optional && !configured) {
+			continue
+		}
+		severity := def.severity
+		if cfg.Severity != 0 {
+			severity = cfg.Severity
+		}
+		checks = append(checks, activeCheck{
+			id:        def.id,
+			severity:  severity,
+			paths:     cfg.Paths,
+			expensive: def.expensive,
+			check:     def.newCheck(v),
+		})
+	}
+	return checks
+}
@@ -0,0 +1,171 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchPath(t *testing.T) {
+	path := []xml.Name{{Space: "samlp", Local: "Response"}, {Space: "saml", Local: "Assertion"}, {Space: "ds", Local: "Signature"}}
+
+	matching := []string{
+		`/samlp:Response/saml:Assertion/ds:Signature`,
+		`//ds:Signature`,
+		`ds:Signature`,
+		`/samlp:Response//ds:Signature`,
+		`/*/*/ds:Signature`,
+		`//saml:Assertion/*`,
+	}
+	for _, pattern := range matching {
+		require.True(t, matchPath(pattern, path), "Pattern %s should match", pattern)
+	}
+
+	nonMatching := []string{
+		`/samlp:Response/saml:Assertion`,
+		`/saml:Assertion/ds:Signature`,
+		`//Signature`,
+		`/*/ds:Signature`,
+		`//ds:Signature/*`,
+	}
+	for _, pattern := range nonMatching {
+		require.False(t, matchPath(pattern, path), "Pattern %s shouldn't match", pattern)
+	}
+}
+
+func TestInScope(t *testing.T) {
+	path := []xml.Name{{Local: "Root"}, {Local: "Child"}, {Local: "Leaf"}}
+
+	require.True(t, inScope(nil, path), "An empty pattern list should match everything")
+	require.True(t, inScope([]string{`/Root/Child`}, path), "Descendants of a matching element should be in scope")
+	require.True(t, inScope([]string{`/Other`, `//Leaf`}, path), "Any matching pattern should put the element in scope")
+	require.False(t, inScope([]string{`/Root/Other`}, path), "Elements outside matching subtrees shouldn't be in scope")
+	require.False(t, inScope([]string{`/Root`}, nil), "Tokens outside of any element shouldn't be in scope of a path")
+}
+
+func TestCheckConfig(t *testing.T) {
+	v := New()
+	defaults := 0
+	for _, def := range builtinChecks {
+		if !def.optional {
+			defaults++
+		}
+	}
+	require.Len(t, v.activeChecks(), defaults, "All non-optional built-in checks should be enabled by default")
+
+	v = New(WithCheck(CheckUndeclaredPrefix, CheckConfig{}))
+	require.Len(t, v.activeChecks(), defaults+1, "Configuring an optional check should enable it")
+
+	v = New(WithCheck(CheckRoundtrip, CheckConfig{Disabled: true}))
+	for _, c := range v.activeChecks() {
+		require.NotEqual(t, CheckRoundtrip, c.id, "Disabled checks shouldn't run")
+	}
+
+	v = New(WithRoundtripCheck(RoundtripConfig{CheckConfig: CheckConfig{Severity: SeverityWarning, Paths: []string{`//Assertion`}}}))
+	for _, c := range v.activeChecks() {
+		if c.id == CheckRoundtrip {
+			require.Equal(t, SeverityWarning, c.severity, "Severity should be overridden by the check's configuration")
+			require.Equal(t, []string{`//Assertion`}, c.paths, "Paths should be taken from the check's configuration")
+		}
+	}
+
+	errs := New(WithCheck(CheckRoundtrip, CheckConfig{Disabled: true})).ValidateAll(strings.NewReader(`<Root></Root>`))
+	require.Len(t, errs, 0, "Should pass on valid XML documents")
+}
+
+// registerTestCheck adds a check to the built-in checks for the duration of a test
+func registerTestCheck(t *testing.T, id CheckID, category Category, severity Severity, check tokenCheck) {
+	t.Helper()
+	builtin := builtinChecks
+	builtinChecks = append(builtin[:len(builtin):len(builtin)], checkDefinition{
+		id:       id,
+		category: category,
+		severity: severity,
+		newCheck: func(v *Validator) tokenCheck { return check },
+	})
+	t.Cleanup(func() { builtinChecks = builtin })
+}
+
+// onStartElements is a check reporting every start element
+func onStartElements(d *document, token xml.Token) error {
+	if start, ok := token.(xml.StartElement); ok {
+		return fmt.Errorf("found %s", start.Name.Local)
+	}
+	return nil
+}
+
+func TestOnePerCategory(t *testing.T) {
+	registerTestCheck(t, "test-a", "a", SeverityError, onStartElements)
+	registerTestCheck(t, "test-b", "b", SeverityWarning, onStartElements)
+	doc := `<Root><Child/><Child/></Root>`
+
+	require.Equal(t, Category("a"), CheckID("test-a").Category(), "Checks should report their category")
+	require.Equal(t, CategorySyntax, CheckSyntax.Category(), "Syntax errors should have their own category")
+
+	errs := New().ValidateAll(strings.NewReader(doc))
+	require.Len(t, errs, 6, "Should report every finding by default")
+
+	errs = New(WithOnePerCategory()).ValidateAll(strings.NewReader(doc))
+	require.Len(t, errs, 2, "Should report one finding per category")
+	require.Equal(t, CheckID("test-a"), errs[0].(XMLValidationError).Check, "First finding should come from the first check")
+	require.Equal(t, CheckID("test-b"), errs[1].(XMLValidationError).Check, "Second finding should come from the second check")
+
+	report, err := New(WithOnePerCategory(), WithCheck("test-a", CheckConfig{Paths: []string{`/Root/Child`}})).Report(strings.NewReader(doc))
+	require.NoError(t, err, "Should report on valid XML documents")
+	require.Len(t, report.Errors, 2, "Should report one finding per category")
+	require.Equal(t, CheckID("test-b"), report.Errors[0].(XMLValidationError).Check, "Unscoped checks should report findings on the root element")
+	require.Equal(t, CheckID("test-a"), report.Errors[1].(XMLValidationError).Check, "Scoped checks should report findings in scope")
+	require.Equal(t, int64(6), report.Errors[1].(XMLValidationError).Start, "Scoped checks should only report findings in scope")
+}
+
+func TestOnePerCategorySeverity(t *testing.T) {
+	doc := `<Root xmlns:a="http://x/ns" xmlns:b="http://X/ns"><e xmlns="1" xmlns="2"/><f xmlns="1" xmlns="2"/></Root>`
+	v := New(WithOnePerCategory(), WithDuplicateDeclarationsCheck(DuplicateDeclarationsConfig{}))
+	require.Error(t, v.Validate(strings.NewReader(doc)), "Validate shouldn't suppress findings")
+	errs := v.ValidateAll(strings.NewReader(doc))
+	require.Len(t, errs, 2, "Findings that don't fail validation shouldn't suppress failing ones")
+	require.Equal(t, []CheckID{CheckNamespaceURI, CheckDuplicateDeclarations},
+		[]CheckID{errs[0].(XMLValidationError).Check, errs[1].(XMLValidationError).Check})
+}
+
+func TestMaxErrors(t *testing.T) {
+	registerTestCheck(t, "test-a", "a", SeverityWarning, onStartElements)
+	doc := `<Root><Child/><Child/><Child/></Root>`
+
+	errs := New(WithMaxErrors(2)).ValidateAll(strings.NewReader(doc))
+	require.Len(t, errs, 3, "Should stop after the maximum number of errors")
+	require.Equal(t, ErrTooManyErrors, errs[2], "Should tell the findings were cut short")
+	require.Len(t, ValidateAllWithOptions(strings.NewReader(doc), WithMaxErrors(5)), 4, "Shouldn't cut findings short below the maximum")
+
+	report, err := New(WithMaxErrors(1)).Report(strings.NewReader(doc))
+	require.NoError(t, err, "Reaching the maximum number of errors shouldn't be a read error")
+	require.Len(t, report.Errors, 2, "Report should stop after the maximum number of errors")
+	require.True(t, report.Failed, "Documents with too many errors should fail validation")
+
+	require.NoError(t, New(WithMaxErrors(1)).Validate(strings.NewReader(doc)), "Validate should ignore the maximum number of errors")
+}
+
+func TestFindingPath(t *testing.T) {
+	registerTestCheck(t, "test-a", "a", SeverityError, func(d *document, token xml.Token) error {
+		if _, ok := token.(xml.CharData); ok && strings.TrimSpace(string(d.raw())) != "" {
+			return errors.New("found text")
+		}
+		return nil
+	})
+	doc := `<samlp:Response><saml:Assertion><ds:Signature/><ds:Signature>a<x/>b</ds:Signature></saml:Assertion>` +
+		`<saml:Assertion><ds:Signature>c</ds:Signature></saml:Assertion></samlp:Response><Other>d</Other>`
+	var paths []string
+	for _, err := range New().ValidateAll(strings.NewReader(doc)) {
+		paths = append(paths, err.(XMLValidationError).Path)
+	}
+	require.Equal(t, []string{
+		"/samlp:Response[1]/saml:Assertion[1]/ds:Signature[2]",
+		"/samlp:Response[1]/saml:Assertion[1]/ds:Signature[2]",
+		"/samlp:Response[1]/saml:Assertion[2]/ds:Signature[1]",
+		"/Other[1]",
+	}, paths, "Should locate findings by the path of their element")
+}
@@ -0,0 +1,10 @@
+//go:build !go1.21
+// +build !go1.21
+
+package main
+
+import "net/http"
+
+// enableFullDuplex is a no-op before Go 1.21, where HTTP/1.x request bodies
+// may become unreadable once the response is written; HTTP/2 is unaffected
+func enableFullDuplex(w http.ResponseWriter) {}
@@ -0,0 +1,12 @@
+//go:build go1.21
+// +build go1.21
+
+package main
+
+import "net/http"
+
+// enableFullDuplex lets HTTP/1.x handlers keep reading the request body
+// after they started writing the response
+func enableFullDuplex(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).EnableFullDuplex()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// goldenSuffix replaces the extension of fixtures to name their golden files
+const goldenSuffix = ".expected.json"
+
+// testGolden implements xrv test, validating every XML file in the given
+// directories and comparing its findings with its golden file
+func testGolden(args []string) {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	update := flags.Bool("update", false, "Write the findings to the golden files instead of comparing them")
+	policyFile := flags.String("policy", "", "JSON policy file configuring validation")
+	flags.Parse(args)
+
+	v := validator.New()
+	if *policyFile != "" {
+		p, err := loadPolicy(*policyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		opts, _, err := p.options()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		v = validator.New(opts...)
+	}
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	passed, err := checkGolden(os.Stdout, v, dirs, *update)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if !passed {
+		os.Exit(1)
+	}
+}
+
+// checkGolden validates the XML files in dirs and compares their findings
+// with their golden files, or writes the golden files if update is set,
+// describing every fixture to w; it reports whether every fixture matched
+func checkGolden(w io.Writer, v *validator.Validator, dirs []string, update bool) (bool, error) {
+	passed := true
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".xml") {
+				return err
+			}
+			result, rpcErr := validateRPC(v, &validateParams{Path: path, All: true})
+			if rpcErr != nil {
+				return errors.New(rpcErr.Message)
+			}
+			observed, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			observed = append(observed, '\n')
+			goldenFile := strings.TrimSuffix(path, filepath.Ext(path)) + goldenSuffix
+			if update {
+				fmt.Fprintf(w, "updated %s\n", goldenFile)
+				return ioutil.WriteFile(goldenFile, observed, 0644)
+			}
+			expected, err := ioutil.ReadFile(goldenFile)
+			if os.IsNotExist(err) {
+				passed = false
+				fmt.Fprintf(w, "FAIL %s: missing %s, run with -update to create it\n", path, goldenFile)
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if bytes.Equal(expected, observed) {
+				fmt.Fprintf(w, "ok   %s\n", path)
+				return nil
+			}
+			passed = false
+			fmt.Fprintf(w, "FAIL %s\n", path)
+			return diffGolden(w, expected, observed)
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	return passed, nil
+}
+
+// goldenResult is a validateResult read back from JSON, with findings kept
+// as written so they can be compared
+type goldenResult struct {
+	Valid    bool              `json:"valid"`
+	Findings []json.RawMessage `json:"findings"`
+}
+
+// diffGolden describes how an observed result differs from a golden file,
+// listing findings only expected with "-" and findings only observed with "+"
+func diffGolden(w io.Writer, golden, result []byte) error {
+	expected, observed := &goldenResult{}, &goldenResult{}
+	if err := json.Unmarshal(golden, expected); err != nil {
+		return fmt.Errorf("invalid golden file: %w", err)
+	}
+	if err := json.Unmarshal(result, observed); err != nil {
+		return err
+	}
+	if expected.Valid != observed.Valid {
+		fmt.Fprintf(w, "\tvalid: expected %t, observed %t\n", expected.Valid, observed.Valid)
+	}
+	expectedLines, observedLines := findingLines(expected.Findings), findingLines(observed.Findings)
+	for _, line := range expectedLines {
+		if !containsLine(observedLines, line) {
+			fmt.Fprintf(w, "\t- %s\n", line)
+		}
+	}
+	for _, line := range observedLines {
+		if !containsLine(expectedLines, line) {
+			fmt.Fprintf(w, "\t+ %s\n", line)
+		}
+	}
+	return nil
+}
+
+// findingLines renders findings as single lines of JSON
+func findingLines(findings []json.RawMessage) []string {
+	lines := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		line := &bytes.Buffer{}
+		if err := json.Compact(line, finding); err != nil {
+			line.Write(finding)
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/mattermost/xml-roundtrip-validator/xrvtest"
+)
+
+func TestCheckGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xrv-golden")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	good, bad := filepath.Join(dir, "good.xml"), filepath.Join(dir, "sub", "bad.xml")
+	require.NoError(t, ioutil.WriteFile(good, []byte(`<Root/>`), 0644))
+	require.NoError(t, ioutil.WriteFile(bad, []byte(`<Root><?xml version="1.0"?></Root>`), 0644))
+	v := validator.New()
+
+	out := &bytes.Buffer{}
+	passed, err := checkGolden(out, v, []string{dir}, false)
+	require.NoError(t, err)
+	require.False(t, passed, "Fixtures without golden files should fail")
+	require.Contains(t, out.String(), "run with -update")
+
+	out.Reset()
+	passed, err = checkGolden(out, v, []string{dir}, true)
+	require.NoError(t, err)
+	require.True(t, passed)
+	golden, err := ioutil.ReadFile(filepath.Join(dir, "sub", "bad.expected.json"))
+	require.NoError(t, err)
+	xrvtest.AssertMatchesSchema(t, "result", golden)
+	require.Contains(t, string(golden), `"check": "xml-declaration"`)
+
+	out.Reset()
+	passed, err = checkGolden(out, v, []string{dir}, false)
+	require.NoError(t, err)
+	require.True(t, passed, "Fixtures should match their golden files: %s", out.String())
+	require.Contains(t, out.String(), "ok   "+good)
+
+	require.NoError(t, ioutil.WriteFile(bad, []byte(`<Root><!-- drift --></Root>`), 0644))
+	out.Reset()
+	passed, err = checkGolden(out, v, []string{dir}, false)
+	require.NoError(t, err)
+	require.False(t, passed, "Changed findings should fail")
+	require.Contains(t, out.String(), "FAIL "+bad)
+	require.Contains(t, out.String(), `- {"check":"xml-declaration"`)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// validateParams are the parameters of the validate method; exactly one
+// of Content and Path must be set
+type validateParams struct {
+	Content *string `json:"content"`
+	Path    string  `json:"path"`
+	// All reports every finding instead of stopping at the first one
+	// failing validation
+	All bool `json:"all"`
+}
+
+type validateResult struct {
+	// Valid is set if none of the findings failed validation
+	Valid    bool                `json:"valid"`
+	Findings []validator.Finding `json:"findings"`
+}
+
+// serveJSONRPC answers JSON-RPC 2.0 requests read from r until r is
+// exhausted or a shutdown request is received. Messages are JSON values
+// separated by newlines; requests are answered in order.
+func serveJSONRPC(r io.Reader, w io.Writer, v *validator.Validator) error {
+	decoder := json.NewDecoder(r)
+	encoder := json.NewEncoder(w)
+	for {
+		var req rpcRequest
+		err := decoder.Decode(&req)
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			// the stream can't be resynchronized after malformed JSON
+			encoder.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{rpcParseError, err.Error()}})
+			return err
+		}
+		result, rpcErr := handleRPC(v, &req)
+		if req.ID == nil {
+			// notifications aren't answered
+			continue
+		}
+		resp := rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+		if rpcErr == nil {
+			if resp.Result, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+		if req.Method == "shutdown" && rpcErr == nil {
+			return nil
+		}
+	}
+}
+
+// handleRPC runs a single request
+func handleRPC(v *validator.Validator, req *rpcRequest) (interface{}, *rpcError) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return nil, &rpcError{rpcInvalidRequest, "invalid JSON-RPC 2.0 request"}
+	}
+	switch req.Method {
+	case "validate":
+		var params validateParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		if (params.Content == nil) == (params.Path == "") {
+			return nil, &rpcError{rpcInvalidParams, "specify either content or path"}
+		}
+		return validateRPC(v, &params)
+	case "shutdown":
+		return nil, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, "method not found: " + req.Method}
+}
+
+func validateRPC(v *validator.Validator, params *validateParams) (*validateResult, *rpcError) {
+	var document io.Reader
+	if params.Content != nil {
+		document = strings.NewReader(*params.Content)
+	} else {
+		f, err := os.Open(params.Path)
+		if err != nil {
+			return nil, &rpcError{rpcInvalidParams, err.Error()}
+		}
+		defer f.Close()
+		document = f
+	}
+	var errs []error
+	if params.All {
+		errs = v.ValidateAll(document)
+	} else if err := v.Validate(document); err != nil {
+		errs = append(errs, err)
+	}
+	result := &validateResult{Valid: true, Findings: []validator.Finding{}}
+	for _, err := range errs {
+		result.Valid = result.Valid && !v.Fails(err)
+		result.Findings = append(result.Findings, validator.FindingOf(err))
+	}
+	return result, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/mattermost/xml-roundtrip-validator/xrvtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeJSONRPC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xrv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "doc.xml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("<Root>\n]]></Root>"), 0600))
+	pathJSON, err := json.Marshal(path)
+	require.NoError(t, err)
+
+	requests := strings.Join([]string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "validate", "params": {"content": "<Root/>"}}`,
+		`{"jsonrpc": "2.0", "id": "two", "method": "validate", "params": {"path": ` + string(pathJSON) + `}}`,
+		`{"jsonrpc": "2.0", "method": "validate", "params": {"content": "<Root/>"}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "validate", "params": {"content": "<Root><!DOCTYPE x SYSTEM \"http://example.com/x.dtd\"></Root>", "all": true}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "validate", "params": {}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "format"}`,
+		`{"id": 6, "method": "validate"}`,
+		`{"jsonrpc": "2.0", "id": 7, "method": "shutdown"}`,
+		`{"jsonrpc": "2.0", "id": 8, "method": "validate", "params": {"content": "<Root/>"}}`,
+	}, "\n")
+	out := &bytes.Buffer{}
+	require.NoError(t, serveJSONRPC(strings.NewReader(requests), out, validator.New()), "Should stop on shutdown")
+
+	var responses []map[string]interface{}
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var resp map[string]interface{}
+		require.NoError(t, decoder.Decode(&resp))
+		responses = append(responses, resp)
+	}
+	require.Len(t, responses, 7, "Should answer every request but notifications, up to shutdown")
+	for _, resp := range responses[:3] {
+		data, err := json.Marshal(resp["result"])
+		require.NoError(t, err)
+		xrvtest.AssertMatchesSchema(t, "result", data)
+	}
+
+	require.Equal(t, float64(1), responses[0]["id"], "Should echo request IDs")
+	require.Equal(t, map[string]interface{}{"valid": true, "findings": []interface{}{}}, responses[0]["result"], "Should validate inline content")
+
+	require.Equal(t, "two", responses[1]["id"], "Should echo request IDs")
+	result := responses[1]["result"].(map[string]interface{})
+	require.Equal(t, false, result["valid"], "Should validate files")
+	finding := result["findings"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "syntax", finding["check"], "Should report the check of findings")
+	require.Equal(t, float64(2), finding["line"], "Should report the line of findings")
+
+	result = responses[2]["result"].(map[string]interface{})
+	require.Equal(t, true, result["valid"], "Warnings shouldn't fail validation")
+	finding = result["findings"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, "warning", finding["severity"], "Should report warnings")
+	require.Equal(t, "known-attacks", finding["code"], "Should report the code of findings")
+	require.Equal(t, []interface{}{float64(1), float64(53)}, []interface{}{finding["end_line"], finding["end_column"]}, "Should report the end of findings")
+
+	for i, code := range []float64{rpcInvalidParams, rpcMethodNotFound, rpcInvalidRequest} {
+		require.Equal(t, code, responses[3+i]["error"].(map[string]interface{})["code"], "Should report errors")
+		require.NotContains(t, responses[3+i], "result", "Errors shouldn't have results")
+	}
+
+	require.Equal(t, float64(7), responses[6]["id"], "Should answer shutdown")
+	require.Contains(t, responses[6], "result", "Should answer shutdown with a result")
+
+	out.Reset()
+	require.Error(t, serveJSONRPC(strings.NewReader(`{"jsonrpc": `), out, validator.New()), "Should stop on malformed JSON")
+	require.Contains(t, out.String(), `"code":-32700`, "Should report malformed JSON")
+}
+
+func TestJSONRPCFindings(t *testing.T) {
+	v := validator.New(validator.WithSnippets(8))
+	doc := `<Root><?xml version="1.0"?></Root>`
+	errs := v.ValidateAll(strings.NewReader(doc))
+	require.Len(t, errs, 1)
+	expected, err := json.Marshal(validator.FindingOf(errs[0]))
+	require.NoError(t, err)
+
+	result, rpcErr := validateRPC(v, &validateParams{Content: &doc, All: true})
+	require.Nil(t, rpcErr)
+	observed, err := json.Marshal(result.Findings[0])
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(observed), "Findings should be written the way Finding marshals them")
+	require.Contains(t, string(observed), `"snippet"`, "Findings should keep their snippet")
+	require.Contains(t, string(observed), `"suggestion"`, "Findings should keep their suggestion")
+}
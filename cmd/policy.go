@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/mattermost/xml-roundtrip-validator/xrvhttp"
+)
+
+// policy is the JSON configuration of xrv serve
+type policy struct {
+	// FailOn is the lowest severity that rejects a request
+	FailOn string `json:"fail_on"`
+	// Streaming validates request bodies as they are forwarded instead of
+	// buffering them
+	Streaming bool `json:"streaming"`
+	// Checks configures individual checks by ID
+	Checks map[string]checkPolicy `json:"checks"`
+	// Limits enables the limit checks with the given values
+	Limits limitsPolicy `json:"limits"`
+	// Redact leaves attribute values and character data out of findings
+	Redact bool `json:"redact"`
+	// Anomaly, if set, enables the anomaly check with the given thresholds
+	Anomaly *anomalyPolicy `json:"anomaly"`
+}
+
+// checkPolicy mirrors validator.CheckConfig
+type checkPolicy struct {
+	Disabled bool     `json:"disabled"`
+	Severity string   `json:"severity"`
+	Paths    []string `json:"paths"`
+}
+
+// limitsPolicy configures the limit checks; zero values leave them disabled
+type limitsPolicy struct {
+	MaxNamespaceDeclarations int `json:"max_namespace_declarations"`
+	MaxChildren              int `json:"max_children"`
+	MaxDepth                 int `json:"max_depth"`
+	MaxAttributes            int `json:"max_attributes"`
+	MaxAttributeLength       int `json:"max_attribute_length"`
+}
+
+// anomalyPolicy mirrors validator.AnomalyConfig; zero values use the
+// defaults
+type anomalyPolicy struct {
+	MinTextSize     int     `json:"min_text_size"`
+	MaxEntropy      float64 `json:"max_entropy"`
+	MinDocumentSize int     `json:"min_document_size"`
+	MaxTextRatio    float64 `json:"max_text_ratio"`
+}
+
+// loadPolicy reads a policy file; an empty filename returns the default policy
+func loadPolicy(filename string) (*policy, error) {
+	p := &policy{}
+	if filename == "" {
+		return p, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(p); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", filename, err)
+	}
+	return p, nil
+}
+
+// options converts the policy into validator and middleware options
+func (p *policy) options() ([]validator.Option, []xrvhttp.Option, error) {
+	var opts []validator.Option
+	if p.FailOn != "" {
+		severity, err := validator.ParseSeverity(p.FailOn)
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, validator.WithFailOn(severity))
+	}
+	// configs holds the configuration of every check listed, for the
+	// options configuring limits to keep
+	configs := map[validator.CheckID]validator.CheckConfig{}
+	for name, check := range p.Checks {
+		id := validator.CheckID(name)
+		if id.Category() == "" {
+			return nil, nil, fmt.Errorf("unknown check %q", name)
+		}
+		cfg := validator.CheckConfig{Disabled: check.Disabled, Paths: check.Paths}
+		if check.Severity != "" {
+			severity, err := validator.ParseSeverity(check.Severity)
+			if err != nil {
+				return nil, nil, fmt.Errorf("check %q: %w", name, err)
+			}
+			cfg.Severity = severity
+		}
+		configs[id] = cfg
+		opts = append(opts, validator.WithCheck(id, cfg))
+	}
+	if p.Limits.MaxNamespaceDeclarations > 0 {
+		opts = append(opts, validator.WithNamespaceDeclarationsCheck(validator.NamespaceDeclarationsConfig{
+			CheckConfig: configs[validator.CheckNamespaceDeclarations],
+			Max:         p.Limits.MaxNamespaceDeclarations,
+		}))
+	}
+	if p.Limits.MaxChildren > 0 {
+		opts = append(opts, validator.WithChildrenCheck(validator.ChildrenConfig{
+			CheckConfig: configs[validator.CheckChildren],
+			Max:         p.Limits.MaxChildren,
+		}))
+	}
+	if p.Limits.MaxDepth > 0 {
+		opts = append(opts, validator.WithDepthCheck(validator.DepthConfig{
+			CheckConfig: configs[validator.CheckDepth],
+			Max:         p.Limits.MaxDepth,
+		}))
+	}
+	if p.Limits.MaxAttributes > 0 {
+		opts = append(opts, validator.WithAttributesCheck(validator.AttributesConfig{
+			CheckConfig: configs[validator.CheckAttributes],
+			Max:         p.Limits.MaxAttributes,
+		}))
+	}
+	if p.Limits.MaxAttributeLength > 0 {
+		opts = append(opts, validator.WithAttributeLengthCheck(validator.AttributeLengthConfig{
+			CheckConfig: configs[validator.CheckAttributeLength],
+			Max:         p.Limits.MaxAttributeLength,
+		}))
+	}
+	if p.Anomaly != nil {
+		opts = append(opts, validator.WithAnomalyCheck(validator.AnomalyConfig{
+			CheckConfig:     configs[validator.CheckAnomaly],
+			MinTextSize:     p.Anomaly.MinTextSize,
+			MaxEntropy:      p.Anomaly.MaxEntropy,
+			MinDocumentSize: p.Anomaly.MinDocumentSize,
+			MaxTextRatio:    p.Anomaly.MaxTextRatio,
+		}))
+	}
+	if p.Redact {
+		opts = append(opts, validator.WithRedaction())
+	}
+	var middlewareOpts []xrvhttp.Option
+	if p.Streaming {
+		middlewareOpts = append(middlewareOpts, xrvhttp.Streaming())
+	}
+	return opts, middlewareOpts, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/mattermost/xml-roundtrip-validator/xrvhttp"
+	"github.com/mattermost/xml-roundtrip-validator/xrvstore"
+)
+
+// serve runs xrv as a reverse proxy validating XML request bodies before
+// forwarding them to an upstream service
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "Address to accept requests on")
+	admin := flags.String("admin", "127.0.0.1:8081", "Address of the health, readiness and reload endpoints")
+	upstream := flags.String("upstream", "", "URL of the service validated requests are forwarded to")
+	policyFile := flags.String("policy", "", "JSON policy file configuring validation; reloaded on SIGHUP")
+	storeFile := flags.String("store", "", "File to record a report of every validated request in, see xrv history")
+	flags.Parse(args)
+
+	if *upstream == "" {
+		fmt.Fprintln(os.Stderr, "Specify an upstream URL")
+		os.Exit(1)
+	}
+	target, err := url.Parse(*upstream)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	s := &server{policyFile: *policyFile, next: httputil.NewSingleHostReverseProxy(target)}
+	if *storeFile != "" {
+		store, err := xrvstore.OpenFileStore(*storeFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		defer store.Close()
+		s.store = store
+	}
+	if err := s.reload(); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := s.reload(); err != nil {
+				log.Printf("failed to reload policy, keeping the previous one: %v", err)
+			} else {
+				log.Printf("reloaded policy")
+			}
+		}
+	}()
+
+	go func() {
+		log.Fatal(http.ListenAndServe(*admin, s.adminHandler()))
+	}()
+	log.Fatal(http.ListenAndServe(*listen, s))
+}
+
+// server validates requests with the current policy and forwards them
+type server struct {
+	policyFile string
+	next       http.Handler
+	// store records validation reports if set
+	store xrvstore.Store
+	// handler holds the http.Handler built from the current policy
+	handler atomic.Value
+	// reloading serializes reloads
+	reloading sync.Mutex
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler, ok := s.handler.Load().(http.Handler)
+	if !ok {
+		http.Error(w, "no policy loaded", http.StatusServiceUnavailable)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
+
+// reload reads the policy file and swaps in a handler enforcing it;
+// requests in flight keep using the previous policy
+func (s *server) reload() error {
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+	p, err := loadPolicy(s.policyFile)
+	if err != nil {
+		return err
+	}
+	opts, middlewareOpts, err := p.options()
+	if err != nil {
+		return err
+	}
+	v := validator.New(opts...)
+	if s.store != nil {
+		s.handler.Store(recordingMiddleware(s.store, v, middlewareOpts, s.next))
+		return nil
+	}
+	s.handler.Store(xrvhttp.Middleware(v, middlewareOpts...)(s.next))
+	return nil
+}
+
+// adminHandler serves the health, readiness and reload endpoints
+func (s *server) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := s.handler.Load().(http.Handler); !ok {
+			http.Error(w, "no policy loaded", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.reload(); err != nil {
+			log.Printf("failed to reload policy, keeping the previous one: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("reloaded policy")
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
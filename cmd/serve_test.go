@@ -0,0 +1,152 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/stretchr/testify/require"
+)
+
+func writePolicy(t *testing.T, filename, content string) {
+	t.Helper()
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0600))
+}
+
+func TestServe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xrv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	policyFile := filepath.Join(dir, "policy.json")
+	writePolicy(t, policyFile, `{"fail_on": "error"}`)
+
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s := &server{policyFile: policyFile, next: upstream}
+	admin := s.adminHandler()
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code, "Shouldn't be ready before loading a policy")
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Should be healthy")
+
+	require.NoError(t, s.reload(), "Should load the policy")
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Should be ready once the policy is loaded")
+
+	warning := `<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd"></Root>`
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(warning)))
+	require.Equal(t, http.StatusNoContent, rec.Code, "Should forward requests passing the policy")
+
+	writePolicy(t, policyFile, `{"fail_on": "warning", "streaming": true}`)
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "Should reload the policy")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(warning)))
+	require.Equal(t, http.StatusBadRequest, rec.Code, "Should enforce the reloaded policy")
+
+	writePolicy(t, policyFile, `{"fail_on": "sometimes"}`)
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload", nil))
+	require.Equal(t, http.StatusInternalServerError, rec.Code, "Should report invalid policies")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(warning)))
+	require.Equal(t, http.StatusBadRequest, rec.Code, "Should keep the previous policy when reloading fails")
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reload", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code, "Should only reload on POST")
+}
+
+func TestPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xrv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	policyFile := filepath.Join(dir, "policy.json")
+
+	p, err := loadPolicy("")
+	require.NoError(t, err, "Should default to an empty policy")
+	_, _, err = p.options()
+	require.NoError(t, err, "The default policy should be valid")
+
+	writePolicy(t, policyFile, `{"checks": {"undeclared-prefix": {"severity": "warning", "paths": ["//saml:Assertion"]}}}`)
+	p, err = loadPolicy(policyFile)
+	require.NoError(t, err, "Should load valid policies")
+	opts, _, err := p.options()
+	require.NoError(t, err, "Should convert valid policies")
+	require.Len(t, opts, 1, "Should configure checks")
+
+	writePolicy(t, policyFile, `{"limits": {"max_namespace_declarations": 2, "max_children": 2, "max_depth": 2, "max_attributes": 3, "max_attribute_length": 8}}`)
+	p, err = loadPolicy(policyFile)
+	require.NoError(t, err, "Should load policies with limits")
+	opts, _, err = p.options()
+	require.NoError(t, err, "Should convert policies with limits")
+	v := validator.New(opts...)
+	require.NoError(t, v.Validate(strings.NewReader(`<Root xmlns="urn:a" xmlns:b="urn:b"/>`)), "Should allow declarations up to the limit")
+	require.Error(t, v.Validate(strings.NewReader(`<Root xmlns="urn:a" xmlns:b="urn:b" xmlns:c="urn:c"/>`)), "Should enforce the limit")
+	require.Error(t, v.Validate(strings.NewReader(`<Root><A/><A/><A/></Root>`)), "Should enforce every limit")
+	require.Error(t, v.Validate(strings.NewReader(`<Root><A><B/></A></Root>`)), "Should enforce every limit")
+	require.Error(t, v.Validate(strings.NewReader(`<Root a="1" b="2" c="3" d="4"/>`)), "Should enforce every limit")
+	require.Error(t, v.Validate(strings.NewReader(`<Root a="123456789"/>`)), "Should enforce every limit")
+
+	writePolicy(t, policyFile, `{"checks": {"namespace-declarations": {"severity": "warning"}, "depth": {"paths": ["/Other"]}},
+		"limits": {"max_namespace_declarations": 1, "max_depth": 1}}`)
+	p, err = loadPolicy(policyFile)
+	require.NoError(t, err, "Should load policies configuring limit checks")
+	opts, _, err = p.options()
+	require.NoError(t, err, "Should convert policies configuring limit checks")
+	v = validator.New(opts...)
+	errs := v.ValidateAll(strings.NewReader(`<Root xmlns="urn:a" xmlns:b="urn:b"><A/></Root>`))
+	require.Len(t, errs, 1, "Should keep the paths the policy gives limit checks")
+	require.Equal(t, validator.CheckNamespaceDeclarations, errs[0].(validator.XMLValidationError).Check)
+	require.Equal(t, validator.SeverityWarning, validator.SeverityOf(errs[0]), "Should keep the severity the policy gives limit checks")
+
+	writePolicy(t, policyFile, `{"redact": true, "checks": {"xml-base": {}}}`)
+	p, err = loadPolicy(policyFile)
+	require.NoError(t, err, "Should load policies with redaction")
+	opts, _, err = p.options()
+	require.NoError(t, err, "Should convert policies with redaction")
+	err = validator.New(opts...).Validate(strings.NewReader(`<Root xml:base="http://secret.example.com/"><A xml:base="//other.example.com/"/></Root>`))
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "example.com", "Should redact findings")
+
+	writePolicy(t, policyFile, `{"fail_on": "warning", "anomaly": {"min_text_size": 16, "max_entropy": 3}}`)
+	p, err = loadPolicy(policyFile)
+	require.NoError(t, err, "Should load policies with anomaly thresholds")
+	opts, _, err = p.options()
+	require.NoError(t, err, "Should convert policies with anomaly thresholds")
+	v = validator.New(opts...)
+	require.NoError(t, v.Validate(strings.NewReader(`<Root>aaaaaaaaaaaaaaaaaaaa</Root>`)), "Should allow character data under the thresholds")
+	require.Error(t, v.Validate(strings.NewReader(`<Root>abcdefghijklmnopqrst</Root>`)), "Should use the thresholds of the policy")
+
+	for content, message := range map[string]string{
+		`{"checks": {"no-such-check": {}}}`:               "unknown check",
+		`{"checks": {"roundtrip": {"severity": "high"}}}`: "roundtrip",
+		`{"fail_on": "high"}`:                             "high",
+	} {
+		writePolicy(t, policyFile, content)
+		p, err = loadPolicy(policyFile)
+		require.NoError(t, err, "Should load well-formed policies")
+		_, _, err = p.options()
+		require.Error(t, err, "Should reject invalid policies")
+		require.Contains(t, err.Error(), message, "Should describe the problem")
+	}
+
+	writePolicy(t, policyFile, `{"failOn": "warning"}`)
+	_, err = loadPolicy(policyFile)
+	require.Error(t, err, "Should reject unknown fields")
+}
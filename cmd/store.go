@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/mattermost/xml-roundtrip-validator/xrvhttp"
+	"github.com/mattermost/xml-roundtrip-validator/xrvstore"
+)
+
+type reportKey struct{}
+
+// recordedRequest collects the outcome of validating a request body
+type recordedRequest struct {
+	findings []error
+}
+
+// recordingMiddleware validates request bodies like xrvhttp.Middleware,
+// and records a report of every validated body in store
+func recordingMiddleware(store xrvstore.Store, v *validator.Validator, opts []xrvhttp.Option, next http.Handler) http.Handler {
+	opts = append(opts[:len(opts):len(opts)], xrvhttp.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		if recorded, ok := r.Context().Value(reportKey{}).(*recordedRequest); ok {
+			recorded.findings = append(recorded.findings, err)
+		}
+		xrvhttp.DefaultErrorHandler(w, r, err)
+	}))
+	handler := xrvhttp.Middleware(v, opts...)(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := &hashingBody{ReadCloser: r.Body, hash: sha256.New()}
+		r.Body = body
+		recorded := &recordedRequest{}
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), reportKey{}, recorded)))
+		// the digest covers the whole body as received, even if the
+		// handler didn't read all of it
+		io.Copy(ioutil.Discard, body)
+		digest := "sha256:" + hex.EncodeToString(body.hash.Sum(nil))
+		if err := store.Put(xrvstore.NewReport(digest, r.RemoteAddr, v, recorded.findings)); err != nil {
+			log.Printf("failed to store validation report: %v", err)
+		}
+	})
+}
+
+// recordFile records the report of validating a file in the store file
+func recordFile(storeFile, file string, v *validator.Validator, findings []error) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	store, err := xrvstore.OpenFileStore(storeFile)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return store.Put(xrvstore.NewReport(xrvstore.Digest(content), file, v, findings))
+}
+
+// hashingBody hashes a request body as it is read
+type hashingBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	return n, err
+}
+
+// history prints the stored reports matching its flags as JSON lines
+func history(args []string) {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	storeFile := flags.String("store", "", "File the reports are stored in")
+	digest := flags.String("digest", "", "Only print reports of the document with this digest")
+	since := flags.String("since", "", "Only print reports from this RFC 3339 time on")
+	until := flags.String("until", "", "Only print reports up to this RFC 3339 time")
+	rejected := flags.Bool("rejected", false, "Only print reports of rejected documents")
+	flags.Parse(args)
+
+	if *storeFile == "" {
+		fmt.Fprintln(os.Stderr, "Specify a store file")
+		os.Exit(1)
+	}
+	q := xrvstore.Query{Digest: *digest, Since: parseTime(*since), Until: parseTime(*until), Rejected: *rejected}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, report := range queryStore(*storeFile, q) {
+		encoder.Encode(report)
+	}
+}
+
+// report prints a summary of the stored reports since the time given by
+// its flags
+func report(args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	storeFile := flags.String("store", "", "File the reports are stored in")
+	since := flags.String("since", "", "RFC 3339 time to summarize the reports from; defaults to a day ago")
+	flags.Parse(args)
+
+	if *storeFile == "" {
+		fmt.Fprintln(os.Stderr, "Specify a store file")
+		os.Exit(1)
+	}
+	from := parseTime(*since)
+	if from.IsZero() {
+		from = time.Now().Add(-24 * time.Hour)
+	}
+	writeSummary(os.Stdout, xrvstore.Summarize(queryStore(*storeFile, xrvstore.Query{}), from))
+}
+
+// writeSummary prints a summary for humans
+func writeSummary(w io.Writer, summary xrvstore.Summary) {
+	fmt.Fprintf(w, "%d documents since %s, %d rejected\n", summary.Documents, summary.Since.Format(time.RFC3339), summary.Rejected)
+	checks := make([]string, 0, len(summary.Checks))
+	for check := range summary.Checks {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	if len(checks) > 0 {
+		fmt.Fprintln(w, "\nFindings by check:")
+	}
+	for _, check := range checks {
+		fmt.Fprintf(w, "  %s: %d\n", check, summary.Checks[check])
+	}
+	if len(summary.Changes) > 0 {
+		fmt.Fprintln(w, "\nChanged documents:")
+	}
+	for _, change := range summary.Changes {
+		fmt.Fprintf(w, "  %s:", change.Source)
+		if len(change.New) > 0 {
+			fmt.Fprintf(w, " new %s", strings.Join(change.New, ", "))
+		}
+		if len(change.Resolved) > 0 {
+			if len(change.New) > 0 {
+				fmt.Fprint(w, ";")
+			}
+			fmt.Fprintf(w, " resolved %s", strings.Join(change.Resolved, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// parseTime parses the value of an RFC 3339 time flag, exiting on errors;
+// empty values are the zero time
+func parseTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	return t
+}
+
+// queryStore returns the reports in the store file matching q, exiting on
+// errors
+func queryStore(storeFile string, q xrvstore.Query) []xrvstore.Report {
+	store, err := xrvstore.OpenFileStore(storeFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+	reports, err := store.Query(q)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	return reports
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/xml-roundtrip-validator/xrvstore"
+	"github.com/mattermost/xml-roundtrip-validator/xrvtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xrv")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	policyFile := filepath.Join(dir, "policy.json")
+	for _, policy := range []string{`{}`, `{"streaming": true}`} {
+		writePolicy(t, policyFile, policy)
+		store, err := xrvstore.OpenFileStore(filepath.Join(dir, strings.Trim(policy, `{}": `)+"store.jsonl"))
+		require.NoError(t, err)
+		defer store.Close()
+
+		upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, err := ioutil.ReadAll(r.Body); err != nil {
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+		s := &server{policyFile: policyFile, next: upstream, store: store}
+		require.NoError(t, s.reload())
+
+		for _, body := range []string{`<Root/>`, `<Root>]]></Root>`} {
+			s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		}
+		reports, err := store.Query(xrvstore.Query{})
+		require.NoError(t, err)
+		require.Len(t, reports, 2, "Should record every request with %s", policy)
+		require.False(t, reports[0].Rejected, "Should record accepted requests with %s", policy)
+		require.Equal(t, xrvstore.Digest([]byte(`<Root/>`)), reports[0].Digest, "Should digest the request body with %s", policy)
+		require.True(t, reports[1].Rejected, "Should record rejected requests with %s", policy)
+		require.Equal(t, xrvstore.Digest([]byte(`<Root>]]></Root>`)), reports[1].Digest, "Should digest the whole request body with %s", policy)
+		require.Len(t, reports[1].Findings, 1, "Should record the finding rejecting the request with %s", policy)
+		for _, report := range reports {
+			data, err := json.Marshal(report)
+			require.NoError(t, err)
+			xrvtest.AssertMatchesSchema(t, "report", data)
+		}
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	var out bytes.Buffer
+	writeSummary(&out, xrvstore.Summary{
+		Since:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Documents: 3,
+		Rejected:  1,
+		Checks:    map[string]int{"roundtrip": 2, "cdata": 1},
+		Changes: []xrvstore.Change{
+			{Source: "a.xml", New: []string{"roundtrip"}, Resolved: []string{"cdata"}},
+			{Source: "b.xml", Resolved: []string{"cdata"}},
+		},
+	})
+	require.Equal(t, `3 documents since 2020-01-01T00:00:00Z, 1 rejected
+
+Findings by check:
+  cdata: 1
+  roundtrip: 2
+
+Changed documents:
+  a.xml: new roundtrip; resolved cdata
+  b.xml: resolved cdata
+`, out.String())
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// streamVerdict ends the findings written by streamHandler
+type streamVerdict struct {
+	Valid bool `json:"valid"`
+}
+
+// streamHandler validates request bodies as they are uploaded, without
+// storing them, and writes every finding as a line of JSON as soon as it is
+// found, followed by the verdict. Uploads are only read as fast as they are
+// validated, and as findings can be written to the client.
+func streamHandler(v *validator.Validator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		enableFullDuplex(w)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		flusher, _ := w.(http.Flusher)
+		valid := true
+		v.ValidateAllFunc(r.Body, func(err error) bool {
+			valid = valid && !v.Fails(err)
+			if err := encoder.Encode(validator.FindingOf(err)); err != nil {
+				// stop validating once the client went away
+				return false
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return true
+		})
+		_ = encoder.Encode(streamVerdict{Valid: valid})
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/mattermost/xml-roundtrip-validator/xrvtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamHandler(t *testing.T) {
+	server := httptest.NewServer(streamHandler(validator.New()))
+	defer server.Close()
+
+	body, upload := io.Pipe()
+	go func() {
+		_, _ = upload.Write([]byte(`<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd">`))
+	}()
+	resp, err := http.Post(server.URL, "application/xml", body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	lines := bufio.NewScanner(resp.Body)
+
+	require.True(t, lines.Scan(), "Should write findings before the upload is complete")
+	var finding map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines.Bytes(), &finding))
+	xrvtest.AssertMatchesSchema(t, "finding", lines.Bytes())
+	require.Equal(t, "known-attacks", finding["check"], "Should write findings as they are found")
+
+	go func() {
+		_, _ = upload.Write([]byte(`]]></Root>`))
+		upload.Close()
+	}()
+	require.True(t, lines.Scan())
+	require.NoError(t, json.Unmarshal(lines.Bytes(), &finding))
+	xrvtest.AssertMatchesSchema(t, "finding", lines.Bytes())
+	require.Equal(t, "syntax", finding["check"], "Should write syntax errors")
+	require.True(t, lines.Scan())
+	require.JSONEq(t, `{"valid": false}`, lines.Text(), "Should end with the verdict")
+	xrvtest.AssertMatchesSchema(t, "result", lines.Bytes())
+	require.False(t, lines.Scan(), "Should end the response after the verdict")
+
+	resp, err = http.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode, "Should only accept uploads")
+}
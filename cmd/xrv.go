@@ -1,17 +1,70 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
 
 	validator "github.com/mattermost/xml-roundtrip-validator"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "history":
+			history(os.Args[2:])
+			return
+		case "report":
+			report(os.Args[2:])
+			return
+		case "test":
+			testGolden(os.Args[2:])
+			return
+		case "schema":
+			fmt.Print(validator.JSONSchema)
+			return
+		}
+	}
+
 	all := flag.Bool("all", false, "Validate the entire document instead of bailing out on the first error")
+	maxErrors := flag.Int("max-errors", 0, "Stop validating the entire document after this many errors, 0 for no limit")
+	snippets := flag.Int("context", 0, "Print a snippet of each error with this many bytes of context around the offending token")
+	redact := flag.Bool("redact", false, "Leave attribute values and character data out of errors")
+	failOn := flag.String("fail-on", "error", "Lowest severity that causes a non-zero exit status (warning or error)")
+	storeFile := flag.String("store", "", "File to record a report of the validation in, see xrv history")
+	listenRaw := flag.String("listen-raw", "", "Address to accept uploads on, validating them as they stream in and answering with findings as NDJSON")
+	stdioJSONRPC := flag.Bool("stdio-jsonrpc", false, "Answer JSON-RPC 2.0 validation requests on stdin instead of validating a file")
 	flag.Parse()
 
+	severity, err := validator.ParseSeverity(*failOn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	opts := []validator.Option{validator.WithFailOn(severity), validator.WithMaxErrors(*maxErrors), validator.WithSnippets(*snippets)}
+	if *redact {
+		opts = append(opts, validator.WithRedaction())
+	}
+	v := validator.New(opts...)
+
+	if *stdioJSONRPC {
+		if err := serveJSONRPC(os.Stdin, os.Stdout, v); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *listenRaw != "" {
+		log.Fatal(http.ListenAndServe(*listenRaw, streamHandler(v)))
+	}
+
 	file := flag.Arg(0)
 
 	if file == "" {
@@ -25,22 +78,64 @@ func main() {
 		os.Exit(1)
 	}
 
+	record := func(findings []error) {
+		if *storeFile == "" {
+			return
+		}
+		if err := recordFile(*storeFile, file, v, findings); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to store validation report: %v\n", err)
+		}
+	}
+
 	if *all {
-		errs := validator.ValidateAll(f)
+		errs := v.ValidateAll(f)
+		record(errs)
 		if len(errs) == 0 {
 			fmt.Println("Document validated without errors")
 			os.Exit(0)
 		}
+		failed := false
 		for _, err := range errs {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+			if validator.SeverityOf(err) == validator.SeverityWarning {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+			}
+			printSnippet(err)
+			printDiff(err)
+			failed = failed || v.Fails(err)
 		}
-		os.Exit(1)
+		if failed {
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
-	err = validator.Validate(f)
+	err = v.Validate(f)
 	if err == nil {
+		record(nil)
 		fmt.Println("Document validated without errors")
 		os.Exit(0)
 	}
+	record([]error{err})
 	fmt.Fprintf(os.Stderr, "%v\n", err)
+	printSnippet(err)
+	printDiff(err)
 	os.Exit(1)
 }
+
+// printSnippet prints the snippet of a finding, if it has one, quoted to keep
+// it on a single line
+func printSnippet(err error) {
+	validationError := validator.XMLValidationError{}
+	if errors.As(err, &validationError) && validationError.Snippet != "" {
+		fmt.Fprintf(os.Stderr, "\t%q\n", validationError.Snippet)
+	}
+}
+
+// printDiff prints how the token of a round trip mismatch changed
+func printDiff(err error) {
+	roundtripError := validator.XMLRoundtripError{}
+	if errors.As(err, &roundtripError) {
+		fmt.Fprintf(os.Stderr, "\tdiff: %s\n", roundtripError.Diff())
+	}
+}
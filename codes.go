@@ -0,0 +1,105 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+)
+
+// ErrorCode identifies the kind of a finding in a stable, machine-readable
+// way, so callers can branch on it without matching error messages. Error
+// codes are errors themselves: errors.Is(err, code) reports whether err is
+// a finding with that code.
+type ErrorCode string
+
+// Error codes of findings reported by CheckRoundtrip; findings of other
+// checks have the code of their check ID, e.g. ErrorCode(CheckKnownAttacks)
+const (
+	// ErrCodeColonInName is used for elements and attributes whose names
+	// have empty or multiple namespace prefixes
+	ErrCodeColonInName ErrorCode = "colon-in-name"
+	// ErrCodeNameMutation is used for other elements and attributes whose
+	// names or values change when round tripped
+	ErrCodeNameMutation ErrorCode = "name-mutation"
+	// ErrCodeDirectiveMutation is used for directives changed by a round trip
+	ErrCodeDirectiveMutation ErrorCode = "directive-mutation"
+	// ErrCodeCommentMutation is used for comments changed by a round trip
+	ErrCodeCommentMutation ErrorCode = "comment-mutation"
+	// ErrCodeCharDataMutation is used for character data changed by a
+	// round trip
+	ErrCodeCharDataMutation ErrorCode = "chardata-mutation"
+	// ErrCodeProcInstMutation is used for processing instructions changed
+	// by a round trip
+	ErrCodeProcInstMutation ErrorCode = "procinst-mutation"
+	// ErrCodeOverflow is used for tokens whose encoding decodes into more
+	// than one token
+	ErrCodeOverflow ErrorCode = "overflow"
+	// ErrCodeUnencodable is used for tokens encoding/xml fails to encode,
+	// or to decode once encoded
+	ErrCodeUnencodable ErrorCode = "unencodable"
+)
+
+func (code ErrorCode) Error() string {
+	return "validator: " + string(code)
+}
+
+// Code returns the error code of the round trip mismatch
+func (err XMLRoundtripError) Code() ErrorCode {
+	if len(err.Overflow) > 0 {
+		return ErrCodeOverflow
+	}
+	switch t := err.Expected.(type) {
+	case xml.StartElement:
+		if strings.Contains(t.Name.Local, ":") {
+			return ErrCodeColonInName
+		}
+		for _, attr := range t.Attr {
+			if strings.Contains(attr.Name.Local, ":") {
+				return ErrCodeColonInName
+			}
+		}
+	case xml.EndElement:
+		if strings.Contains(t.Name.Local, ":") {
+			return ErrCodeColonInName
+		}
+	case xml.Directive:
+		return ErrCodeDirectiveMutation
+	case xml.Comment:
+		return ErrCodeCommentMutation
+	case xml.CharData:
+		return ErrCodeCharDataMutation
+	case xml.ProcInst:
+		return ErrCodeProcInstMutation
+	}
+	return ErrCodeNameMutation
+}
+
+// Is reports whether target is the error code of the mismatch
+func (err XMLRoundtripError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == err.Code()
+}
+
+// Code returns the error code of the finding
+func (err XMLValidationError) Code() ErrorCode {
+	roundtripError := XMLRoundtripError{}
+	policyError := XMLPolicyError{}
+	entityError := XMLEntityExpansionError{}
+	switch {
+	case errors.As(err.err, &roundtripError):
+		return roundtripError.Code()
+	case errors.As(err.err, &policyError):
+		return policyError.Code
+	case errors.As(err.err, &entityError):
+		return entityError.Code()
+	case err.Check == CheckRoundtrip:
+		return ErrCodeUnencodable
+	}
+	return ErrorCode(err.Check)
+}
+
+// Is reports whether target is the error code of the finding
+func (err XMLValidationError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == err.Code()
+}
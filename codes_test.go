@@ -0,0 +1,38 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorCodes(t *testing.T) {
+	for code, err := range map[ErrorCode]XMLRoundtripError{
+		ErrCodeColonInName:       {Expected: xml.StartElement{Name: xml.Name{Local: "a:b"}}, Observed: xml.StartElement{Name: xml.Name{Local: "b"}}},
+		ErrCodeNameMutation:      {Expected: xml.StartElement{Name: xml.Name{Space: "a", Local: "b"}}, Observed: xml.StartElement{Name: xml.Name{Local: "b"}}},
+		ErrCodeDirectiveMutation: {Expected: xml.Directive("x"), Observed: xml.Directive("y")},
+		ErrCodeCommentMutation:   {Expected: xml.Comment("x"), Observed: xml.Comment("y")},
+		ErrCodeCharDataMutation:  {Expected: xml.CharData("x"), Observed: xml.CharData("y")},
+		ErrCodeProcInstMutation:  {Expected: xml.ProcInst{Target: "x"}, Observed: xml.ProcInst{Target: "y"}},
+		ErrCodeOverflow:          {Expected: xml.CharData("x"), Observed: xml.CharData("x"), Overflow: []byte("y")},
+	} {
+		require.Equal(t, code, err.Code(), "Should classify %v", err)
+		finding := XMLValidationError{Check: CheckRoundtrip, err: err}
+		require.Equal(t, code, finding.Code(), "Findings should have the code of their round trip error")
+		require.True(t, errors.Is(finding, code), "Findings should match their code")
+		require.False(t, errors.Is(finding, ErrCodeUnencodable), "Findings should only match their code")
+	}
+
+	finding := XMLValidationError{Check: CheckRoundtrip, err: errors.New("xml: invalid name")}
+	require.True(t, errors.Is(finding, ErrCodeUnencodable), "Encoding errors should have their own code")
+
+	errs := New().ValidateAll(strings.NewReader(`<!DOCTYPE x SYSTEM "http://example.com/x.dtd"><Root/>`))
+	require.NotEmpty(t, errs)
+	require.Equal(t, ErrorCode(CheckKnownAttacks), errs[0].(XMLValidationError).Code(), "Findings of other checks should have the code of their check")
+	require.True(t, errors.Is(errs[0], ErrorCode(CheckKnownAttacks)), "Findings of other checks should match the code of their check")
+	require.Equal(t, ErrorCode(CheckKnownAttacks), FindingOf(errs[0]).Code, "Should describe the code of findings")
+	require.Equal(t, "validator: overflow", ErrCodeOverflow.Error(), "Codes should be errors")
+}
@@ -0,0 +1,251 @@
+package validator
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// Decompressor returns a reader of the decompressed content of r
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]Decompressor{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		// HTTP's deflate content coding is actually zlib
+		"deflate":     func(r io.Reader) (io.ReadCloser, error) { return zlib.NewReader(r) },
+		"raw-deflate": func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil },
+		"identity":    func(r io.Reader) (io.ReadCloser, error) { return ioutil.NopCloser(r), nil },
+	}
+)
+
+// RegisterDecompressor makes a content coding available to
+// ValidateCompressed; encodings are case-insensitive, and registering an
+// encoding again replaces its decompressor. The zstd and br encodings are
+// provided by the xrvzstd and xrvbrotli modules, which keep this module
+// free of dependencies.
+func RegisterDecompressor(encoding string, d Decompressor) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	decompressors[strings.ToLower(encoding)] = d
+}
+
+// ErrUnsupportedEncoding is returned for content codings without a
+// registered decompressor
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// DefaultMaxDecompressedSize is the default limit on the decompressed size
+// of compressed documents
+const DefaultMaxDecompressedSize = 64 << 20
+
+// ErrDecompressedTooLarge is returned when a compressed document expands
+// past the Validator's limit, which protects against decompression bombs
+var ErrDecompressedTooLarge = errors.New("decompressed document exceeds the size limit")
+
+// DefaultMaxDecompressionRatio is the default limit on the ratio of the
+// decompressed size of compressed documents to their compressed size
+const DefaultMaxDecompressionRatio = 100
+
+// decompressionRatioFloor is the decompressed size up to which the ratio
+// isn't enforced, since small documents are harmless whatever their ratio
+const decompressionRatioFloor = 1 << 20
+
+// ErrDecompressionRatio is returned when a compressed document expands
+// more than the Validator's ratio limit allows, the shape of decompression
+// bombs, which expand by factors of a thousand and more
+var ErrDecompressionRatio = errors.New("decompressed document exceeds the expansion ratio limit")
+
+// WithMaxDecompressedSize limits the decompressed size of compressed
+// documents, in bytes; a limit of zero or less disables the limit
+func WithMaxDecompressedSize(n int64) Option {
+	return func(v *Validator) {
+		v.maxDecompressed = n
+	}
+}
+
+// WithMaxDecompressionRatio limits the ratio of the decompressed size of
+// compressed documents to their compressed size; it is only enforced past
+// the first MiB of decompressed content, and a limit of zero or less
+// disables it
+func WithMaxDecompressionRatio(ratio int64) Option {
+	return func(v *Validator) {
+		v.maxRatio = ratio
+	}
+}
+
+// WithAutoDecompression makes the Validator tell compressed documents
+// apart by their magic bytes and decompress them before validating them:
+// gzip and zlib, the deflate content coding of HTTP, are detected, as is
+// zstd if the xrvzstd module is imported. Decompression is subject to the
+// Validator's size and ratio limits, and findings are located in the
+// decompressed document. Coding without magic bytes, such as raw deflate
+// and brotli, can't be detected and need ValidateCompressed.
+func WithAutoDecompression() Option {
+	return func(v *Validator) {
+		v.autoDecompression = true
+	}
+}
+
+// ValidateCompressed validates a document compressed with the given content
+// coding, as named by the HTTP Content-Encoding header: gzip, deflate,
+// raw-deflate and identity are supported, as are encodings registered with
+// RegisterDecompressor. An empty encoding is the same as identity.
+func (v *Validator) ValidateCompressed(xmlReader io.Reader, encoding string) error {
+	r, err := v.Decompress(xmlReader, encoding)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return v.Validate(r)
+}
+
+// Decompress returns a reader of the decompressed content of a document
+// compressed with the given content coding, as supported by
+// ValidateCompressed; reading past the Validator's decompressed size limit
+// fails with ErrDecompressedTooLarge, and past its ratio limit with
+// ErrDecompressionRatio
+func (v *Validator) Decompress(r io.Reader, encoding string) (io.ReadCloser, error) {
+	if encoding == "" {
+		encoding = "identity"
+	}
+	decompressorsMu.RLock()
+	decompress, ok := decompressors[strings.ToLower(encoding)]
+	decompressorsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, encoding)
+	}
+	compressed := &countingReader{r: r}
+	decompressed, err := decompress(compressed)
+	if err != nil {
+		return nil, IOError{err}
+	}
+	if v.maxRatio > 0 {
+		decompressed = &ratioReader{ReadCloser: decompressed, compressed: compressed, ratio: v.maxRatio}
+	}
+	if v.maxDecompressed <= 0 {
+		return decompressed, nil
+	}
+	return &limitedReader{decompressed, v.maxDecompressed}, nil
+}
+
+// autoDecompress returns a reader of the decompressed content of a
+// document compressed with a coding told apart by its magic bytes, if
+// enabled with WithAutoDecompression, or of the document itself
+func (v *Validator) autoDecompress(xmlReader io.Reader) io.Reader {
+	if !v.autoDecompression {
+		return xmlReader
+	}
+	magic := make([]byte, 4)
+	n, err := io.ReadFull(xmlReader, magic)
+	magic = magic[:n]
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return io.MultiReader(bytes.NewReader(magic), &errorReader{err: err})
+	}
+	xmlReader = io.MultiReader(bytes.NewReader(magic), xmlReader)
+	encoding := detectEncoding(magic)
+	if encoding == "identity" {
+		return xmlReader
+	}
+	decompressed, err := v.Decompress(xmlReader, encoding)
+	if err != nil {
+		return &errorReader{err: err}
+	}
+	return &closingReader{ReadCloser: decompressed}
+}
+
+// detectEncoding returns the content coding of a document starting with
+// magic, as told by its first magic bytes, or identity
+func detectEncoding(magic []byte) string {
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return "gzip"
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "zstd"
+	// zlib headers use the deflate method with a window of at most 32 KiB,
+	// and their first two bytes make a multiple of 31
+	case len(magic) >= 2 && magic[0]&0x0f == 8 && magic[0]>>4 <= 7 && (uint16(magic[0])<<8|uint16(magic[1]))%31 == 0:
+		return "deflate"
+	}
+	return "identity"
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// ratioReader fails with ErrDecompressionRatio once the bytes read from it
+// exceed the bytes read from the compressed reader by more than ratio
+type ratioReader struct {
+	io.ReadCloser
+	compressed *countingReader
+	ratio      int64
+	n          int64
+}
+
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if r.n > decompressionRatioFloor && r.n > r.ratio*r.compressed.n {
+		return n, ErrDecompressionRatio
+	}
+	return n, err
+}
+
+// closingReader closes the decompressor it reads from once it fails or
+// reaches the end of the document, since validation doesn't hand the
+// caller a reader to close; decompressors may fail differently once
+// closed, so the error that ended reading is returned from then on
+type closingReader struct {
+	io.ReadCloser
+	err error
+}
+
+func (r *closingReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.ReadCloser.Read(p)
+	if err != nil {
+		r.err = err
+		r.Close()
+	}
+	return n, err
+}
+
+// limitedReader fails with ErrDecompressedTooLarge once more than n bytes
+// were read from it
+type limitedReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.n < 0 {
+		return 0, ErrDecompressedTooLarge
+	}
+	if int64(len(p)) > r.n+1 {
+		p = p[:r.n+1]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.n -= int64(n)
+	if r.n < 0 {
+		return n + int(r.n), ErrDecompressedTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cancellingReader cancels a context once its input is exhausted
+type cancellingReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n == 0 {
+		r.cancel()
+	}
+	return n, err
+}
+
+func TestValidateContext(t *testing.T) {
+	require.NoError(t, ValidateContext(context.Background(), strings.NewReader(`<Root/>`)), "Should pass on valid documents")
+	require.Error(t, ValidateContext(context.Background(), strings.NewReader(`<Root>]]></Root>`)), "Should error on invalid documents")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ValidateContext(ctx, strings.NewReader(`<Root/>`))
+	require.True(t, errors.Is(err, context.Canceled), "Should return the context's error")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	err = New().ValidateContext(ctx, io.MultiReader(
+		strings.NewReader(`<Root><Child>`),
+		&cancellingReader{strings.NewReader(""), cancel},
+		strings.NewReader(`</Child></Root>`),
+	))
+	require.True(t, errors.Is(err, context.Canceled), "Should stop validating once the context is done")
+}
+
+func TestValidateAllContext(t *testing.T) {
+	doc := `<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd"><Child/></Root>`
+	require.Empty(t, ValidateAllContext(context.Background(), strings.NewReader(doc)), "Should only report errors")
+	require.Len(t, New().ValidateAllContext(context.Background(), strings.NewReader(doc)), 1, "Should report warnings")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := New().ValidateAllContext(ctx, io.MultiReader(
+		strings.NewReader(`<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd">`),
+		&cancellingReader{strings.NewReader(""), cancel},
+		strings.NewReader(`<Child/></Root>`),
+	))
+	require.Len(t, errs, 2, "Should report the findings so far and the context's error")
+	require.Equal(t, CheckKnownAttacks, errs[0].(XMLValidationError).Check, "Should report the findings so far")
+	require.True(t, errors.Is(errs[1], context.Canceled), "Should report the context's error")
+
+	errs = ValidateAllContext(ctx, strings.NewReader(doc))
+	require.Len(t, errs, 1, "Should report the context's error")
+	require.True(t, errors.Is(errs[0], context.Canceled), "Should report the context's error")
+}
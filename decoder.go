@@ -0,0 +1,222 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// ValidatingDecoder is an xml.Decoder that validates the document while
+// decoding it, in a single pass. Before Token or RawToken return a token,
+// every token read up to its end is validated; the first finding that
+// fails validation is returned instead, and by every later call.
+//
+// Decode, DecodeElement and Skip validate the tokens they consumed before
+// returning, so a value decoded from an invalid document may have been
+// partially filled in when they fail.
+//
+// The embedded Decoder's fields, such as Strict, can be set before reading
+// the first token; a CharsetReader only affects decoding, since the
+// document is validated as it is read. Validation may read one token ahead
+// of decoding, and fail on it early.
+type ValidatingDecoder struct {
+	*xml.Decoder
+	// input is the embedded Decoder's view of the document, and d the
+	// validator's; whichever of them is ahead reads from the source
+	input *sourceCursor
+	d     *document
+	err   error
+}
+
+// NewValidatingDecoder returns a ValidatingDecoder reading from r, and
+// validating what it reads with v
+func (v *Validator) NewValidatingDecoder(r io.Reader) *ValidatingDecoder {
+	return v.newValidatingDecoder(&source{r: v.limitBytes(r)})
+}
+
+func (v *Validator) newValidatingDecoder(s *source) *ValidatingDecoder {
+	input := &sourceCursor{source: s}
+	return &ValidatingDecoder{
+		Decoder: xml.NewDecoder(input),
+		input:   input,
+		d:       v.newDocumentFrom(&sourceCursor{source: s}, true),
+	}
+}
+
+// NewValidatingDecoder is like Validator.NewValidatingDecoder, using Default
+func NewValidatingDecoder(r io.Reader) *ValidatingDecoder {
+	return Default().NewValidatingDecoder(r)
+}
+
+// Unmarshal is like xml.Unmarshal, but validates the whole document in the
+// same pass, including anything following the element decoded into out;
+// out may have been partially filled in when validation fails
+func (v *Validator) Unmarshal(data []byte, out interface{}) error {
+	// the source already holds every byte, so it is never read from
+	return v.decode(v.newValidatingDecoder(&source{data: data, err: io.EOF}), out)
+}
+
+// Decode is like Unmarshal, but reads the document from r
+func (v *Validator) Decode(r io.Reader, out interface{}) error {
+	return v.decode(v.NewValidatingDecoder(r), out)
+}
+
+func (v *Validator) decode(vd *ValidatingDecoder, out interface{}) error {
+	if err := vd.Decode(out); err != nil {
+		return err
+	}
+	for {
+		if _, err := vd.RawToken(); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Unmarshal is like Validator.Unmarshal, using Default
+func Unmarshal(data []byte, out interface{}) error {
+	return Default().Unmarshal(data, out)
+}
+
+// Decode is like Validator.Decode, using Default
+func Decode(r io.Reader, out interface{}) error {
+	return Default().Decode(r, out)
+}
+
+// Token is like xml.Decoder.Token, but validates the token first
+func (vd *ValidatingDecoder) Token() (xml.Token, error) {
+	token, err := vd.Decoder.Token()
+	if verr := vd.validate(); verr != nil {
+		return nil, verr
+	}
+	return token, readError(err)
+}
+
+// RawToken is like xml.Decoder.RawToken, but validates the token first
+func (vd *ValidatingDecoder) RawToken() (xml.Token, error) {
+	token, err := vd.Decoder.RawToken()
+	if verr := vd.validate(); verr != nil {
+		return nil, verr
+	}
+	return token, readError(err)
+}
+
+// Decode is like xml.Decoder.Decode, but validates the tokens it consumed
+func (vd *ValidatingDecoder) Decode(v interface{}) error {
+	return vd.DecodeElement(v, nil)
+}
+
+// DecodeElement is like xml.Decoder.DecodeElement, but validates the
+// tokens it consumed
+func (vd *ValidatingDecoder) DecodeElement(v interface{}, start *xml.StartElement) error {
+	err := vd.Decoder.DecodeElement(v, start)
+	if verr := vd.validate(); verr != nil {
+		return verr
+	}
+	return err
+}
+
+// Skip is like xml.Decoder.Skip, but validates the tokens it consumed
+func (vd *ValidatingDecoder) Skip() error {
+	err := vd.Decoder.Skip()
+	if verr := vd.validate(); verr != nil {
+		return verr
+	}
+	return err
+}
+
+// validate validates every token up to the last byte consumed by the
+// embedded Decoder, returning the first finding that fails validation
+func (vd *ValidatingDecoder) validate() error {
+	for vd.err == nil && vd.d.offset < int64(vd.input.pos) {
+		_, findings, err := vd.d.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			vd.err = err
+			break
+		}
+		for _, finding := range findings {
+			vd.d.record(finding.Check, finding.Severity, finding)
+			if vd.d.v.Fails(finding) {
+				vd.err = finding
+				break
+			}
+		}
+	}
+	return vd.err
+}
+
+// maxEmptyReads is the number of reads returning neither data nor an error
+// tolerated in a row, like bufio does
+const maxEmptyReads = 100
+
+// sourceChunkSize is the number of bytes a source reads at once
+const sourceChunkSize = 4096
+
+// source is a document read by several cursors at their own pace; it keeps
+// every byte read, since findings are located in the whole document
+type source struct {
+	r    io.Reader
+	data []byte
+	err  error
+}
+
+// fill reads the next chunk of the document, returning an error if it
+// can't read any byte
+func (s *source) fill() error {
+	if s.err != nil {
+		return s.err
+	}
+	if cap(s.data)-len(s.data) < sourceChunkSize {
+		data := make([]byte, len(s.data), 2*cap(s.data)+sourceChunkSize)
+		copy(data, s.data)
+		s.data = data
+	}
+	chunk := s.data[len(s.data) : len(s.data)+sourceChunkSize]
+	n, err := 0, error(nil)
+	for i := 0; n == 0 && err == nil; i++ {
+		if i == maxEmptyReads {
+			return io.ErrNoProgress
+		}
+		n, err = s.r.Read(chunk)
+	}
+	s.data = s.data[:len(s.data)+n]
+	s.err = err
+	if n == 0 {
+		return err
+	}
+	return nil
+}
+
+// sourceCursor reads a source from its own position
+type sourceCursor struct {
+	*source
+	pos int
+}
+
+func (c *sourceCursor) ReadByte() (byte, error) {
+	if c.pos == len(c.data) {
+		if err := c.fill(); err != nil {
+			return 0, err
+		}
+	}
+	c.pos++
+	return c.data[c.pos-1], nil
+}
+
+func (c *sourceCursor) Read(p []byte) (int, error) {
+	if c.pos == len(c.data) {
+		if err := c.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.data[c.pos:])
+	c.pos += n
+	return n, nil
+}
+
+func (c *sourceCursor) consumed() []byte {
+	return c.data[:c.pos]
+}
@@ -0,0 +1,121 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatingDecoder(t *testing.T) {
+	doc := `<Root xmlns="urn:x"><Child attr="value">text</Child><!-- comment --></Root>`
+	expected := []xml.Token{}
+	decoder := xml.NewDecoder(strings.NewReader(doc))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			require.True(t, errors.Is(err, io.EOF))
+			break
+		}
+		expected = append(expected, xml.CopyToken(token))
+	}
+	tokens := []xml.Token{}
+	vd := NewValidatingDecoder(&chunkedReader{[]string{`<Root xmlns="urn:x"><Chi`, `ld attr="value">te`, `xt</Child><!-- comment --></Root>`}})
+	for {
+		token, err := vd.Token()
+		if err != nil {
+			require.True(t, errors.Is(err, io.EOF), "Should pass valid documents")
+			break
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+	require.Equal(t, expected, tokens, "Should return the tokens xml.Decoder returns")
+
+	registerTestCheck(t, "test-forbidden", "test", SeverityError, func(d *document, token xml.Token) error {
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Forbidden" {
+			return errors.New("forbidden element")
+		}
+		return nil
+	})
+	vd = New().NewValidatingDecoder(strings.NewReader(`<Root><Child/><Forbidden/></Root>`))
+	for _, name := range []string{"Root", "Child", "Child"} {
+		token, err := vd.RawToken()
+		require.NoError(t, err, "Should return valid tokens")
+		switch token := token.(type) {
+		case xml.StartElement:
+			require.Equal(t, name, token.Name.Local)
+		case xml.EndElement:
+			require.Equal(t, name, token.Name.Local)
+		}
+	}
+	_, err := vd.RawToken()
+	require.Error(t, err, "Should fail on the invalid token")
+	require.Equal(t, CheckID("test-forbidden"), err.(XMLValidationError).Check, "Should return the failing finding")
+	_, err = vd.RawToken()
+	require.Error(t, err, "Should keep returning the error")
+
+	var root struct {
+		Child     string `xml:"Child"`
+		Forbidden string `xml:"Forbidden"`
+	}
+	require.NoError(t, New().NewValidatingDecoder(strings.NewReader(`<Root><Child>text</Child></Root>`)).Decode(&root), "Should decode valid documents")
+	require.Equal(t, "text", root.Child)
+	err = New().NewValidatingDecoder(strings.NewReader(`<Root><Forbidden>text</Forbidden></Root>`)).Decode(&root)
+	require.Equal(t, CheckID("test-forbidden"), err.(XMLValidationError).Check, "Should fail decoding invalid documents")
+}
+
+// readCounter counts the reads of the reader it wraps
+type readCounter struct {
+	io.Reader
+	reads int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(p)
+}
+
+func TestValidatingDecoderReads(t *testing.T) {
+	doc := "<Root>" + strings.Repeat("<Child>text</Child>", 10000) + "</Root>"
+	r := &readCounter{Reader: strings.NewReader(doc)}
+	var root struct {
+		Child []string `xml:"Child"`
+	}
+	require.NoError(t, New().Decode(r, &root))
+	require.Len(t, root.Child, 10000)
+	require.LessOrEqual(t, r.reads, len(doc)/sourceChunkSize+2, "Should read the document in chunks")
+}
+
+func TestUnmarshal(t *testing.T) {
+	type root struct {
+		Child string `xml:"Child"`
+	}
+	var out root
+	require.NoError(t, Unmarshal([]byte(`<Root><Child>text</Child></Root>`), &out), "Should unmarshal valid documents")
+	require.Equal(t, "text", out.Child)
+	out = root{}
+	require.NoError(t, Decode(strings.NewReader(`<Root><Child>text</Child></Root>`), &out), "Should decode valid documents")
+	require.Equal(t, "text", out.Child)
+
+	registerTestCheck(t, "test-forbidden", "test", SeverityError, func(d *document, token xml.Token) error {
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Forbidden" {
+			return errors.New("forbidden element")
+		}
+		return nil
+	})
+	for _, doc := range []string{
+		`<Root><Forbidden/><Child>text</Child></Root>`,
+		`<Root><Child>text</Child></Root><Forbidden/>`,
+	} {
+		err := New().Unmarshal([]byte(doc), &out)
+		require.Error(t, err, "Should fail on invalid documents")
+		require.Equal(t, CheckID("test-forbidden"), err.(XMLValidationError).Check, "Should return the failing finding")
+		err = New().Decode(strings.NewReader(doc), &out)
+		require.Equal(t, CheckID("test-forbidden"), err.(XMLValidationError).Check, "Should return the failing finding")
+	}
+	var syntaxError *xml.SyntaxError
+	require.True(t, errors.As(Unmarshal([]byte(`<Root>`), &out), &syntaxError), "Should return decoding errors")
+}
@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table used to diff the differing
+// middle of two tokens; larger differences are shown as a single change
+const maxDiffCells = 1 << 20
+
+// Diff renders a character-level diff from the expected token to the
+// observed one, both written as markup the way they were tokenized, with
+// removed text enclosed in [-...-] and added text in {+...+}, e.g.
+// `<Root [-x:-]a="1">` for a prefix lost in the round trip; overflow is
+// shown as added after the observed token
+func (err XMLRoundtripError) Diff() string {
+	return diffStrings(formatToken(err.Expected), formatToken(err.Observed)+string(err.Overflow))
+}
+
+// diffStrings renders a character-level diff from a to b
+func diffStrings(a, b string) string {
+	x, y := []rune(a), []rune(b)
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	diff := &strings.Builder{}
+	diff.WriteString(string(x[:prefix]))
+	writeDiff(diff, x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])
+	diff.WriteString(string(x[len(x)-suffix:]))
+	return diff.String()
+}
+
+// writeDiff writes the diff between x and y along their longest common
+// subsequence, grouping consecutive changes
+func writeDiff(diff *strings.Builder, x, y []rune) {
+	if len(x)*len(y) > maxDiffCells {
+		writeChange(diff, x, y)
+		return
+	}
+	// common[i][j] is the length of the longest common subsequence of
+	// x[i:] and y[j:]
+	common := make([][]int, len(x)+1)
+	for i := range common {
+		common[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				common[i][j] = common[i+1][j+1] + 1
+			case common[i+1][j] >= common[i][j+1]:
+				common[i][j] = common[i+1][j]
+			default:
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+	var removed, added []rune
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			writeChange(diff, removed, added)
+			removed, added = removed[:0], added[:0]
+			diff.WriteRune(x[i])
+			i++
+			j++
+		case j == len(y) || (i < len(x) && common[i+1][j] >= common[i][j+1]):
+			removed = append(removed, x[i])
+			i++
+		default:
+			added = append(added, y[j])
+			j++
+		}
+	}
+	writeChange(diff, removed, added)
+}
+
+// writeChange writes removed and added text, if any
+func writeChange(diff *strings.Builder, removed, added []rune) {
+	if len(removed) > 0 {
+		diff.WriteString("[-" + string(removed) + "-]")
+	}
+	if len(added) > 0 {
+		diff.WriteString("{+" + string(added) + "+}")
+	}
+}
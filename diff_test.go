@@ -0,0 +1,40 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	cases := []struct {
+		err      XMLRoundtripError
+		expected string
+	}{
+		{
+			XMLRoundtripError{Expected: tokenize(t, `<Root a="1" b="2">`), Observed: tokenize(t, `<Root a="1" b="2">`)},
+			`<Root a="1" b="2">`,
+		},
+		{
+			XMLRoundtripError{Expected: tokenize(t, `<Root id="a" b="2">`), Observed: tokenize(t, `<Root id="b" b="3">`)},
+			`<Root id="[-a-]{+b+}" b="[-2-]{+3+}">`,
+		},
+		{
+			XMLRoundtripError{Expected: tokenize(t, `<Root a="1" b="2">`), Observed: tokenize(t, `<Root a="1">`)},
+			`<Root a="1"[- b="2"-]>`,
+		},
+		{
+			XMLRoundtripError{Expected: xml.Comment("x"), Observed: xml.Comment("x"), Overflow: []byte("-->")},
+			`<!--x-->{+-->+}`,
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expected, c.err.Diff(), "Diff of %v", c.err)
+	}
+
+	a, b := strings.Repeat("a", 2000), strings.Repeat("b", 2000)
+	require.Equal(t, "x[-"+a+"-]{+"+b+"+}x", diffStrings("x"+a+"x", "x"+b+"x"),
+		"Large differences should be shown as a single change")
+}
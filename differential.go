@@ -0,0 +1,124 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// TokenParser is a second XML parser, which CheckDifferential compares the
+// tokens of encoding/xml with. Bindings to other parsers, such as libxml2
+// through cgo, belong in modules of their own, so this package keeps
+// building without them.
+type TokenParser interface {
+	// ParseToken parses the token at the start of markup, which holds the
+	// bytes of a single token as delimited by encoding/xml, returning it
+	// the way xml.Decoder.RawToken would, with character data decoded and
+	// names split at their first colon, along with the number of bytes it
+	// spans
+	ParseToken(markup []byte) (xml.Token, int, error)
+}
+
+// DifferentialConfig configures CheckDifferential
+type DifferentialConfig struct {
+	CheckConfig
+	// Parser is the parser compared with encoding/xml, StrictTokenizer if
+	// unset
+	Parser TokenParser
+}
+
+// WithDifferentialCheck enables and configures CheckDifferential
+func WithDifferentialCheck(cfg DifferentialConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckDifferential] = cfg.CheckConfig
+		v.differential = cfg
+	}
+}
+
+// XMLDifferentialError is returned when a second parser reads a token
+// differently than encoding/xml
+type XMLDifferentialError struct {
+	// Token is the token as read by encoding/xml
+	Token xml.Token
+	// Other is the token as read by the second parser, or nil if it failed
+	// to read one
+	Other xml.Token
+	// Err is the reason the second parser failed to read the token, or
+	// read it with different bounds, if any
+	Err error
+}
+
+func (err XMLDifferentialError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("parser differential: second parser fails on %q: %v", formatToken(err.Token), err.Err)
+	}
+	return fmt.Sprintf("parser differential: encoding/xml reads %q, second parser reads %q",
+		formatToken(err.Token), formatToken(err.Other))
+}
+
+func (err XMLDifferentialError) Unwrap() error {
+	return err.Err
+}
+
+// newDifferentialCheck creates the per-document state of CheckDifferential
+func newDifferentialCheck(v *Validator) tokenCheck {
+	parser := v.differential.Parser
+	if parser == nil {
+		parser = StrictTokenizer{}
+	}
+	return func(d *document, token xml.Token) error {
+		markup := d.raw()
+		// the end of an empty-element tag spans no bytes of its own
+		if len(markup) == 0 {
+			return nil
+		}
+		other, n, err := parser.ParseToken(markup)
+		if err != nil {
+			return XMLDifferentialError{Token: xml.CopyToken(token), Err: err}
+		}
+		if n != len(markup) {
+			return XMLDifferentialError{
+				Token: xml.CopyToken(token),
+				Other: other,
+				Err:   fmt.Errorf("token spans %d bytes instead of %d", n, len(markup)),
+			}
+		}
+		if !sameToken(token, other) {
+			return XMLDifferentialError{Token: xml.CopyToken(token), Other: other}
+		}
+		return nil
+	}
+}
+
+// sameToken reports whether two raw tokens are identical
+func sameToken(a, b xml.Token) bool {
+	switch t1 := a.(type) {
+	case xml.StartElement:
+		t2, ok := b.(xml.StartElement)
+		if !ok || t1.Name != t2.Name || len(t1.Attr) != len(t2.Attr) {
+			return false
+		}
+		for i := range t1.Attr {
+			if t1.Attr[i] != t2.Attr[i] {
+				return false
+			}
+		}
+		return true
+	case xml.EndElement:
+		t2, ok := b.(xml.EndElement)
+		return ok && t1.Name == t2.Name
+	case xml.CharData:
+		t2, ok := b.(xml.CharData)
+		return ok && bytes.Equal(t1, t2)
+	case xml.Comment:
+		t2, ok := b.(xml.Comment)
+		return ok && bytes.Equal(t1, t2)
+	case xml.ProcInst:
+		t2, ok := b.(xml.ProcInst)
+		return ok && t1.Target == t2.Target && bytes.Equal(t1.Inst, t2.Inst)
+	case xml.Directive:
+		t2, ok := b.(xml.Directive)
+		return ok && bytes.Equal(t1, t2)
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// strictDecoderParser reads tokens with a strict xml.Decoder, which rejects
+// constructs the validator's lenient decoder lets through
+type strictDecoderParser struct {
+	// rename, if set, renames start elements to simulate a disagreement
+	rename string
+}
+
+func (p strictDecoderParser) ParseToken(markup []byte) (xml.Token, int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(markup))
+	token, err := decoder.RawToken()
+	if err != nil {
+		return nil, 0, err
+	}
+	if start, ok := token.(xml.StartElement); ok && p.rename != "" {
+		start.Name.Local = p.rename
+		token = start
+	}
+	return xml.CopyToken(token), int(decoder.InputOffset()), nil
+}
+
+func TestDifferential(t *testing.T) {
+	v := New(WithDifferentialCheck(DifferentialConfig{Parser: strictDecoderParser{}}))
+	require.NoError(t, v.Validate(strings.NewReader(`<?xml version="1.0"?><x:Root xmlns:x="urn:x" a="1"><!--c--><?pi x?>text &amp; more<![CDATA[<>]]></x:Root>`)),
+		"Should pass when both parsers agree")
+
+	errs := v.ValidateAll(strings.NewReader(`<Root>&custom;</Root>`))
+	require.Len(t, errs, 1, "Should report tokens the second parser rejects")
+	var differentialError XMLDifferentialError
+	require.True(t, errors.As(errs[0], &differentialError))
+	require.Equal(t, xml.CharData("&custom;"), differentialError.Token)
+	require.Nil(t, differentialError.Other)
+	require.Error(t, differentialError.Err)
+	require.Equal(t, CheckDifferential, errs[0].(XMLValidationError).Check)
+
+	v = New(WithDifferentialCheck(DifferentialConfig{Parser: strictDecoderParser{rename: "Other"}}))
+	errs = v.ValidateAll(strings.NewReader(`<Root/>`))
+	require.Len(t, errs, 1, "Should report tokens the second parser reads differently")
+	require.True(t, errors.As(errs[0], &differentialError))
+	require.Equal(t, xml.StartElement{Name: xml.Name{Local: "Other"}, Attr: []xml.Attr{}}, differentialError.Other)
+	require.NoError(t, differentialError.Err)
+	require.Contains(t, errs[0].Error(), `encoding/xml reads "<Root>", second parser reads "<Other>"`)
+
+	v = New(WithDifferentialCheck(DifferentialConfig{Parser: strictDecoderParser{}}), WithRedaction())
+	errs = v.ValidateAll(strings.NewReader(`<Root>&secret;</Root>`))
+	require.Len(t, errs, 1)
+	require.NotContains(t, errs[0].Error(), "secret", "Should redact the tokens of differentials")
+
+	errs = New(WithDifferentialCheck(DifferentialConfig{})).ValidateAll(strings.NewReader(`<Root>&custom;</Root>`))
+	require.Len(t, errs, 1, "Should compare with StrictTokenizer by default")
+}
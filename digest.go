@@ -0,0 +1,151 @@
+package validator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"hash"
+	"io"
+	"sort"
+)
+
+// TokenStreamDigest is like the Validator's TokenStreamDigest, validating
+// the document with Default
+func TokenStreamDigest(xmlReader io.Reader) ([]byte, error) {
+	return Default().TokenStreamDigest(xmlReader)
+}
+
+// TokenStreamDigest validates the document and returns the SHA-256 digest
+// of its canonicalized token sequence, returning the first finding that
+// fails validation instead. Documents differing only in how they are
+// serialized have the same digest, so systems can tell whether a document
+// kept its meaning across hops that reserialize it.
+//
+// Tokens are canonicalized the way encoding/xml reads them: names are
+// resolved into namespace URIs and namespace declarations dropped, so the
+// choice of prefixes doesn't matter; attributes are sorted by namespace URI
+// and local name; adjacent character data is merged, whether written as
+// text, CDATA sections or references; empty elements are written as start
+// and end elements; and comments, the XML declaration and character data
+// outside the root element are dropped. Attribute values whose meaning
+// depends on prefixes, like xsi:type, are taken as they are.
+//
+// Each remaining token is hashed as a tag byte followed by its fields, each
+// field written as its length in bytes, as a big-endian uint64, followed by
+// its UTF-8 bytes:
+//
+//	'S' namespace, local name, then attribute count as a big-endian uint64
+//	    and the namespace, local name and value of each attribute
+//	'E' namespace, local name
+//	'T' text
+//	'P' target, instruction
+//	'D' directive
+func (v *Validator) TokenStreamDigest(xmlReader io.Reader) ([]byte, error) {
+	d := v.newDocument(xmlReader)
+	defer d.release()
+	digest := &tokenDigest{hash: sha256.New()}
+	for {
+		token, findings, err := d.next()
+		if errors.Is(err, io.EOF) {
+			digest.flushText()
+			return digest.hash.Sum(nil), nil
+		} else if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			d.record(finding.Check, finding.Severity, finding)
+			if v.Fails(finding) {
+				return nil, finding
+			}
+		}
+		digest.add(d, token)
+	}
+}
+
+// tokenDigest hashes a canonicalized token sequence
+type tokenDigest struct {
+	hash hash.Hash
+	// text holds the character data read since the last other token
+	text []byte
+}
+
+// add hashes a token read by the document, resolving its names
+func (digest *tokenDigest) add(d *document, token xml.Token) {
+	switch t := token.(type) {
+	case xml.CharData:
+		if len(d.path) > 0 {
+			digest.text = append(digest.text, t...)
+		}
+		return
+	case xml.Comment:
+		return
+	case xml.ProcInst:
+		if t.Target == "xml" {
+			return
+		}
+	}
+	digest.flushText()
+	switch t := token.(type) {
+	case xml.StartElement:
+		resolved := d.resolveToken(t).(xml.StartElement)
+		attrs := make([]xml.Attr, 0, len(t.Attr))
+		for i, attr := range t.Attr {
+			if _, ok := declaredPrefix(attr); !ok {
+				attrs = append(attrs, resolved.Attr[i])
+			}
+		}
+		sort.SliceStable(attrs, func(i, j int) bool {
+			if attrs[i].Name.Space != attrs[j].Name.Space {
+				return attrs[i].Name.Space < attrs[j].Name.Space
+			}
+			return attrs[i].Name.Local < attrs[j].Name.Local
+		})
+		digest.writeTag('S')
+		digest.writeString(resolved.Name.Space)
+		digest.writeString(resolved.Name.Local)
+		digest.writeLength(len(attrs))
+		for _, attr := range attrs {
+			digest.writeString(attr.Name.Space)
+			digest.writeString(attr.Name.Local)
+			digest.writeString(attr.Value)
+		}
+	case xml.EndElement:
+		resolved := d.resolveToken(t).(xml.EndElement)
+		digest.writeTag('E')
+		digest.writeString(resolved.Name.Space)
+		digest.writeString(resolved.Name.Local)
+	case xml.ProcInst:
+		digest.writeTag('P')
+		digest.writeString(t.Target)
+		digest.writeString(string(t.Inst))
+	case xml.Directive:
+		digest.writeTag('D')
+		digest.writeString(string(t))
+	}
+}
+
+// flushText hashes the character data merged since the last other token
+func (digest *tokenDigest) flushText() {
+	if len(digest.text) == 0 {
+		return
+	}
+	digest.writeTag('T')
+	digest.writeString(string(digest.text))
+	digest.text = digest.text[:0]
+}
+
+func (digest *tokenDigest) writeTag(tag byte) {
+	digest.hash.Write([]byte{tag})
+}
+
+func (digest *tokenDigest) writeLength(n int) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(n))
+	digest.hash.Write(length[:])
+}
+
+func (digest *tokenDigest) writeString(s string) {
+	digest.writeLength(len(s))
+	io.WriteString(digest.hash, s)
+}
@@ -0,0 +1,36 @@
+package validator
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenStreamDigest(t *testing.T) {
+	digest := func(doc string) string {
+		sum, err := TokenStreamDigest(strings.NewReader(doc))
+		require.NoError(t, err, "Should digest valid documents")
+		return hex.EncodeToString(sum)
+	}
+
+	original := digest(`<a:Root xmlns:a="urn:a" a:y="2" x="1"><Child/>text &amp; more</a:Root>`)
+	for _, equivalent := range []string{
+		`<?xml version="1.0"?>` + "\n" + `<b:Root xmlns:b="urn:a" x='1' b:y="2"><Child></Child>text <![CDATA[&]]> more</b:Root>`,
+		`<Root xmlns="urn:a" x="1" xmlns:c="urn:a" c:y="2"><Child xmlns=""/><!-- comment -->text &#38; more</Root>` + "\n",
+	} {
+		require.Equal(t, original, digest(equivalent), "Serialization differences shouldn't change the digest of %s", equivalent)
+	}
+	for _, different := range []string{
+		`<a:Root xmlns:a="urn:b" a:y="2" x="1"><Child/>text &amp; more</a:Root>`,
+		`<a:Root xmlns:a="urn:a" a:y="2" x="1"><Child/>text &amp;  more</a:Root>`,
+		`<a:Root xmlns:a="urn:a" a:y="2" x="1"><Child/><?pi?>text &amp; more</a:Root>`,
+		`<a:Root xmlns:a="urn:a" y="2" x="1"><Child/>text &amp; more</a:Root>`,
+	} {
+		require.NotEqual(t, original, digest(different), "Changes in meaning should change the digest of %s", different)
+	}
+
+	_, err := TokenStreamDigest(strings.NewReader(`<Root>]]></Root>`))
+	require.Error(t, err, "Should fail on invalid documents")
+}
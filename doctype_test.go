@@ -0,0 +1,49 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctype(t *testing.T) {
+	internal := `<!DOCTYPE Root [<!ELEMENT Root ANY>]><Root/>`
+	external := `<!DOCTYPE Root SYSTEM "http://example.com/root.dtd"><Root/>`
+	externalEntity := `<!DOCTYPE Root [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><Root>&xxe;</Root>`
+	externalParameterEntity := `<!DOCTYPE Root [<!ENTITY % remote PUBLIC "-//X//Y" "http://example.com/x.ent">%remote;]><Root/>`
+	notation := `<!DOCTYPE Root [<!NOTATION gif SYSTEM "image/gif">]><Root/>`
+	externalCodes := map[string]ErrorCode{
+		external:                ErrCodeExternalDoctype,
+		externalEntity:          ErrCodeExternalEntity,
+		externalParameterEntity: ErrCodeExternalParameterEntity,
+		notation:                ErrCodeExternalDoctype,
+	}
+
+	v := New(WithDoctypeCheck(DoctypeConfig{}), WithCheck(CheckKnownAttacks, CheckConfig{Disabled: true}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root/>`)), "Should allow documents without directives")
+	err := v.Validate(strings.NewReader(internal))
+	require.True(t, errors.Is(err, ErrCodeDoctype), "Should reject any document type declaration")
+	require.Equal(t, ErrCodeDoctype, FindingOf(err).Code)
+	require.Contains(t, err.Error(), "doctype not allowed: document type declaration")
+	require.True(t, errors.Is(v.Validate(strings.NewReader(`<!ELEMENT Root ANY><Root/>`)), ErrCodeDoctype), "Should reject other directives")
+	for doc, code := range externalCodes {
+		err = v.Validate(strings.NewReader(doc))
+		require.True(t, errors.Is(err, code), "Should tell external identifiers apart in %s", doc)
+		var policyError XMLPolicyError
+		require.True(t, errors.As(err, &policyError))
+		require.Equal(t, code, policyError.Code)
+		require.Equal(t, code, FindingOf(err).Code)
+	}
+
+	v = New(WithDoctypeCheck(DoctypeConfig{ExternalOnly: true}), WithCheck(CheckKnownAttacks, CheckConfig{Disabled: true}))
+	require.NoError(t, v.Validate(strings.NewReader(internal)), "Should allow self-contained declarations")
+	for doc, code := range externalCodes {
+		require.True(t, errors.Is(v.Validate(strings.NewReader(doc)), code), "Should reject external identifiers in %s", doc)
+	}
+
+	caps := New(WithDoctypeCheck(DoctypeConfig{})).Capabilities()
+	require.Equal(t, CheckDoctype, caps.Checks[len(caps.Checks)-1].ID)
+	require.Equal(t, []ErrorCode{ErrCodeDoctype, ErrCodeExternalDoctype, ErrCodeExternalEntity, ErrCodeExternalParameterEntity}, caps.Checks[len(caps.Checks)-1].Codes)
+}
@@ -0,0 +1,189 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Node is a node of a ValidatedDocument, either an *Element or a *Text
+type Node interface {
+	node()
+}
+
+// Element is an element of a ValidatedDocument
+type Element struct {
+	// Name and Attr are the element's name and attributes as written in
+	// the document, without namespace resolution
+	Name xml.Name
+	Attr []xml.Attr
+	// Parent is the enclosing element, or nil for top-level elements
+	Parent *Element
+	// Children holds the element's content in document order
+	Children []Node
+	// Start and End are the byte offsets of the start of the element's
+	// start tag and the end of its end tag in ValidatedDocument.Bytes
+	Start, End int64
+	// Line and Column are the 1-based position of the element's start tag
+	Line, Column int64
+	// Base is the effective base URI established by xml:base attributes
+	// on the element and its ancestors, or an empty string if there are none
+	Base string
+}
+
+// Text is a run of character data in a ValidatedDocument
+type Text struct {
+	Data []byte
+	// Start and End are the byte offsets of the raw text, including any
+	// entity references or CDATA markup, in ValidatedDocument.Bytes
+	Start, End int64
+}
+
+func (*Element) node() {}
+func (*Text) node()    {}
+
+// ValidatedDocument is a lightweight tree built exclusively from tokens that
+// passed validation; comments, directives and processing instructions are
+// validated, but not retained
+type ValidatedDocument struct {
+	// Children holds the top-level nodes of the document
+	Children []Node
+	// Bytes holds the document exactly as it was read and validated
+	Bytes []byte
+}
+
+// Parse validates the document and builds a ValidatedDocument from it,
+// returning the first finding that fails validation instead
+func (v *Validator) Parse(xmlReader io.Reader) (*ValidatedDocument, error) {
+	doc := &ValidatedDocument{}
+	d := v.newDocument(xmlReader)
+	var current *Element
+	for {
+		start := d.offset
+		token, findings, err := d.next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			d.record(finding.Check, finding.Severity, finding)
+			if v.Fails(finding) {
+				return nil, finding
+			}
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			line, column := d.position(start)
+			el := &Element{
+				Name:   t.Name,
+				Attr:   t.Copy().Attr,
+				Parent: current,
+				Start:  start,
+				Line:   line,
+				Column: column,
+				Base:   d.base(),
+			}
+			doc.appendChild(current, el)
+			current = el
+		case xml.EndElement:
+			if current != nil {
+				current.End = d.offset
+				current = current.Parent
+			}
+		case xml.CharData:
+			doc.appendChild(current, &Text{Data: t.Copy(), Start: start, End: d.offset})
+		}
+	}
+	doc.Bytes = d.input.consumed()
+	// elements left open by the end of the document extend to its end
+	for ; current != nil; current = current.Parent {
+		current.End = int64(len(doc.Bytes))
+	}
+	return doc, nil
+}
+
+func (doc *ValidatedDocument) appendChild(parent *Element, child Node) {
+	if parent == nil {
+		doc.Children = append(doc.Children, child)
+	} else {
+		parent.Children = append(parent.Children, child)
+	}
+}
+
+// Root returns the first top-level element of the document, or nil if
+// there is none
+func (doc *ValidatedDocument) Root() *Element {
+	for _, child := range doc.Children {
+		if el, ok := child.(*Element); ok {
+			return el
+		}
+	}
+	return nil
+}
+
+// Find returns every element whose path matches the pattern, in document
+// order; see CheckConfig.Paths for the pattern syntax
+func (doc *ValidatedDocument) Find(pattern string) []*Element {
+	var found []*Element
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, node := range nodes {
+			if el, ok := node.(*Element); ok {
+				if matchPath(pattern, el.Path()) {
+					found = append(found, el)
+				}
+				walk(el.Children)
+			}
+		}
+	}
+	walk(doc.Children)
+	return found
+}
+
+// Raw returns the original bytes of the element, from the start of its
+// start tag to the end of its end tag
+func (doc *ValidatedDocument) Raw(el *Element) []byte {
+	return doc.Bytes[el.Start:el.End]
+}
+
+// Path returns the names of the element and its ancestors, starting with
+// the top-level element
+func (el *Element) Path() []xml.Name {
+	var path []xml.Name
+	for ; el != nil; el = el.Parent {
+		path = append([]xml.Name{el.Name}, path...)
+	}
+	return path
+}
+
+// Attribute returns the value of the attribute with the given name, which
+// is written as in the document, including any namespace prefix
+func (el *Element) Attribute(name string) (string, bool) {
+	for _, attr := range el.Attr {
+		if qualifiedName(attr.Name) == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Text returns the concatenated character data of the element and its
+// descendants
+func (el *Element) Text() string {
+	buffer := &bytes.Buffer{}
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case *Element:
+				walk(n.Children)
+			case *Text:
+				buffer.Write(n.Data)
+			}
+		}
+	}
+	walk(el.Children)
+	return buffer.String()
+}
@@ -0,0 +1,54 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	xmlString := "<samlp:Response ID=\"r\">\n  <saml:Assertion ID=\"a\"><saml:Subject>user<!-- x -->name</saml:Subject></saml:Assertion>\n  <saml:Assertion ID=\"b\"/>\n</samlp:Response>"
+	doc, err := New().Parse(strings.NewReader(xmlString))
+	require.NoError(t, err, "Should parse valid XML documents")
+	require.Equal(t, []byte(xmlString), doc.Bytes, "Document should retain the validated bytes")
+
+	root := doc.Root()
+	require.Equal(t, xml.Name{Space: "samlp", Local: "Response"}, root.Name, "Root element should be parsed")
+	id, ok := root.Attribute("ID")
+	require.True(t, ok, "Root attribute should be found")
+	require.Equal(t, "r", id, "Root attribute value should be parsed")
+
+	assertions := doc.Find("/samlp:Response/saml:Assertion")
+	require.Len(t, assertions, 2, "Should find both assertions")
+	require.Equal(t, `<saml:Assertion ID="a"><saml:Subject>user<!-- x -->name</saml:Subject></saml:Assertion>`, string(doc.Raw(assertions[0])),
+		"Raw bytes of an element should span its start and end tags")
+	require.Equal(t, `<saml:Assertion ID="b"/>`, string(doc.Raw(assertions[1])), "Raw bytes of an empty element should span its tag")
+	require.Equal(t, int64(2), assertions[0].Line, "Element line should be recorded")
+	require.Equal(t, int64(3), assertions[0].Column, "Element column should be recorded")
+	require.Equal(t, root, assertions[0].Parent, "Element parent should be recorded")
+
+	subjects := doc.Find("//saml:Subject")
+	require.Len(t, subjects, 1, "Should find the subject")
+	require.Equal(t, "username", subjects[0].Text(), "Comments shouldn't be part of the text")
+	require.Equal(t, assertions[0].Path(), subjects[0].Parent.Path(), "Element paths should be consistent")
+
+	_, err = New().Parse(strings.NewReader(`<Root>]]></Root>`))
+	require.Error(t, err, "Should error on unparseable XML documents")
+
+	doc, err = New().Parse(strings.NewReader(`<Root><Child>`))
+	require.NoError(t, err, "Should parse unclosed elements")
+	require.Equal(t, int64(13), doc.Root().End, "Unclosed elements should extend to the end of the document")
+}
+
+func TestParseSingleLine(t *testing.T) {
+	doc := "<Root>" + strings.Repeat(`<e a="1">text</e>`, 20000) + "</Root>"
+	parsed, err := New().Parse(strings.NewReader(doc))
+	require.NoError(t, err)
+	children := parsed.Children[0].(*Element).Children
+	require.Len(t, children, 20000)
+	last := children[len(children)-1].(*Element)
+	require.Equal(t, []int64{1, int64(len(doc) - len(`<e a="1">text</e></Root>`) + 1)}, []int64{last.Line, last.Column},
+		"Should locate elements on a single line")
+}
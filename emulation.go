@@ -0,0 +1,90 @@
+package validator
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// EmulateGo116 makes verdicts on names match those of a program built with
+// Go 1.16 or older, whose encoding/xml mutated names with an empty
+// namespace prefix or local name and names with several colons; see
+// Emulate
+func EmulateGo116() Option {
+	return Emulate(SemanticsGo116)
+}
+
+// EmulateGo117 makes verdicts on names match those of a program built with
+// Go 1.17 to 1.19, which tokenized names with several colons as local
+// names; see Emulate
+func EmulateGo117() Option {
+	return Emulate(SemanticsGo117)
+}
+
+// EmulateGo120 makes verdicts on names match those of a program built with
+// Go 1.20 or newer, which rejects names with several colons as syntax
+// errors; see Emulate
+func EmulateGo120() Option {
+	return Emulate(SemanticsGo120)
+}
+
+// Emulate makes the Validator assume the given encoding/xml semantics
+// instead of those of the Go version the program is built with, so
+// services built with a newer Go version reach the same verdicts as
+// consumers pinned to an older one:
+//
+//   - emulating Go 1.16 enables CheckEmptyNames and CheckNameColons, like
+//     StrictLegacy, failing the names Go 1.16 mutated
+//   - emulating Go 1.17 to 1.19 with Go 1.20 or newer renames the names
+//     with several colons the way ValidateDual does, so they pass instead
+//     of being syntax errors; documents are read whole before validation
+//     starts, and findings show the renamed names
+//   - emulating Go 1.20 with Go 1.17 to 1.19 enables CheckNameColons,
+//     failing the names Go 1.20 rejects
+//
+// Only verdicts are emulated, not the findings explaining them: a name
+// failing CheckNameColons is a syntax error under Go 1.20. Since Go 1.16
+// mutates names newer versions let through, newer semantics can't be
+// emulated by programs built with Go 1.16 or older.
+func Emulate(semantics Semantics) Option {
+	return func(v *Validator) {
+		v.emulation = semantics
+		switch {
+		case semantics == SemanticsGo116:
+			StrictLegacy()(v)
+		case semantics == SemanticsGo120 && StdlibSemantics() == SemanticsGo117:
+			WithNameColonsCheck(NameColonsConfig{})(v)
+		}
+	}
+}
+
+// renamesLegacyNames reports whether documents need their names with
+// several colons renamed to emulate Go 1.17 to 1.19
+func (v *Validator) renamesLegacyNames() bool {
+	return v.emulation == SemanticsGo117 && StdlibSemantics() == SemanticsGo120
+}
+
+// emulate returns the document as the emulated semantics tokenize it
+func (v *Validator) emulate(xmlReader io.Reader) io.Reader {
+	if !v.renamesLegacyNames() {
+		return xmlReader
+	}
+	xmlBytes, err := ioutil.ReadAll(xmlReader)
+	rewritten, _ := rewriteLegacyNames(xmlBytes)
+	if err != nil {
+		return io.MultiReader(bytes.NewReader(rewritten), &errorReader{err: err})
+	}
+	return bytes.NewReader(rewritten)
+}
+
+// emulateBytes is like emulate, but for in-memory documents, which are
+// only copied if they need renaming
+func (v *Validator) emulateBytes(xmlBytes []byte) []byte {
+	if !v.renamesLegacyNames() {
+		return xmlBytes
+	}
+	if rewritten, changed := rewriteLegacyNames(xmlBytes); changed {
+		return rewritten
+	}
+	return xmlBytes
+}
@@ -0,0 +1,48 @@
+package validator
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmulate(t *testing.T) {
+	emptyNames := `<Root :="value"><x:></x:></Root>`
+	colons := `<x::Root ::attr="a:b:c"></x::Root>`
+	valid := `<x:Root xmlns:x="urn:x" x:attr="value"/>`
+
+	v := New(EmulateGo116())
+	require.Error(t, v.Validate(strings.NewReader(emptyNames)), "Names with empty parts should fail like on Go 1.16")
+	require.Error(t, v.Validate(strings.NewReader(colons)), "Names with several colons should fail like on Go 1.16")
+	require.NoError(t, v.Validate(strings.NewReader(valid)), "Valid documents should pass")
+	require.Equal(t, SemanticsGo116, v.Capabilities().Emulation)
+
+	if StdlibSemantics() == SemanticsGo116 {
+		t.Skip("Newer semantics can't be emulated with Go 1.16")
+	}
+
+	v = New(EmulateGo117())
+	require.NoError(t, v.Validate(strings.NewReader(emptyNames)), "Names with empty parts should pass like on Go 1.17")
+	require.NoError(t, v.Validate(strings.NewReader(colons)), "Names with several colons should pass like on Go 1.17")
+	require.NoError(t, v.ValidateBytes([]byte(colons)), "In-memory documents should be emulated too")
+	require.NoError(t, v.Validate(strings.NewReader(valid)), "Valid documents should pass")
+	errs := v.ValidateAll(strings.NewReader(`<x::Root><?xml version="1.0"?></x::Root>`))
+	require.NotEmpty(t, errs, "Other findings should still be reported")
+	var validationError XMLValidationError
+	require.True(t, errors.As(errs[0], &validationError))
+	require.EqualValues(t, 1, validationError.Line)
+	require.EqualValues(t, 10, validationError.Column, "Renaming names shouldn't move findings")
+
+	v = New(EmulateGo120())
+	require.NoError(t, v.Validate(strings.NewReader(emptyNames)), "Names with empty parts should pass like on Go 1.20")
+	require.Error(t, v.Validate(strings.NewReader(colons)), "Names with several colons should fail like on Go 1.20")
+	require.NoError(t, v.Validate(strings.NewReader(valid)), "Valid documents should pass")
+
+	readErr := errors.New("read failed")
+	v = New(EmulateGo117())
+	err := v.Validate(io.MultiReader(strings.NewReader(colons), &failingReader{err: readErr}))
+	require.True(t, errors.Is(err, readErr), "Read errors should be returned when emulating")
+}
@@ -0,0 +1,379 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Validate is like the package-level Validate, but only returns findings
+// whose severity reaches the Validator's fail-on threshold
+func (v *Validator) Validate(xmlReader io.Reader) error {
+	return v.ValidateContext(context.Background(), xmlReader)
+}
+
+// ValidateContext is like Validate, but stops validating and returns the
+// context's error once the context is done; the context is checked between
+// tokens, so a read blocking on the underlying reader isn't interrupted
+func (v *Validator) ValidateContext(ctx context.Context, xmlReader io.Reader) error {
+	d := v.newDocument(xmlReader)
+	d.ctx = ctx
+	return d.validate()
+}
+
+// ValidateBytes is like Validate, but validates an in-memory document
+// without copying it
+func (v *Validator) ValidateBytes(xmlBytes []byte) error {
+	return v.newDocumentBytes(xmlBytes).validate()
+}
+
+// validate runs the document's checks until a finding fails validation
+func (d *document) validate() error {
+	defer d.release()
+	var result error
+	err := d.run(func(err error) bool {
+		if d.v.Fails(err) {
+			result = err
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// ValidateAll is like the package-level ValidateAll, but reports findings
+// of every severity; use Fails to tell which of them are fatal
+func (v *Validator) ValidateAll(xmlReader io.Reader) []error {
+	return v.ValidateAllContext(context.Background(), xmlReader)
+}
+
+// ValidateAllContext is like ValidateAll, but stops validating once the
+// context is done, returning the findings so far followed by the
+// context's error
+func (v *Validator) ValidateAllContext(ctx context.Context, xmlReader io.Reader) []error {
+	d := v.newDocument(xmlReader)
+	d.ctx = ctx
+	return d.validateAll()
+}
+
+// ValidateAllBytes is like ValidateAll, but validates an in-memory document
+// without copying it
+func (v *Validator) ValidateAllBytes(xmlBytes []byte) []error {
+	return v.newDocumentBytes(xmlBytes).validateAll()
+}
+
+// validateAll runs the document's checks on the whole document
+func (d *document) validateAll() []error {
+	errs := []error{}
+	d.validateAllFunc(func(err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	return errs
+}
+
+// ValidateAllDetailed is like ValidateAll, but returns the findings as
+// XMLValidationError values, so their position can be read without type
+// assertions; syntax errors are returned as findings of CheckSyntax
+// located at the token they were found in. Errors that stopped validation
+// without being findings, such as read errors, are returned separately.
+func (v *Validator) ValidateAllDetailed(xmlReader io.Reader) ([]XMLValidationError, error) {
+	d := v.newDocument(xmlReader)
+	defer d.release()
+	findings := []XMLValidationError{}
+	err := d.runAll(func(err error) bool {
+		findings = append(findings, err.(XMLValidationError))
+		return true
+	})
+	syntaxError := &xml.SyntaxError{}
+	if errors.As(err, &syntaxError) {
+		return append(findings, d.syntaxFinding(err)), nil
+	}
+	return findings, err
+}
+
+// syntaxFinding locates a syntax error at the token it was found in
+func (d *document) syntaxFinding(err error) XMLValidationError {
+	line, column := d.position(d.offset)
+	endLine, endColumn := d.position(d.decoder.InputOffset())
+	return XMLValidationError{
+		Start:     d.offset,
+		End:       d.decoder.InputOffset(),
+		Line:      line,
+		Column:    column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+		Path:      d.xpath(),
+		Severity:  SeverityError,
+		elements:  d.elements(),
+		Check:     CheckSyntax,
+		Snippet:   d.snippet(d.offset, d.decoder.InputOffset()),
+		err:       err,
+	}
+}
+
+// ValidateAllFunc is like ValidateAll, but passes every finding to fn as
+// soon as it is found instead of collecting them, and stops reading the
+// document once fn returns false; errors that stopped validation early
+// are passed to fn last
+func (v *Validator) ValidateAllFunc(xmlReader io.Reader, fn func(error) bool) {
+	v.newDocument(xmlReader).validateAllFunc(fn)
+}
+
+// validateAllFunc runs the document's checks until fn returns false
+func (d *document) validateAllFunc(fn func(err error) bool) {
+	defer d.release()
+	if err := d.runAll(fn); err != nil {
+		fn(err)
+	}
+}
+
+// runAll is like run, but also stops once the Validator's maximum number
+// of errors is reached, in which case it returns ErrTooManyErrors
+func (d *document) runAll(fn func(err error) bool) error {
+	limited := false
+	// pending holds the categories no failing finding was reported in yet,
+	// and warned those a finding that doesn't fail validation was
+	pending, warned := map[Category]bool{}, map[Category]bool{}
+	for _, c := range d.checks {
+		pending[c.id.Category()] = true
+	}
+	err := d.run(func(err error) bool {
+		if d.v.perCategory {
+			category := err.(XMLValidationError).Check.Category()
+			switch {
+			case !pending[category]:
+				return true
+			case d.v.Fails(err):
+				delete(pending, category)
+			case warned[category]:
+				return true
+			default:
+				warned[category] = true
+			}
+		}
+		if !fn(err) {
+			return false
+		}
+		d.reported++
+		limited = d.v.maxErrors > 0 && d.reported >= d.v.maxErrors
+		return !limited && !(d.v.perCategory && len(pending) == 0)
+	})
+	if limited {
+		return ErrTooManyErrors
+	}
+	return err
+}
+
+// Fails reports whether the given error is severe enough to fail validation
+func (v *Validator) Fails(err error) bool {
+	return err != nil && SeverityOf(err) >= v.failOn
+}
+
+// document holds the state of a single validation run
+type document struct {
+	v *Validator
+	// input records the bytes consumed by the decoder
+	input   documentInput
+	decoder *xml.Decoder
+	checks  []activeCheck
+	// path holds the names of the currently open elements, including
+	// the one closed by the current token
+	path []xml.Name
+	// names holds the names of the open elements resolved to their
+	// namespace URI
+	names []xml.Name
+	// indexes holds the position of each open element among its siblings
+	// of the same name, and siblings counts the names of the children of
+	// the document and of each open element
+	indexes  []int
+	siblings []map[xml.Name]int
+	// bindings holds the namespace declarations in scope, and scopes the
+	// number of bindings in scope before each open element
+	bindings []namespaceBinding
+	scopes   []int
+	// bases holds the effective base URI of each open element
+	bases []string
+	// closing is set if the current token closes an element
+	closing bool
+	// offset is the offset of the first byte of the current token
+	offset int64
+	// lines locates offsets from the start of the current token on
+	lines lineTracker
+	// stats counts the findings reported for this document
+	stats map[CheckID]CheckStats
+	// stop is set by checks finding the rest of the document not worth
+	// validating, once their findings are reported
+	stop bool
+	// reported is the number of findings passed to runAll's callback
+	reported int
+	// report, if set, records the shape of every token read
+	report *ValidationReport
+	// ctx is checked for cancellation before reading every token
+	ctx context.Context
+}
+
+func (v *Validator) newDocument(xmlReader io.Reader) *document {
+	xmlReader = v.limitBytes(v.autoDecompress(xmlReader))
+	sampled := true
+	if v.sampling != nil {
+		xmlReader, sampled = v.sampling.sample(xmlReader)
+	}
+	limited := v.limitTokens(v.emulate(xmlReader))
+	d := v.newDocumentFrom(newBufferedInput(limited), sampled)
+	if r, ok := limited.(*tokenLimitedReader); ok {
+		r.d = d
+	}
+	return d
+}
+
+func (v *Validator) newDocumentBytes(xmlBytes []byte) *document {
+	if v.autoDecompression && detectEncoding(xmlBytes) != "identity" {
+		return v.newDocument(bytes.NewReader(xmlBytes))
+	}
+	sampled := true
+	if v.sampling != nil {
+		sampled = v.sampling.sampleBytes(xmlBytes)
+	}
+	return v.newDocumentFrom(&sliceReader{data: v.emulateBytes(xmlBytes)}, sampled)
+}
+
+func (v *Validator) newDocumentFrom(input documentInput, sampled bool) *document {
+	d := &document{
+		v:      v,
+		input:  input,
+		checks: v.activeChecks(),
+		stats:  map[CheckID]CheckStats{},
+		ctx:    context.Background(),
+	}
+	if !sampled {
+		d.checks = cheapChecks(d.checks)
+	}
+	d.decoder = xml.NewDecoder(input)
+	d.decoder.Strict = false
+	d.decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	return d
+}
+
+// next reads the next token and runs every active check on it
+func (d *document) next() (xml.Token, []XMLValidationError, error) {
+	select {
+	case <-d.ctx.Done():
+		return nil, nil, d.ctx.Err()
+	default:
+	}
+	if d.closing {
+		d.path = d.path[:len(d.path)-1]
+		d.names = d.names[:len(d.names)-1]
+		d.popIndex()
+		d.popNamespaces()
+		d.popBase()
+		d.closing = false
+	}
+	d.lines.advance(d.input.consumed(), d.offset)
+	token, err := d.decoder.RawToken()
+	if err != nil {
+		syntaxError := &xml.SyntaxError{}
+		if errors.As(err, &syntaxError) {
+			d.record(CheckSyntax, SeverityError, err)
+		}
+		return nil, nil, readError(err)
+	}
+	if err := d.checkSize(d.decoder.InputOffset()); err != nil {
+		return nil, nil, err
+	}
+	switch t := token.(type) {
+	case xml.StartElement:
+		d.path = append(d.path, t.Name)
+		d.pushIndex(t.Name)
+		d.pushNamespaces(t)
+		d.names = append(d.names, d.resolveName(t.Name, true))
+		d.pushBase(t)
+	case xml.EndElement:
+		d.closing = len(d.path) > 0
+	}
+	if d.report != nil {
+		d.report.count(token, len(d.path))
+	}
+	end := d.decoder.InputOffset()
+	var findings []XMLValidationError
+	for _, c := range d.checks {
+		if !inScope(c.paths, d.path) {
+			continue
+		}
+		if err := c.check(d, token); err != nil {
+			if d.v.redaction {
+				err = redactError(err)
+			}
+			line, column := d.position(d.offset)
+			endLine, endColumn := d.position(end)
+			var namespaces map[string]string
+			if errors.As(err, &XMLRoundtripError{}) {
+				namespaces = d.namespaceContext()
+			}
+			findings = append(findings, XMLValidationError{
+				Start:      d.offset,
+				End:        end,
+				Line:       line,
+				Column:     column,
+				EndLine:    endLine,
+				EndColumn:  endColumn,
+				Path:       d.xpath(),
+				Namespaces: namespaces,
+				elements:   d.elements(),
+				Severity:   c.severity,
+				Check:      c.id,
+				Snippet:    d.snippet(d.offset, end),
+				err:        err,
+			})
+		}
+	}
+	d.offset = end
+	return token, findings, nil
+}
+
+// release returns the document's scratch buffers to their pools once
+// validation finished and nothing refers to the document's bytes anymore
+func (d *document) release() {
+	if in, ok := d.input.(*bufferedInput); ok {
+		in.release()
+	}
+}
+
+// position returns the 1-based line and column of the byte at offset, which
+// is cheap from the start of the current token on
+func (d *document) position(offset int64) (line, column int64) {
+	return d.lines.position(d.input.consumed(), offset)
+}
+
+// raw returns the bytes of the current token as they appear in the document
+func (d *document) raw() []byte {
+	return d.input.consumed()[d.offset:d.decoder.InputOffset()]
+}
+
+// run validates the whole document, calling fn for every finding until fn
+// returns false; errors that prevent further parsing are returned instead
+func (d *document) run(fn func(err error) bool) error {
+	for {
+		_, findings, err := d.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		for _, finding := range findings {
+			d.record(finding.Check, finding.Severity, finding)
+			if !fn(finding) {
+				return nil
+			}
+		}
+		if d.stop {
+			return nil
+		}
+	}
+}
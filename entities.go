@@ -0,0 +1,300 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Error codes of findings reported by CheckEntityExpansion
+const (
+	// ErrCodeEntityRecursion is used for entities whose replacement text
+	// references themselves, directly or through other entities
+	ErrCodeEntityRecursion ErrorCode = "entity-recursion"
+	// ErrCodeEntityExpansion is used for entities, and documents
+	// referencing entities, expanding beyond the configured limit
+	ErrCodeEntityExpansion ErrorCode = "entity-expansion"
+)
+
+// DefaultMaxEntityExpansion is the number of bytes entity references may
+// expand to unless configured otherwise
+const DefaultMaxEntityExpansion = 1 << 20
+
+// XMLEntityExpansionError is returned when the internal subset of a
+// document type declaration defines an entity bomb, such as the billion
+// laughs attack, or when a document references entities often enough to
+// blow up once expanded, as in the quadratic blowup attack
+type XMLEntityExpansionError struct {
+	// Entity is the name of the offending entity, parameter entities
+	// starting with "%"; it is empty when references across the document
+	// add up to more than the limit
+	Entity string
+	// Recursive is set if the entity references itself
+	Recursive bool
+	// Size is the number of bytes the entity, or the references across
+	// the document, expand to, and Limit the configured limit; they are
+	// zero for recursive entities
+	Size, Limit int64
+}
+
+func (err XMLEntityExpansionError) Error() string {
+	switch {
+	case err.Recursive:
+		return fmt.Sprintf("entity %q references itself", err.Entity)
+	case err.Entity == "":
+		return fmt.Sprintf("entity references expand to %d bytes, more than the limit of %d", err.Size, err.Limit)
+	}
+	return fmt.Sprintf("entity %q expands to %d bytes, more than the limit of %d", err.Entity, err.Size, err.Limit)
+}
+
+// Code returns the error code of the finding
+func (err XMLEntityExpansionError) Code() ErrorCode {
+	if err.Recursive {
+		return ErrCodeEntityRecursion
+	}
+	return ErrCodeEntityExpansion
+}
+
+// Is reports whether target is the error code of the finding
+func (err XMLEntityExpansionError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == err.Code()
+}
+
+// EntityExpansionConfig configures CheckEntityExpansion
+type EntityExpansionConfig struct {
+	CheckConfig
+	// MaxExpansion is the number of bytes a single entity, and the entity
+	// references across the document, may expand to; zero or less uses
+	// DefaultMaxEntityExpansion
+	MaxExpansion int64
+}
+
+// WithEntityExpansionCheck enables and configures CheckEntityExpansion
+func WithEntityExpansionCheck(cfg EntityExpansionConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckEntityExpansion] = cfg.CheckConfig
+		v.entityExpansion = cfg
+	}
+}
+
+// newEntityExpansionCheck creates the per-document state of
+// CheckEntityExpansion. encoding/xml leaves entity references unexpanded,
+// so the document's own references are measured from the raw bytes of
+// character data and start elements, against the entities declared so far.
+func newEntityExpansionCheck(v *Validator) tokenCheck {
+	max := v.entityExpansion.MaxExpansion
+	if max <= 0 {
+		max = DefaultMaxEntityExpansion
+	}
+	dtd := &entityDefinitions{values: map[string][]byte{}, sizes: map[string]int64{}, max: max}
+	expanded, reported := int64(0), false
+	return func(d *document, token xml.Token) error {
+		var markup []byte
+		switch t := token.(type) {
+		case xml.Directive:
+			return dtd.declare(t)
+		case xml.CharData:
+			markup = d.raw()
+			if bytes.HasPrefix(markup, []byte("<![CDATA[")) {
+				return nil
+			}
+		case xml.StartElement:
+			markup = d.raw()
+		default:
+			return nil
+		}
+		if reported || len(dtd.values) == 0 {
+			return nil
+		}
+		for _, name := range entityReferences(markup, '&') {
+			size, err := dtd.size(name)
+			if err != nil {
+				reported = true
+				return err
+			}
+			if expanded = saturatingAdd(expanded, size, max); expanded > max {
+				reported = true
+				return XMLEntityExpansionError{Size: expanded, Limit: max}
+			}
+		}
+		return nil
+	}
+}
+
+// entityDefinitions holds the internal entities declared by a document,
+// general entities by name and parameter entities by name prefixed with
+// "%", along with the sizes their replacement text expands to
+type entityDefinitions struct {
+	values map[string][]byte
+	sizes  map[string]int64
+	max    int64
+}
+
+// declare records the entities declared in the internal subset of a
+// document type declaration, returning an error for the first of them
+// that is recursive or expands beyond the limit
+func (dtd *entityDefinitions) declare(directive xml.Directive) error {
+	if !bytes.HasPrefix(directive, []byte("DOCTYPE")) {
+		return nil
+	}
+	start := bytes.IndexByte(directive, '[')
+	if start < 0 {
+		return nil
+	}
+	declared := []string{}
+	subset := directive[start+1:]
+	for i := 0; i < len(subset); {
+		switch {
+		case bytes.HasPrefix(subset[i:], []byte("<!ENTITY")):
+			name, value, n := parseEntityDeclaration(subset[i:])
+			// the first declaration of an entity is binding
+			if _, ok := dtd.values[name]; name != "" && !ok {
+				dtd.values[name] = value
+				declared = append(declared, name)
+			}
+			i += n
+		case bytes.HasPrefix(subset[i:], []byte("<!")) || bytes.HasPrefix(subset[i:], []byte("<?")):
+			i += markupDeclarationLength(subset[i:])
+		default:
+			i++
+		}
+	}
+	for _, name := range declared {
+		size, err := dtd.size(name)
+		if err != nil {
+			return err
+		}
+		if size > dtd.max {
+			return XMLEntityExpansionError{Entity: name, Size: size, Limit: dtd.max}
+		}
+	}
+	return nil
+}
+
+// size returns the number of bytes an entity expands to, capped just
+// above the limit; references to undeclared entities, such as the
+// predefined ones, count as their own length
+func (dtd *entityDefinitions) size(name string) (int64, error) {
+	return dtd.expand(name, map[string]bool{})
+}
+
+func (dtd *entityDefinitions) expand(name string, expanding map[string]bool) (int64, error) {
+	if size, ok := dtd.sizes[name]; ok {
+		return size, nil
+	}
+	value, ok := dtd.values[name]
+	if !ok {
+		return referenceLength(name), nil
+	}
+	if expanding[name] {
+		return 0, XMLEntityExpansionError{Entity: name, Recursive: true}
+	}
+	expanding[name] = true
+	defer delete(expanding, name)
+	refs := entityReferences(value, '&')
+	for _, ref := range entityReferences(value, '%') {
+		refs = append(refs, "%"+ref)
+	}
+	size := int64(len(value))
+	for _, ref := range refs {
+		refSize, err := dtd.expand(ref, expanding)
+		if err != nil {
+			return 0, err
+		}
+		if size = saturatingAdd(size, refSize-referenceLength(ref), dtd.max); size > dtd.max {
+			break
+		}
+	}
+	dtd.sizes[name] = size
+	return size, nil
+}
+
+// referenceLength returns the length of a reference to the entity
+func referenceLength(name string) int64 {
+	if strings.HasPrefix(name, "%") {
+		return int64(len(name)) + 1
+	}
+	return int64(len(name)) + 2
+}
+
+// parseEntityDeclaration parses the entity declaration at the start of
+// markup, returning the name of an internal entity, prefixed with "%" for
+// parameter entities, with its literal value, along with the length of the
+// declaration; external entities are returned without a name
+func parseEntityDeclaration(markup []byte) (string, []byte, int) {
+	n := markupDeclarationLength(markup)
+	fields := markup[len("<!ENTITY"):n]
+	fields = bytes.TrimSuffix(fields, []byte(">"))
+	prefix := ""
+	fields = bytes.TrimLeft(fields, " \t\r\n")
+	if bytes.HasPrefix(fields, []byte("%")) {
+		prefix = "%"
+		fields = bytes.TrimLeft(fields[1:], " \t\r\n")
+	}
+	end := bytes.IndexAny(fields, " \t\r\n")
+	if end <= 0 {
+		return "", nil, n
+	}
+	name := string(fields[:end])
+	fields = bytes.TrimLeft(fields[end:], " \t\r\n")
+	if len(fields) == 0 || (fields[0] != '"' && fields[0] != '\'') {
+		return "", nil, n
+	}
+	value := fields[1:]
+	if end := bytes.IndexByte(value, fields[0]); end >= 0 {
+		value = value[:end]
+	}
+	return prefix + name, value, n
+}
+
+// markupDeclarationLength returns the length of the markup declaration or
+// processing instruction at the start of markup, up to the closing ">"
+// outside quoted literals
+func markupDeclarationLength(markup []byte) int {
+	var quote byte
+	for i, c := range markup {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return i + 1
+		}
+	}
+	return len(markup)
+}
+
+// entityReferences returns the names of the entity references starting
+// with delimiter in data, leaving out character references
+func entityReferences(data []byte, delimiter byte) []string {
+	names := []string{}
+	for {
+		start := bytes.IndexByte(data, delimiter)
+		if start < 0 {
+			return names
+		}
+		data = data[start+1:]
+		end := bytes.IndexByte(data, ';')
+		if end <= 0 || bytes.IndexAny(data[:end], " \t\r\n<&%\"'") >= 0 {
+			continue
+		}
+		if data[0] != '#' {
+			names = append(names, string(data[:end]))
+		}
+		data = data[end+1:]
+	}
+}
+
+// saturatingAdd adds b to a, capping the sum just above max so sizes of
+// nested entity bombs can't overflow
+func saturatingAdd(a, b, max int64) int64 {
+	if a > max || b > max || a+b > max {
+		return max + 1
+	}
+	return a + b
+}
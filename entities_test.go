@@ -0,0 +1,57 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntityExpansion(t *testing.T) {
+	laughs := &strings.Builder{}
+	laughs.WriteString(`<!DOCTYPE lolz [<!ENTITY lol0 "lol">`)
+	for i := 1; i < 10; i++ {
+		laughs.WriteString(`<!ENTITY lol` + string(rune('0'+i)) + ` "`)
+		laughs.WriteString(strings.Repeat(`&lol`+string(rune('0'+i-1))+`;`, 10))
+		laughs.WriteString(`">`)
+	}
+	laughs.WriteString(`]><lolz>&lol9;</lolz>`)
+	quadratic := `<!DOCTYPE Root [<!ENTITY a "` + strings.Repeat("a", 1000) + `">]><Root>` + strings.Repeat("&a;", 2000) + `</Root>`
+
+	v := New(WithEntityExpansionCheck(EntityExpansionConfig{}), WithCheck(CheckKnownAttacks, CheckConfig{Disabled: true}))
+	require.NoError(t, v.Validate(strings.NewReader(`<!DOCTYPE Root [<!ENTITY a "&b;&b;"><!ENTITY b "b">]><Root a="&a;">&a;&amp;&#65;</Root>`)),
+		"Should allow entities expanding within the limit")
+	require.NoError(t, v.Validate(strings.NewReader(`<!DOCTYPE Root [<!ENTITY a "a > b"><!-- <!ENTITY a "&a;"> --><!ATTLIST Root b CDATA "<!ENTITY b '&b;'>">]><Root/>`)),
+		"Shouldn't mistake comments and literals for declarations")
+	require.NoError(t, New(WithEntityExpansionCheck(EntityExpansionConfig{MaxExpansion: 10})).Validate(strings.NewReader(
+		`<!DOCTYPE Root [<!ENTITY a "aaaaa">]><Root><![CDATA[&a;&a;&a;]]></Root>`)), "Shouldn't mistake CDATA sections for references")
+
+	err := v.Validate(strings.NewReader(laughs.String()))
+	require.True(t, errors.Is(err, ErrCodeEntityExpansion), "Should reject billion laughs")
+	var entityError XMLEntityExpansionError
+	require.True(t, errors.As(err, &entityError))
+	require.Equal(t, "lol6", entityError.Entity, "Should report the first entity expanding beyond the limit")
+	require.EqualValues(t, DefaultMaxEntityExpansion, entityError.Limit)
+	require.Equal(t, ErrCodeEntityExpansion, FindingOf(err).Code)
+
+	err = v.Validate(strings.NewReader(quadratic))
+	require.True(t, errors.Is(err, ErrCodeEntityExpansion), "Should reject quadratic blowup")
+	require.True(t, errors.As(err, &entityError))
+	require.Empty(t, entityError.Entity, "Should report references across the document")
+	require.Len(t, New(WithEntityExpansionCheck(EntityExpansionConfig{MaxExpansion: 1000})).ValidateAll(strings.NewReader(quadratic)), 1,
+		"Should report references across the document once")
+
+	for _, doc := range []string{
+		`<!DOCTYPE Root [<!ENTITY a "&a;">]><Root/>`,
+		`<!DOCTYPE Root [<!ENTITY a "&b;"><!ENTITY b "x&a;">]><Root/>`,
+		`<!DOCTYPE Root [<!ENTITY % a "%b;"><!ENTITY % b "%a;">]><Root/>`,
+	} {
+		err = v.Validate(strings.NewReader(doc))
+		require.True(t, errors.Is(err, ErrCodeEntityRecursion), "Should reject recursive entities in %s", doc)
+	}
+
+	caps := New(WithEntityExpansionCheck(EntityExpansionConfig{})).Capabilities()
+	require.Equal(t, CheckEntityExpansion, caps.Checks[len(caps.Checks)-1].ID)
+	require.Equal(t, []ErrorCode{ErrCodeEntityRecursion, ErrCodeEntityExpansion}, caps.Checks[len(caps.Checks)-1].Codes)
+}
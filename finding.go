@@ -0,0 +1,124 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Finding describes a problem found in a document. It is the one type
+// describing findings of every kind, which output formats, sinks and
+// reports build on; the errors returned by validation, such as
+// XMLValidationError, describe themselves as a Finding, see FindingOf.
+type Finding struct {
+	Check    CheckID
+	Code     ErrorCode
+	Severity Severity
+	// Message describes the problem, without its position
+	Message string
+	// Start and End are the byte offsets of the offending token, Line
+	// and Column the 1-based position of its start, and EndLine and
+	// EndColumn that of its end; they are zero when unknown, and syntax
+	// errors only carry a Line
+	Start, End, Line, Column int64
+	EndLine, EndColumn       int64
+	// Path locates the offending token in the element tree, see
+	// XMLValidationError
+	Path string
+	// Elements holds the names of the elements in Path, resolved to their
+	// namespace URI; unlike the prefixes in Path, they can't be chosen by
+	// the author of the document
+	Elements []xml.Name
+	// Namespaces and Snippet are those of the XMLValidationError
+	Namespaces map[string]string
+	Snippet    string
+	// Expected, Observed and Overflow describe round trip mismatches, see
+	// XMLRoundtripError; they are unset for other findings
+	Expected, Observed xml.Token
+	Overflow           []byte
+	// Suggestion describes how to fix the document, for findings with a
+	// known fix
+	Suggestion string
+}
+
+// suggestions holds the fixes of findings by error code
+var suggestions = map[ErrorCode]string{
+	ErrCodeColonInName:               "write names with at most one colon, between a non-empty namespace prefix and local name",
+	ErrorCode(CheckEmptyNames):       "write names with a non-empty namespace prefix and local name",
+	ErrorCode(CheckNameColons):       "write names with at most one colon",
+	ErrorCode(CheckUndeclaredPrefix): "declare the namespace prefix on the element or one of its ancestors",
+	ErrorCode(CheckXMLDeclaration):   "move the XML declaration to the very start of the document, or remove it",
+	ErrorCode(CheckTrailingContent):  "remove the content following the root element",
+	ErrorCode(CheckXMLBase):          "remove the xml:base attribute",
+	ErrCodeDoctype:                   "remove the document type declaration",
+	ErrCodeExternalDoctype:           "remove the SYSTEM or PUBLIC identifier, or the whole document type declaration",
+	ErrCodeExternalEntity:            "remove the external entity declaration and the references to it",
+	ErrCodeExternalParameterEntity:   "remove the external parameter entity declaration and the references to it",
+}
+
+// Finding describes the error as a Finding
+func (err XMLValidationError) Finding() Finding {
+	code := err.Code()
+	finding := Finding{
+		Check:      err.Check,
+		Code:       code,
+		Severity:   SeverityOf(err),
+		Start:      err.Start,
+		End:        err.End,
+		Line:       err.Line,
+		Column:     err.Column,
+		EndLine:    err.EndLine,
+		EndColumn:  err.EndColumn,
+		Path:       err.Path,
+		Elements:   err.elements,
+		Namespaces: err.Namespaces,
+		Snippet:    err.Snippet,
+		Suggestion: suggestions[code],
+	}
+	if err.err != nil {
+		finding.Message = err.err.Error()
+	}
+	roundtripError := XMLRoundtripError{}
+	if errors.As(err.err, &roundtripError) {
+		finding.Expected = roundtripError.Expected
+		finding.Observed = roundtripError.Observed
+		finding.Overflow = roundtripError.Overflow
+	}
+	return finding
+}
+
+// FindingOf describes an error returned by this package as a Finding;
+// errors that aren't findings, such as read errors, are described as
+// syntax errors without a position
+func FindingOf(err error) Finding {
+	validationError := XMLValidationError{}
+	syntaxError := &xml.SyntaxError{}
+	switch {
+	case errors.As(err, &validationError):
+		return validationError.Finding()
+	case errors.As(err, &syntaxError):
+		return Finding{Check: CheckSyntax, Severity: SeverityError, Message: syntaxError.Msg, Line: int64(syntaxError.Line)}
+	}
+	return Finding{Check: CheckSyntax, Severity: SeverityError, Message: err.Error()}
+}
+
+// Check validates the entire document, reporting whether it passed along
+// with every finding in document order, including those that don't fail
+// validation. Problems with the document are never returned as an error;
+// an error is only returned if the document couldn't be read.
+func (v *Validator) Check(xmlReader io.Reader) (bool, []Finding, error) {
+	report, err := v.Report(xmlReader)
+	if err != nil {
+		return false, nil, err
+	}
+	findings := make([]Finding, 0, len(report.Errors))
+	for _, err := range report.Errors {
+		findings = append(findings, FindingOf(err))
+	}
+	return !report.Failed, findings, nil
+}
+
+// Check is like Validator.Check, using Default
+func Check(xmlReader io.Reader) (bool, []Finding, error) {
+	return Default().Check(xmlReader)
+}
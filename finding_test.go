@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	ok, findings, err := Check(strings.NewReader(`<Root/>`))
+	require.NoError(t, err)
+	require.True(t, ok, "Should pass valid documents")
+	require.Empty(t, findings)
+
+	ok, findings, err = Check(strings.NewReader("<Root>\n<!DOCTYPE x SYSTEM \"http://example.com/x.dtd\"></Root>"))
+	require.NoError(t, err, "Shouldn't return errors for findings")
+	require.True(t, ok, "Warnings shouldn't fail the document")
+	require.Len(t, findings, 1, "Should report warnings")
+	require.Equal(t, SeverityWarning, findings[0].Severity)
+	require.Equal(t, int64(2), findings[0].Line, "Should locate findings")
+	require.Equal(t, int64(1), findings[0].Column, "Should locate findings")
+	require.Equal(t, [2]int64{2, 47}, [2]int64{findings[0].EndLine, findings[0].EndColumn}, "Should locate the end of findings")
+	require.False(t, strings.HasPrefix(findings[0].Message, "validator:"), "Messages shouldn't repeat the position")
+
+	ok, findings, err = Check(strings.NewReader(`<Root>]]></Root>`))
+	require.NoError(t, err, "Shouldn't return errors for syntax errors")
+	require.False(t, ok, "Should fail on syntax errors")
+	require.Equal(t, []Finding{{Check: CheckSyntax, Severity: SeverityError, Message: "unescaped ]]> not in CDATA section", Line: 1}}, findings)
+
+	readErr := errors.New("connection reset")
+	_, _, err = Check(&failingReader{readErr})
+	require.True(t, errors.Is(err, readErr), "Should return read errors")
+}
+
+func TestFinding(t *testing.T) {
+	roundtripError := XMLRoundtripError{
+		Expected: xml.StartElement{Name: xml.Name{Local: ":Element"}},
+		Observed: xml.StartElement{Name: xml.Name{Local: "Element"}},
+	}
+	finding := XMLValidationError{
+		Start: 6, End: 17, Line: 1, Column: 7, Path: "/Root/:Element",
+		Severity: SeverityError, Check: CheckRoundtrip, err: roundtripError,
+	}.Finding()
+	require.Equal(t, ErrCodeColonInName, finding.Code)
+	require.Equal(t, roundtripError.Error(), finding.Message)
+	require.Equal(t, "/Root/:Element", finding.Path)
+	require.Equal(t, roundtripError.Expected, finding.Expected, "Should describe round trip mismatches")
+	require.Equal(t, roundtripError.Observed, finding.Observed, "Should describe round trip mismatches")
+	require.NotEmpty(t, finding.Suggestion, "Should suggest fixes for clear-cut findings")
+
+	events := make(chan FindingEvent, 2)
+	errs := New(WithFindingSink(NewChannelSink(events))).ValidateAll(strings.NewReader("<Root>\n<!DOCTYPE x SYSTEM \"http://example.com/x.dtd\"></Root>"))
+	require.Len(t, errs, 1)
+	finding = FindingOf(errs[0])
+	require.Nil(t, finding.Expected, "Should only describe round trip mismatches of round trip findings")
+	require.Equal(t, finding, (<-events).Finding, "Should publish findings to sinks")
+}
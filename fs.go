@@ -0,0 +1,94 @@
+//go:build go1.16
+// +build go1.16
+
+package validator
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ValidateFS validates the files of fsys whose path is accepted by match,
+// concurrently, with a Validator configured with opts, and returns the
+// report of each of them keyed by path; a nil match accepts files with an
+// .xml extension. It works on any file system, such as embedded files, zip
+// archives or os.DirFS. Validation stops at the first error walking the
+// file system or reading a file, or once the context is done, returning
+// the error along with the reports completed so far.
+func ValidateFS(ctx context.Context, fsys fs.FS, match func(path string) bool, opts ...Option) (map[string]*ValidationReport, error) {
+	if match == nil {
+		match = func(name string) bool { return strings.EqualFold(path.Ext(name), ".xml") }
+	}
+	v := New(opts...)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reports := map[string]*ValidationReport{}
+	var firstErr error
+	var mutex sync.Mutex
+	fail := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
+
+	paths := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range paths {
+				report, err := validateFile(ctx, v, fsys, name)
+				if err != nil {
+					fail(fmt.Errorf("%s: %w", name, err))
+					continue
+				}
+				mutex.Lock()
+				reports[name] = report
+				mutex.Unlock()
+			}
+		}()
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !match(name) {
+			return nil
+		}
+		select {
+		case paths <- name:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(paths)
+	wg.Wait()
+	if err != nil {
+		fail(err)
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return reports, firstErr
+}
+
+// validateFile reports on a single file of fsys
+func validateFile(ctx context.Context, v *Validator, fsys fs.FS, name string) (*ValidationReport, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return v.reportContext(ctx, f)
+}
@@ -0,0 +1,52 @@
+//go:build go1.16
+// +build go1.16
+
+package validator
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"good.xml":            {Data: []byte(`<Root/>`)},
+		"assets/bad.XML":      {Data: []byte(`<Root>]]></Root>`)},
+		"assets/warning.xml":  {Data: []byte(`<Root><?xml version="1.0"?></Root>`)},
+		"assets/readme.txt":   {Data: []byte(`not XML`)},
+		"assets/deep/doc.svg": {Data: []byte(`<svg/>`)},
+	}
+
+	reports, err := ValidateFS(context.Background(), fsys, nil)
+	require.NoError(t, err)
+	require.Len(t, reports, 3, "Should validate files with an .xml extension by default")
+	require.False(t, reports["good.xml"].Failed)
+	require.True(t, reports["assets/bad.XML"].Failed)
+	require.False(t, reports["assets/warning.xml"].Failed)
+	require.Len(t, reports["assets/warning.xml"].Errors, 1, "Reports should hold every finding")
+
+	reports, err = ValidateFS(context.Background(), fsys, func(path string) bool { return strings.HasSuffix(path, ".svg") },
+		WithFailOn(SeverityWarning))
+	require.NoError(t, err)
+	require.Len(t, reports, 1, "Should only validate files accepted by match")
+	require.NotNil(t, reports["assets/deep/doc.svg"])
+
+	reports, err = ValidateFS(context.Background(), fsys, nil, WithFailOn(SeverityWarning))
+	require.NoError(t, err)
+	require.True(t, reports["assets/warning.xml"].Failed, "Should validate with the given options")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ValidateFS(ctx, fsys, nil)
+	require.True(t, errors.Is(err, context.Canceled), "Should stop once the context is done")
+
+	_, err = ValidateFS(context.Background(), fstest.MapFS{"dir.xml": {Mode: fs.ModeSymlink}}, nil)
+	require.Error(t, err, "Should return errors reading files")
+	require.Contains(t, err.Error(), "dir.xml", "Errors should name the file")
+}
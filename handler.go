@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Handler receives the tokens of a document from Validator.Stream as they
+// are validated. Tokens and the byte slices they reference are only valid
+// until the callback returns; use xml.CopyToken to retain them.
+type Handler interface {
+	// StartElement is called for every validated start element
+	StartElement(xml.StartElement) error
+	// EndElement is called for every validated end element
+	EndElement(xml.EndElement) error
+	// CharData is called for every validated run of character data
+	CharData(xml.CharData) error
+	// Finding is called for every finding, before the token it was
+	// reported on would have been passed to the other callbacks
+	Finding(err error) error
+}
+
+// Stream validates the document in a single pass and passes its tokens to h
+// as they are validated, so h only ever sees tokens that have already passed
+// every check. Findings below the fail-on threshold are passed to h.Finding
+// and processing continues; the first finding at or above the threshold
+// stops processing and is returned, as is any error returned by h.
+// Comments, directives and processing instructions are validated but not
+// passed to h.
+func (v *Validator) Stream(xmlReader io.Reader, h Handler) error {
+	d := v.newDocument(xmlReader)
+	defer d.release()
+	for {
+		token, findings, err := d.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		for _, finding := range findings {
+			d.record(finding.Check, finding.Severity, finding)
+			if err := h.Finding(finding); err != nil {
+				return err
+			}
+			if v.Fails(finding) {
+				return finding
+			}
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			err = h.StartElement(t)
+		case xml.EndElement:
+			err = h.EndElement(t)
+		case xml.CharData:
+			err = h.CharData(t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
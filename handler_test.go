@@ -0,0 +1,72 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHandler struct {
+	events   []string
+	findings []error
+}
+
+func (h *recordingHandler) StartElement(t xml.StartElement) error {
+	h.events = append(h.events, "<"+t.Name.Local+">")
+	return nil
+}
+
+func (h *recordingHandler) EndElement(t xml.EndElement) error {
+	h.events = append(h.events, "</"+t.Name.Local+">")
+	return nil
+}
+
+func (h *recordingHandler) CharData(t xml.CharData) error {
+	h.events = append(h.events, string(t))
+	return nil
+}
+
+func (h *recordingHandler) Finding(err error) error {
+	h.findings = append(h.findings, err)
+	return nil
+}
+
+func TestStream(t *testing.T) {
+	h := &recordingHandler{}
+	err := New().Stream(strings.NewReader(`<Root><!-- comment -->text<Child/></Root>`), h)
+	require.NoError(t, err, "Should pass on valid XML documents")
+	require.Equal(t, []string{"<Root>", "text", "<Child>", "</Child>", "</Root>"}, h.events,
+		"Handler should receive elements and character data in document order")
+	require.Empty(t, h.findings, "Valid XML documents shouldn't have findings")
+
+	h = &recordingHandler{}
+	err = New().Stream(strings.NewReader(`<Root>]]></Root>`), h)
+	require.Error(t, err, "Should error on unparseable XML documents")
+	require.Equal(t, []string{"<Root>"}, h.events, "Handler should only receive tokens before the syntax error")
+}
+
+func TestStreamFindings(t *testing.T) {
+	registerTestCheck(t, "test-child", "test", SeverityWarning, func(d *document, token xml.Token) error {
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Child" {
+			return errors.New("child found")
+		}
+		return nil
+	})
+	doc := `<Root><Child/><Other/></Root>`
+
+	h := &recordingHandler{}
+	err := New().Stream(strings.NewReader(doc), h)
+	require.NoError(t, err, "Warnings shouldn't stop processing")
+	require.Len(t, h.findings, 1, "Handler should be told about warnings")
+	require.Equal(t, []string{"<Root>", "<Child>", "</Child>", "<Other>", "</Other>", "</Root>"}, h.events,
+		"Tokens with warnings should still be passed to the handler")
+
+	h = &recordingHandler{}
+	err = New(WithFailOn(SeverityWarning)).Stream(strings.NewReader(doc), h)
+	require.Error(t, err, "Failing findings should stop processing")
+	require.Equal(t, h.findings[0], err, "The failing finding should be returned")
+	require.Equal(t, []string{"<Root>"}, h.events, "Failing tokens shouldn't be passed to the handler")
+}
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// NamespaceDeclaration is a single prefix binding declared in a document
+type NamespaceDeclaration struct {
+	// Prefix is the declared prefix; it is empty for default namespace declarations
+	Prefix string
+	// URI is the namespace URI bound to the prefix
+	URI string
+	// Element is the name of the declaring element, as written in the document
+	Element xml.Name
+	// Start and End are the byte offsets delimiting the declaration's scope,
+	// from the start of the declaring element's start tag to the end of
+	// its end tag
+	Start, End int64
+	// Line and Column are the 1-based position of the declaring element
+	Line, Column int64
+}
+
+// Namespaces returns every namespace declaration in the document along with
+// its scope, in document order, regardless of whether any check reports a
+// finding; only errors preventing the document from being parsed are returned
+func (v *Validator) Namespaces(xmlReader io.Reader) ([]NamespaceDeclaration, error) {
+	declarations := []NamespaceDeclaration{}
+	// open holds the indices of the declarations made by each open element
+	open := [][]int{}
+	d := v.newDocument(xmlReader)
+	defer d.release()
+	for {
+		start := d.offset
+		token, _, err := d.next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			line, column := d.position(start)
+			indices := []int{}
+			for _, binding := range d.bindings[d.scopes[len(d.scopes)-1]:] {
+				indices = append(indices, len(declarations))
+				declarations = append(declarations, NamespaceDeclaration{
+					Prefix:  binding.prefix,
+					URI:     binding.uri,
+					Element: t.Name,
+					Start:   start,
+					Line:    line,
+					Column:  column,
+				})
+			}
+			open = append(open, indices)
+		case xml.EndElement:
+			if len(open) > 0 {
+				for _, i := range open[len(open)-1] {
+					declarations[i].End = d.offset
+				}
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	// declarations on elements left open extend to the end of the document
+	for _, indices := range open {
+		for _, i := range indices {
+			declarations[i].End = int64(len(d.input.consumed()))
+		}
+	}
+	return declarations, nil
+}
@@ -0,0 +1,40 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaces(t *testing.T) {
+	xmlString := "<Root xmlns=\"urn:default\" xmlns:a=\"urn:a\">\n  <a:Child xmlns:b=\"urn:b\"/>\n  <Open xmlns:a=\"urn:other\">"
+	declarations, err := New().Namespaces(strings.NewReader(xmlString))
+	require.NoError(t, err, "Should list namespaces of parseable documents")
+	require.Equal(t, []NamespaceDeclaration{
+		{Prefix: "", URI: "urn:default", Element: xml.Name{Local: "Root"}, Start: 0, End: int64(len(xmlString)), Line: 1, Column: 1},
+		{Prefix: "a", URI: "urn:a", Element: xml.Name{Local: "Root"}, Start: 0, End: int64(len(xmlString)), Line: 1, Column: 1},
+		{Prefix: "b", URI: "urn:b", Element: xml.Name{Space: "a", Local: "Child"}, Start: 45, End: 71, Line: 2, Column: 3},
+		{Prefix: "a", URI: "urn:other", Element: xml.Name{Local: "Open"}, Start: 74, End: int64(len(xmlString)), Line: 3, Column: 3},
+	}, declarations, "Should list every declaration with its scope")
+	require.Equal(t, `<a:Child xmlns:b="urn:b"/>`, xmlString[declarations[2].Start:declarations[2].End], "Scope should span the declaring element")
+
+	v := New(WithUndeclaredPrefixCheck(UndeclaredPrefixConfig{}))
+	declarations, err = v.Namespaces(strings.NewReader(`<x:Root xmlns:y="urn:y"/>`))
+	require.NoError(t, err, "Findings shouldn't prevent listing namespaces")
+	require.Len(t, declarations, 1, "Should list declarations of documents with findings")
+
+	_, err = New().Namespaces(strings.NewReader(`<Root xmlns:a="urn:a">]]></Root>`))
+	require.Error(t, err, "Should error on unparseable XML documents")
+}
+
+func TestNamespacesSingleLine(t *testing.T) {
+	doc := "<Root>" + strings.Repeat(`<e xmlns:a="urn:a"/>`, 20000) + "</Root>"
+	declarations, err := New().Namespaces(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Len(t, declarations, 20000)
+	last := declarations[len(declarations)-1]
+	require.Equal(t, []int64{1, int64(len(doc) - len(`<e xmlns:a="urn:a"/></Root>`) + 1)}, []int64{last.Line, last.Column},
+		"Should locate declarations on a single line")
+}
@@ -0,0 +1,27 @@
+//go:build go1.23
+// +build go1.23
+
+package validator
+
+import (
+	"io"
+	"iter"
+)
+
+// Errors returns the findings ValidateAll would return as an iterator,
+// validating the document lazily as the caller ranges over it, and stopping
+// as soon as the loop does. The document is read from r by the first loop,
+// so the iterator can only be ranged over once.
+func (v *Validator) Errors(xmlReader io.Reader) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		v.ValidateAllFunc(xmlReader, yield)
+	}
+}
+
+// Errors is like Validator.Errors, iterating over the errors the
+// package-level ValidateAll would return
+func Errors(xmlReader io.Reader) iter.Seq[error] {
+	return func(yield func(error) bool) {
+		ValidateAllFunc(xmlReader, yield)
+	}
+}
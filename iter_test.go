@@ -0,0 +1,38 @@
+//go:build go1.23
+// +build go1.23
+
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+	registerTestCheck(t, "test-a", "a", SeverityError, onStartElements)
+	registerTestCheck(t, "test-b", "b", SeverityWarning, onStartElements)
+	doc := `<Root><Child/><Child/></Root>`
+
+	var errs []error
+	for err := range Errors(strings.NewReader(doc)) {
+		errs = append(errs, err)
+	}
+	require.Equal(t, ValidateAll(strings.NewReader(doc)), errs, "Should iterate over the errors ValidateAll returns")
+
+	errs = nil
+	for err := range New().Errors(strings.NewReader(doc)) {
+		errs = append(errs, err)
+	}
+	require.Equal(t, New().ValidateAll(strings.NewReader(doc)), errs, "Should iterate over the findings Validator.ValidateAll returns")
+
+	errs = nil
+	for err := range New().Errors(strings.NewReader(doc)) {
+		errs = append(errs, err)
+		if len(errs) == 2 {
+			break
+		}
+	}
+	require.Len(t, errs, 2, "Should stop validating when the loop stops")
+}
@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// roundtripErrorJSON is the JSON representation of an XMLRoundtripError
+type roundtripErrorJSON struct {
+	Code     ErrorCode `json:"code"`
+	Message  string    `json:"message"`
+	Expected string    `json:"expected"`
+	Observed string    `json:"observed"`
+	Overflow string    `json:"overflow,omitempty"`
+}
+
+// MarshalJSON describes the mismatch along with its error code; the tokens
+// are rendered as markup the way they were tokenized
+func (err XMLRoundtripError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(roundtripErrorJSON{
+		Code:     err.Code(),
+		Message:  err.Error(),
+		Expected: formatToken(err.Expected),
+		Observed: formatToken(err.Observed),
+		Overflow: string(err.Overflow),
+	})
+}
+
+// findingJSON is the JSON representation of a Finding
+type findingJSON struct {
+	Check      CheckID            `json:"check"`
+	Code       ErrorCode          `json:"code,omitempty"`
+	Severity   string             `json:"severity"`
+	Message    string             `json:"message"`
+	Start      int64              `json:"start"`
+	End        int64              `json:"end"`
+	Line       int64              `json:"line"`
+	Column     int64              `json:"column"`
+	EndLine    int64              `json:"end_line,omitempty"`
+	EndColumn  int64              `json:"end_column,omitempty"`
+	Path       string             `json:"path,omitempty"`
+	Namespaces map[string]string  `json:"namespaces,omitempty"`
+	Snippet    string             `json:"snippet,omitempty"`
+	Roundtrip  *XMLRoundtripError `json:"roundtrip,omitempty"`
+	Suggestion string             `json:"suggestion,omitempty"`
+}
+
+// MarshalJSON describes the finding with its position, without repeating
+// the position in its message; round trip mismatches are described in
+// their own field
+func (f Finding) MarshalJSON() ([]byte, error) {
+	out := findingJSON{
+		Check:      f.Check,
+		Code:       f.Code,
+		Severity:   f.Severity.String(),
+		Message:    f.Message,
+		Start:      f.Start,
+		End:        f.End,
+		Line:       f.Line,
+		Column:     f.Column,
+		EndLine:    f.EndLine,
+		EndColumn:  f.EndColumn,
+		Path:       f.Path,
+		Namespaces: f.Namespaces,
+		Snippet:    f.Snippet,
+		Suggestion: f.Suggestion,
+	}
+	if f.Expected != nil {
+		out.Roundtrip = &XMLRoundtripError{Expected: f.Expected, Observed: f.Observed, Overflow: f.Overflow}
+	}
+	return json.Marshal(out)
+}
+
+// MarshalJSON describes the finding the way Finding does
+func (err XMLValidationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(err.Finding())
+}
+
+// formatToken renders a token as markup, without escaping or normalizing
+// anything, so names and values appear the way they were tokenized
+func formatToken(token xml.Token) string {
+	switch t := token.(type) {
+	case xml.StartElement:
+		markup := &strings.Builder{}
+		markup.WriteString("<" + qualifiedName(t.Name))
+		for _, attr := range t.Attr {
+			fmt.Fprintf(markup, ` %s="%s"`, qualifiedName(attr.Name), attr.Value)
+		}
+		markup.WriteString(">")
+		return markup.String()
+	case xml.EndElement:
+		return "</" + qualifiedName(t.Name) + ">"
+	case xml.CharData:
+		return string(t)
+	case xml.Comment:
+		return "<!--" + string(t) + "-->"
+	case xml.ProcInst:
+		if len(t.Inst) == 0 {
+			return "<?" + t.Target + "?>"
+		}
+		return "<?" + t.Target + " " + string(t.Inst) + "?>"
+	case xml.Directive:
+		return "<!" + string(t) + ">"
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", token)
+}
@@ -0,0 +1,61 @@
+package validator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	roundtripError := XMLRoundtripError{
+		Expected: xml.StartElement{Name: xml.Name{Local: ":Element"}, Attr: []xml.Attr{{Name: xml.Name{Space: "a", Local: "b"}, Value: "1"}}},
+		Observed: xml.StartElement{Name: xml.Name{Local: "Element"}, Attr: []xml.Attr{{Name: xml.Name{Local: "b"}, Value: "1"}}},
+	}
+	data, err := json.Marshal(XMLValidationError{
+		Start: 6, End: 24, Line: 1, Column: 7, EndLine: 1, EndColumn: 25,
+		Severity: SeverityError, Check: CheckRoundtrip, err: roundtripError,
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"check": "roundtrip",
+		"code": "colon-in-name",
+		"severity": "error",
+		"message": "`+strings.Replace(roundtripError.Error(), `"`, `\"`, -1)+`",
+		"start": 6, "end": 24, "line": 1, "column": 7, "end_line": 1, "end_column": 25,
+		"roundtrip": {
+			"code": "colon-in-name",
+			"message": "`+strings.Replace(roundtripError.Error(), `"`, `\"`, -1)+`",
+			"expected": "<:Element a:b=\"1\">",
+			"observed": "<Element b=\"1\">"
+		},
+		"suggestion": "write names with at most one colon, between a non-empty namespace prefix and local name"
+	}`, string(data), "Should marshal findings with their round trip error")
+
+	errs := New().ValidateAll(strings.NewReader("<Root>\n<!DOCTYPE x SYSTEM \"http://example.com/x.dtd\"></Root>"))
+	require.Len(t, errs, 1)
+	data, err = json.Marshal(errs[0])
+	require.NoError(t, err)
+	var finding map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &finding))
+	require.Equal(t, "warning", finding["severity"], "Should marshal the severity by name")
+	require.Equal(t, float64(2), finding["line"], "Should marshal the position")
+	require.NotContains(t, finding, "roundtrip", "Should only describe round trip errors of round trip findings")
+	require.False(t, strings.HasPrefix(finding["message"].(string), "validator:"), "Messages shouldn't repeat the position")
+
+	for _, test := range []struct {
+		token  xml.Token
+		markup string
+	}{
+		{xml.EndElement{Name: xml.Name{Space: "x", Local: "a"}}, "</x:a>"},
+		{xml.CharData("a < b"), "a < b"},
+		{xml.Comment(" c "), "<!-- c -->"},
+		{xml.ProcInst{Target: "t"}, "<?t?>"},
+		{xml.ProcInst{Target: "t", Inst: []byte("i")}, "<?t i?>"},
+		{xml.Directive("DOCTYPE x"), "<!DOCTYPE x>"},
+	} {
+		require.Equal(t, test.markup, formatToken(test.token), "Should render tokens as they were tokenized")
+	}
+}
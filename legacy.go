@@ -0,0 +1,123 @@
+package validator
+
+import (
+	"bytes"
+	"io"
+)
+
+// DualVerdict holds the verdicts of a document under the tokenization
+// semantics of different Go versions
+type DualVerdict struct {
+	// Legacy is the verdict under the semantics of Go 1.17 to 1.19, where
+	// names with several colons are tokenized as local names
+	Legacy error
+	// Current is the verdict under the semantics of the Go version the
+	// program is built with; since Go 1.20, names with several colons are
+	// syntax errors
+	Current error
+}
+
+// Changed reports whether the document passes validation under one of the
+// semantics, but not the other
+func (dv DualVerdict) Changed() bool {
+	return (dv.Legacy == nil) != (dv.Current == nil)
+}
+
+// ValidateDual validates the document under both legacy and current
+// tokenization semantics, reading it only once, so operators migrating
+// between Go versions can measure how many documents would change verdict.
+// The legacy semantics are emulated by renaming the names the current
+// tokenizer rejects before validating the document again; only the
+// current verdict is reported to statistics, metrics and finding sinks.
+func (v *Validator) ValidateDual(xmlReader io.Reader) DualVerdict {
+	xmlBytes, err := readAll(v.limitBytes(xmlReader))
+	if err != nil {
+		return DualVerdict{Legacy: err, Current: err}
+	}
+	verdict := DualVerdict{Current: v.Validate(bytes.NewReader(xmlBytes))}
+	rewritten, changed := rewriteLegacyNames(xmlBytes)
+	if !changed {
+		verdict.Legacy = verdict.Current
+		return verdict
+	}
+	legacy := *v
+	legacy.metrics = nil
+	legacy.sinks = nil
+	legacy.stats = &statsCounter{stats: map[CheckID]CheckStats{}}
+	verdict.Legacy = legacy.Validate(bytes.NewReader(rewritten))
+	return verdict
+}
+
+// rewriteLegacyNames returns a copy of the document where the colons of
+// element and attribute names with several colons are replaced with
+// underscores, which turns them into the same local names without
+// namespace prefix that Go 1.17 to 1.19 tokenized them as
+func rewriteLegacyNames(xmlBytes []byte) ([]byte, bool) {
+	rewritten := append([]byte(nil), xmlBytes...)
+	changed := false
+	rename := func(name []byte) string {
+		if bytes.Count(name, []byte(":")) > 1 {
+			for i := range name {
+				if name[i] == ':' {
+					name[i] = '_'
+				}
+			}
+			changed = true
+		}
+		return ""
+	}
+	for i := 0; i < len(rewritten); {
+		rest := rewritten[i:]
+		end := 1
+		switch {
+		case rest[0] != '<':
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end = skipPast(rest, 4, "-->")
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			end = skipPast(rest, 9, "]]>")
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end = skipPast(rest, 2, "?>")
+		case bytes.HasPrefix(rest, []byte("<!")):
+			end = skipDirective(rest)
+		default:
+			end, _ = scanTag(rest, rename)
+		}
+		if end < 0 {
+			break
+		}
+		i += end
+	}
+	return rewritten, changed
+}
+
+// skipDirective returns the length of the directive at the start of markup
+// the way encoding/xml delimits it, or -1 if it is unterminated
+func skipDirective(markup []byte) int {
+	depth := 0
+	var quote byte
+	for i := 2; i < len(markup); i++ {
+		c := markup[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case bytes.HasPrefix(markup[i:], []byte("<!--")):
+			end := skipPast(markup[i:], 4, "-->")
+			if end < 0 {
+				return -1
+			}
+			i += end - 1
+		case c == '<':
+			depth++
+		case c == '>':
+			if depth == 0 {
+				return i + 1
+			}
+			depth--
+		}
+	}
+	return -1
+}
@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDual(t *testing.T) {
+	v := New()
+
+	verdict := v.ValidateDual(strings.NewReader(`<Root><Element attr="value"/></Root>`))
+	require.NoError(t, verdict.Legacy, "Should pass on valid documents under legacy semantics")
+	require.NoError(t, verdict.Current, "Should pass on valid documents under current semantics")
+	require.False(t, verdict.Changed(), "Verdict shouldn't change on valid documents")
+
+	verdict = v.ValidateDual(strings.NewReader(`<Root>]]></Root>`))
+	require.Error(t, verdict.Legacy, "Should error on invalid documents under legacy semantics")
+	require.Error(t, verdict.Current, "Should error on invalid documents under current semantics")
+	require.False(t, verdict.Changed(), "Verdict shouldn't change on documents invalid under both semantics")
+
+	before := v.Stats()
+	verdict = v.ValidateDual(strings.NewReader(`<Root><!-- x::y --><x::Element ::attr="a:b:c"></x::Element></Root>`))
+	require.NoError(t, verdict.Legacy, "Names with several colons should pass under legacy semantics")
+	require.Equal(t, rejectsColons, verdict.Current != nil, "Names with several colons should be syntax errors since Go 1.20")
+	require.Equal(t, rejectsColons, verdict.Changed(), "Verdict should change on names with several colons since Go 1.20")
+	require.Equal(t, totalHits(before)+countIf(rejectsColons), totalHits(v.Stats()), "Only the current verdict should be counted")
+}
+
+func TestRewriteLegacyNames(t *testing.T) {
+	doc := `<?pi a::b?><!DOCTYPE x [ <!ENTITY a "x::y"> <!-- <x::y> --> ]><!-- <x::y> --><x::Root a:b:c="x::y"><![CDATA[<x::y>]]></x::Root>`
+	rewritten, changed := rewriteLegacyNames([]byte(doc))
+	require.True(t, changed, "Should rename names with several colons")
+	require.Equal(t, `<?pi a::b?><!DOCTYPE x [ <!ENTITY a "x::y"> <!-- <x::y> --> ]><!-- <x::y> --><x__Root a_b_c="x::y"><![CDATA[<x::y>]]></x__Root>`, string(rewritten),
+		"Should only rename element and attribute names")
+
+	_, changed = rewriteLegacyNames([]byte(`<x:Root x:a="b"/>`))
+	require.False(t, changed, "Shouldn't rename prefixed names")
+}
+
+func countIf(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func totalHits(stats map[CheckID]CheckStats) int64 {
+	var hits int64
+	for _, s := range stats {
+		hits += s.Hits
+	}
+	return hits
+}
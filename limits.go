@@ -0,0 +1,242 @@
+package validator
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// LimitError is the error wrapped by the findings of limit checks, telling
+// which limit a document exceeded and by how much
+type LimitError struct {
+	Check CheckID
+	// Limit is the configured limit, and Count the value that exceeded it
+	Limit, Count int
+}
+
+func (err *LimitError) Error() string {
+	switch err.Check {
+	case CheckNamespaceDeclarations:
+		return fmt.Sprintf("element declares %d namespaces, more than the limit of %d", err.Count, err.Limit)
+	case CheckChildren:
+		return fmt.Sprintf("element has more than the limit of %d children", err.Limit)
+	case CheckDepth:
+		return fmt.Sprintf("element is nested %d levels deep, more than the limit of %d", err.Count, err.Limit)
+	case CheckAttributes:
+		return fmt.Sprintf("element has %d attributes, more than the limit of %d", err.Count, err.Limit)
+	case CheckAttributeLength:
+		return fmt.Sprintf("attribute value is %d bytes long, more than the limit of %d", err.Count, err.Limit)
+	}
+	return fmt.Sprintf("%s: %d, more than the limit of %d", err.Check, err.Count, err.Limit)
+}
+
+// DefaultMaxNamespaceDeclarations is the limit applied by
+// CheckNamespaceDeclarations unless configured otherwise
+const DefaultMaxNamespaceDeclarations = 32
+
+// NamespaceDeclarationsConfig configures CheckNamespaceDeclarations
+type NamespaceDeclarationsConfig struct {
+	CheckConfig
+	// Max is the number of namespace declarations allowed on a single
+	// start element; zero or less uses DefaultMaxNamespaceDeclarations
+	Max int
+}
+
+// WithNamespaceDeclarationsCheck enables and configures
+// CheckNamespaceDeclarations
+func WithNamespaceDeclarationsCheck(cfg NamespaceDeclarationsConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckNamespaceDeclarations] = cfg.CheckConfig
+		v.namespaceDeclarations = cfg
+	}
+}
+
+// newNamespaceDeclarationsCheck creates the per-document state of
+// CheckNamespaceDeclarations
+func newNamespaceDeclarationsCheck(v *Validator) tokenCheck {
+	max := v.namespaceDeclarations.Max
+	if max <= 0 {
+		max = DefaultMaxNamespaceDeclarations
+	}
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		declarations := 0
+		for _, attr := range start.Attr {
+			if _, ok := declaredPrefix(attr); ok {
+				declarations++
+			}
+		}
+		if declarations > max {
+			return &LimitError{Check: CheckNamespaceDeclarations, Limit: max, Count: declarations}
+		}
+		return nil
+	}
+}
+
+// DefaultMaxChildren is the limit applied by CheckChildren unless
+// configured otherwise
+const DefaultMaxChildren = 10000
+
+// ChildrenConfig configures CheckChildren
+type ChildrenConfig struct {
+	CheckConfig
+	// Max is the number of child elements allowed in a single element, or
+	// of root elements in the document; zero or less uses DefaultMaxChildren
+	Max int
+}
+
+// WithChildrenCheck enables and configures CheckChildren
+func WithChildrenCheck(cfg ChildrenConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckChildren] = cfg.CheckConfig
+		v.children = cfg
+	}
+}
+
+// newChildrenCheck creates the per-document state of CheckChildren, which
+// reports the first child element over the limit of every element
+func newChildrenCheck(v *Validator) tokenCheck {
+	max := v.children.Max
+	if max <= 0 {
+		max = DefaultMaxChildren
+	}
+	// counts holds the number of children of each open element, after
+	// the number of root elements
+	counts := []int{0}
+	return func(d *document, token xml.Token) error {
+		switch token.(type) {
+		case xml.StartElement:
+			counts[len(counts)-1]++
+			count := counts[len(counts)-1]
+			counts = append(counts, 0)
+			if count == max+1 {
+				return &LimitError{Check: CheckChildren, Limit: max, Count: count}
+			}
+		case xml.EndElement:
+			if len(counts) > 1 {
+				counts = counts[:len(counts)-1]
+			}
+		}
+		return nil
+	}
+}
+
+// DefaultMaxDepth is the limit applied by CheckDepth unless configured
+// otherwise
+const DefaultMaxDepth = 256
+
+// DepthConfig configures CheckDepth
+type DepthConfig struct {
+	CheckConfig
+	// Max is the depth elements may be nested at, the root element being
+	// at depth 1; zero or less uses DefaultMaxDepth
+	Max int
+}
+
+// WithDepthCheck enables and configures CheckDepth
+func WithDepthCheck(cfg DepthConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckDepth] = cfg.CheckConfig
+		v.depth = cfg
+	}
+}
+
+// newDepthCheck creates the per-document state of CheckDepth, which
+// reports elements nested right below the limit, but not their
+// descendants, so a deep subtree is reported once
+func newDepthCheck(v *Validator) tokenCheck {
+	max := v.depth.Max
+	if max <= 0 {
+		max = DefaultMaxDepth
+	}
+	return func(d *document, token xml.Token) error {
+		if _, ok := token.(xml.StartElement); ok && len(d.path) == max+1 {
+			return &LimitError{Check: CheckDepth, Limit: max, Count: len(d.path)}
+		}
+		return nil
+	}
+}
+
+// DefaultMaxAttributes is the limit applied by CheckAttributes unless
+// configured otherwise
+const DefaultMaxAttributes = 256
+
+// AttributesConfig configures CheckAttributes
+type AttributesConfig struct {
+	CheckConfig
+	// Max is the number of attributes allowed on a single start element,
+	// namespace declarations included; zero or less uses
+	// DefaultMaxAttributes
+	Max int
+}
+
+// WithAttributesCheck enables and configures CheckAttributes
+func WithAttributesCheck(cfg AttributesConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckAttributes] = cfg.CheckConfig
+		v.attributes = cfg
+	}
+}
+
+// newAttributesCheck creates the per-document state of CheckAttributes
+func newAttributesCheck(v *Validator) tokenCheck {
+	max := v.attributes.Max
+	if max <= 0 {
+		max = DefaultMaxAttributes
+	}
+	return func(d *document, token xml.Token) error {
+		if start, ok := token.(xml.StartElement); ok && len(start.Attr) > max {
+			return &LimitError{Check: CheckAttributes, Limit: max, Count: len(start.Attr)}
+		}
+		return nil
+	}
+}
+
+// DefaultMaxAttributeLength is the limit applied by CheckAttributeLength
+// unless configured otherwise
+const DefaultMaxAttributeLength = 64 * 1024
+
+// AttributeLengthConfig configures CheckAttributeLength
+type AttributeLengthConfig struct {
+	CheckConfig
+	// Max is the length in bytes allowed for a single attribute value,
+	// once its references are replaced; zero or less uses
+	// DefaultMaxAttributeLength
+	Max int
+}
+
+// WithAttributeLengthCheck enables and configures CheckAttributeLength
+func WithAttributeLengthCheck(cfg AttributeLengthConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckAttributeLength] = cfg.CheckConfig
+		v.attributeLength = cfg
+	}
+}
+
+// newAttributeLengthCheck creates the per-document state of
+// CheckAttributeLength, which reports the longest attribute value of start
+// elements holding values over the limit
+func newAttributeLengthCheck(v *Validator) tokenCheck {
+	max := v.attributeLength.Max
+	if max <= 0 {
+		max = DefaultMaxAttributeLength
+	}
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		longest := 0
+		for _, attr := range start.Attr {
+			if len(attr.Value) > longest {
+				longest = len(attr.Value)
+			}
+		}
+		if longest > max {
+			return &LimitError{Check: CheckAttributeLength, Limit: max, Count: longest}
+		}
+		return nil
+	}
+}
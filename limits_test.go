@@ -0,0 +1,140 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespaceDeclarations(t *testing.T) {
+	declarations := func(n int) string {
+		var doc strings.Builder
+		doc.WriteString(`<Root xmlns="urn:default"`)
+		for i := 1; i < n; i++ {
+			doc.WriteString(` xmlns:p` + strings.Repeat("x", i) + `="urn:p"`)
+		}
+		doc.WriteString(` id="1"/>`)
+		return doc.String()
+	}
+
+	require.NoError(t, New().Validate(strings.NewReader(declarations(100))), "Should be disabled unless configured")
+
+	v := New(WithNamespaceDeclarationsCheck(NamespaceDeclarationsConfig{}))
+	require.NoError(t, v.Validate(strings.NewReader(declarations(DefaultMaxNamespaceDeclarations))), "Should allow declarations up to the default limit")
+	errs := v.ValidateAll(strings.NewReader(declarations(DefaultMaxNamespaceDeclarations + 1)))
+	require.Len(t, errs, 1, "Should report elements over the default limit")
+	require.Equal(t, CheckNamespaceDeclarations, errs[0].(XMLValidationError).Check, "Finding should be reported by the namespace declarations check")
+	require.Contains(t, errs[0].Error(), "element declares 33 namespaces, more than the limit of 32", "Should describe the declarations")
+	require.Equal(t, CategoryLimit, CheckNamespaceDeclarations.Category(), "Should be a limit check")
+	limitError := &LimitError{}
+	require.True(t, errors.As(errs[0], &limitError), "Should wrap a LimitError")
+	require.Equal(t, LimitError{Check: CheckNamespaceDeclarations, Limit: 32, Count: 33}, *limitError, "Should tell the limit and count")
+
+	v = New(WithNamespaceDeclarationsCheck(NamespaceDeclarationsConfig{Max: 2}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root xmlns:a="urn:a"><a:Child xmlns:b="urn:b" xmlns:c="urn:c"/></Root>`)), "Should count declarations per element")
+	require.Error(t, v.Validate(strings.NewReader(`<Root><Child xmlns="urn:a" xmlns:b="urn:b" xmlns:c="urn:c"/></Root>`)), "Should enforce configured limits")
+
+	v = New(WithNamespaceDeclarationsCheck(NamespaceDeclarationsConfig{Max: 2, CheckConfig: CheckConfig{Severity: SeverityWarning}}))
+	require.NoError(t, v.Validate(strings.NewReader(declarations(3))), "Should honor configured severities")
+}
+
+func TestChildren(t *testing.T) {
+	children := func(n int) string {
+		return `<Root>` + strings.Repeat(`<Child><Leaf/></Child>`, n) + `</Root>`
+	}
+
+	require.NoError(t, New().Validate(strings.NewReader(children(DefaultMaxChildren+1))), "Should be disabled unless configured")
+
+	v := New(WithChildrenCheck(ChildrenConfig{}))
+	require.NoError(t, v.Validate(strings.NewReader(children(DefaultMaxChildren))), "Should allow children up to the default limit")
+	errs := v.ValidateAll(strings.NewReader(children(DefaultMaxChildren + 10)))
+	require.Len(t, errs, 1, "Should only report the first child over the limit")
+	require.Equal(t, CheckChildren, errs[0].(XMLValidationError).Check, "Finding should be reported by the children check")
+	limitError := &LimitError{}
+	require.True(t, errors.As(errs[0], &limitError), "Should wrap a LimitError")
+	require.Equal(t, LimitError{Check: CheckChildren, Limit: DefaultMaxChildren, Count: DefaultMaxChildren + 1}, *limitError, "Should tell the limit and count")
+
+	v = New(WithChildrenCheck(ChildrenConfig{Max: 2}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root><A><B/><B/></A><A><B/><B/></A></Root>`)), "Should count children per element")
+	errs = v.ValidateAll(strings.NewReader(`<Root><A/><A><B/><B/><B/></A><A/></Root>`))
+	require.Len(t, errs, 2, "Should report every element over the limit")
+	require.Equal(t, int64(21), errs[0].(XMLValidationError).Start, "Should report the first nested child over the limit")
+	require.Equal(t, int64(29), errs[1].(XMLValidationError).Start, "Should report the first child of the root over the limit")
+	require.Error(t, v.Validate(strings.NewReader(`<A/><A/><A/>`)), "Should limit root elements")
+}
+
+func TestDepth(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat(`<A>`, n) + strings.Repeat(`</A>`, n)
+	}
+
+	require.NoError(t, New().Validate(strings.NewReader(nested(DefaultMaxDepth+1))), "Should be disabled unless configured")
+
+	v := New(WithDepthCheck(DepthConfig{}))
+	require.NoError(t, v.Validate(strings.NewReader(nested(DefaultMaxDepth))), "Should allow nesting up to the default limit")
+	errs := v.ValidateAll(strings.NewReader(nested(DefaultMaxDepth + 10)))
+	require.Len(t, errs, 1, "Should only report the outermost element over the limit")
+	require.Equal(t, CheckDepth, errs[0].(XMLValidationError).Check, "Finding should be reported by the depth check")
+	require.Contains(t, errs[0].Error(), "element is nested 257 levels deep, more than the limit of 256", "Should describe the nesting")
+	limitError := &LimitError{}
+	require.True(t, errors.As(errs[0], &limitError), "Should wrap a LimitError")
+	require.Equal(t, LimitError{Check: CheckDepth, Limit: DefaultMaxDepth, Count: DefaultMaxDepth + 1}, *limitError, "Should tell the limit and count")
+
+	v = New(WithDepthCheck(DepthConfig{Max: 2}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root><A/><A>text</A></Root>`)), "Should only count elements")
+	errs = v.ValidateAll(strings.NewReader(`<Root><A><B/></A><A><B><C/></B></A></Root>`))
+	require.Len(t, errs, 2, "Should report every subtree over the limit")
+	require.Equal(t, "/Root[1]/A[2]/B[1]", errs[1].(XMLValidationError).Path, "Should report the outermost element over the limit")
+}
+
+func TestAttributes(t *testing.T) {
+	attributes := func(n int) string {
+		var doc strings.Builder
+		doc.WriteString(`<Root`)
+		for i := 0; i < n; i++ {
+			doc.WriteString(` a` + strings.Repeat("x", i) + `="1"`)
+		}
+		doc.WriteString(`/>`)
+		return doc.String()
+	}
+
+	require.NoError(t, New().Validate(strings.NewReader(attributes(DefaultMaxAttributes+1))), "Should be disabled unless configured")
+
+	v := New(WithAttributesCheck(AttributesConfig{}))
+	require.NoError(t, v.Validate(strings.NewReader(attributes(DefaultMaxAttributes))), "Should allow attributes up to the default limit")
+	errs := v.ValidateAll(strings.NewReader(attributes(DefaultMaxAttributes + 1)))
+	require.Len(t, errs, 1, "Should report elements over the default limit")
+	require.Equal(t, CheckAttributes, errs[0].(XMLValidationError).Check, "Finding should be reported by the attributes check")
+	require.Contains(t, errs[0].Error(), "element has 257 attributes, more than the limit of 256", "Should describe the attributes")
+	limitError := &LimitError{}
+	require.True(t, errors.As(errs[0], &limitError), "Should wrap a LimitError")
+	require.Equal(t, LimitError{Check: CheckAttributes, Limit: DefaultMaxAttributes, Count: DefaultMaxAttributes + 1}, *limitError, "Should tell the limit and count")
+
+	v = New(WithAttributesCheck(AttributesConfig{Max: 2}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root a="1" b="2"><Child a="1" b="2"/></Root>`)), "Should count attributes per element")
+	require.Error(t, v.Validate(strings.NewReader(`<Root xmlns="urn:a" xmlns:b="urn:b" b:c="1"/>`)), "Should count namespace declarations")
+}
+
+func TestAttributeLength(t *testing.T) {
+	long := `<Root a="` + strings.Repeat("x", DefaultMaxAttributeLength+1) + `"/>`
+
+	require.NoError(t, New().Validate(strings.NewReader(long)), "Should be disabled unless configured")
+
+	v := New(WithAttributeLengthCheck(AttributeLengthConfig{}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root a="`+strings.Repeat("x", DefaultMaxAttributeLength)+`"/>`)),
+		"Should allow values up to the default limit")
+	errs := v.ValidateAll(strings.NewReader(long))
+	require.Len(t, errs, 1, "Should report values over the default limit")
+	require.Equal(t, CheckAttributeLength, errs[0].(XMLValidationError).Check, "Finding should be reported by the attribute length check")
+	limitError := &LimitError{}
+	require.True(t, errors.As(errs[0], &limitError), "Should wrap a LimitError")
+	require.Equal(t, LimitError{Check: CheckAttributeLength, Limit: DefaultMaxAttributeLength, Count: DefaultMaxAttributeLength + 1}, *limitError, "Should tell the limit and length")
+
+	v = New(WithAttributeLengthCheck(AttributeLengthConfig{Max: 3}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root a="&lt;&gt;&amp;"/>`)), "Should measure values once references are replaced")
+	errs = v.ValidateAll(strings.NewReader(`<Root a="1" b="1234" c="12345"/>`))
+	require.Len(t, errs, 1, "Should report elements once")
+	require.Contains(t, errs[0].Error(), "attribute value is 5 bytes long, more than the limit of 3", "Should report the longest value")
+}
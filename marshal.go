@@ -0,0 +1,158 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// MarshalStable is like xml.Marshal, but guarantees its output passes
+// Validate, returning the validation error instead if the value would
+// serialize to markup that doesn't survive round trips
+func MarshalStable(v interface{}) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := NewStableEncoder(buffer).Encode(v); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// StableEncoder wraps an xml.Encoder and makes sure everything it writes
+// passes Validate
+type StableEncoder struct {
+	encoder *xml.Encoder
+	w       io.Writer
+	buffer  bytes.Buffer
+}
+
+// NewStableEncoder returns a new StableEncoder writing to w
+func NewStableEncoder(w io.Writer) *StableEncoder {
+	e := &StableEncoder{w: w}
+	e.encoder = xml.NewEncoder(&e.buffer)
+	return e
+}
+
+// Indent is like xml.Encoder.Indent
+func (e *StableEncoder) Indent(prefix, indent string) {
+	e.encoder.Indent(prefix, indent)
+}
+
+// Encode is like xml.Encoder.Encode, but validates the encoded markup
+// before writing it to the underlying writer
+func (e *StableEncoder) Encode(v interface{}) error {
+	if err := e.encoder.Encode(v); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// EncodeElement is like xml.Encoder.EncodeElement, but validates the
+// encoded markup before writing it to the underlying writer
+func (e *StableEncoder) EncodeElement(v interface{}, start xml.StartElement) error {
+	if err := e.encoder.EncodeElement(v, start); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// EncodeToken is like xml.Encoder.EncodeToken, but returns an error
+// without encoding anything if the token wouldn't survive a round trip
+func (e *StableEncoder) EncodeToken(t xml.Token) error {
+	if err := CheckToken(t); err != nil {
+		return err
+	}
+	return e.encoder.EncodeToken(t)
+}
+
+// Flush is like xml.Encoder.Flush, but validates the encoded markup
+// before writing it to the underlying writer
+func (e *StableEncoder) Flush() error {
+	return e.flush()
+}
+
+// flush validates the buffered markup and writes it out; since encoding
+// may stop in the middle of an element, only the tokens themselves are
+// validated and unclosed elements are fine
+func (e *StableEncoder) flush() error {
+	if err := e.encoder.Flush(); err != nil {
+		return err
+	}
+	if err := Validate(bytes.NewReader(e.buffer.Bytes())); err != nil {
+		e.buffer.Reset()
+		return err
+	}
+	_, err := e.buffer.WriteTo(e.w)
+	return err
+}
+
+// VerifyEncodedOutput confirms that the markup a producer is about to sign
+// or send decodes back to the tokens it encoded, in order, as CheckToken
+// does for single tokens; tokens may be raw or have their namespaces
+// resolved. Adjacent character data tokens are compared as one, since
+// they decode as one, and whitespace added by xml.Encoder.Indent is
+// ignored. A mismatch is returned as an XMLRoundtripError, with markup
+// following the last token as its Overflow, and a missing token as one
+// whose Observed token is nil.
+func VerifyEncodedOutput(tokens []xml.Token, encoded []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(encoded))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	var previous, observed xml.Token
+	for _, expected := range mergeCharData(tokens) {
+		for {
+			token, err := decoder.RawToken()
+			if errors.Is(err, io.EOF) {
+				return XMLRoundtripError{Expected: expected}
+			} else if err != nil {
+				return err
+			}
+			observed = xml.CopyToken(token)
+			if _, ok := expected.(xml.CharData); ok || !isWhitespace(observed) {
+				break
+			}
+		}
+		if !tokenEquals(expected, observed) {
+			return XMLRoundtripError{Expected: expected, Observed: observed}
+		}
+		previous = expected
+	}
+	offset := decoder.InputOffset()
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !isWhitespace(token) {
+			return XMLRoundtripError{Expected: previous, Observed: observed, Overflow: append([]byte(nil), encoded[offset:]...)}
+		}
+	}
+}
+
+// mergeCharData returns the tokens with adjacent character data merged
+func mergeCharData(tokens []xml.Token) []xml.Token {
+	merged := make([]xml.Token, 0, len(tokens))
+	for _, token := range tokens {
+		data, ok := token.(xml.CharData)
+		if !ok {
+			merged = append(merged, token)
+			continue
+		}
+		if n := len(merged); n > 0 {
+			if last, ok := merged[n-1].(xml.CharData); ok {
+				merged[n-1] = append(last[:len(last):len(last)], data...)
+				continue
+			}
+		}
+		merged = append(merged, data)
+	}
+	return merged
+}
+
+// isWhitespace reports whether a token is character data made of
+// whitespace only
+func isWhitespace(token xml.Token) bool {
+	data, ok := token.(xml.CharData)
+	return ok && len(bytes.TrimSpace(data)) == 0
+}
@@ -0,0 +1,90 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stableDocument struct {
+	XMLName xml.Name `xml:"Root"`
+	ID      string   `xml:"ID,attr"`
+	Items   []string `xml:"Item"`
+	Note    string   `xml:",comment"`
+}
+
+func TestMarshalStable(t *testing.T) {
+	doc := stableDocument{ID: "1", Items: []string{"a", "b"}, Note: " note "}
+	expected, err := xml.Marshal(doc)
+	require.NoError(t, err, "Marshaling should succeed")
+
+	encoded, err := MarshalStable(doc)
+	require.NoError(t, err, "Should marshal stable values")
+	require.Equal(t, expected, encoded, "Output should match encoding/xml")
+	require.NoError(t, Validate(bytes.NewReader(encoded)), "Output should pass validation")
+
+	_, err = MarshalStable(make(chan int))
+	require.Error(t, err, "Should return marshaling errors")
+}
+
+func TestStableEncoder(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	e := NewStableEncoder(buffer)
+	e.Indent("", " ")
+
+	require.NoError(t, e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "Root"}}), "Should encode start elements")
+	require.NoError(t, e.EncodeElement("text", xml.StartElement{Name: xml.Name{Local: "Child"}}), "Should encode elements")
+	require.NoError(t, e.EncodeToken(xml.EndElement{Name: xml.Name{Local: "Root"}}), "Should encode end elements")
+	require.NoError(t, e.Flush(), "Should flush")
+	require.Equal(t, "<Root>\n <Child>text</Child>\n</Root>", buffer.String(), "Encoded output should be written out")
+
+	require.Error(t, e.EncodeToken(xml.Directive(`<!-- -->`)), "Should refuse to encode unstable tokens")
+}
+
+func TestVerifyEncodedOutput(t *testing.T) {
+	tokens := []xml.Token{
+		xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0"`)},
+		xml.StartElement{Name: xml.Name{Space: "urn:x", Local: "Root"}, Attr: []xml.Attr{{Name: xml.Name{Local: "ID"}, Value: "1"}}},
+		xml.StartElement{Name: xml.Name{Local: "Child"}},
+		xml.CharData("a < "),
+		xml.CharData("b"),
+		xml.EndElement{Name: xml.Name{Local: "Child"}},
+		xml.Comment(" note "),
+		xml.EndElement{Name: xml.Name{Space: "urn:x", Local: "Root"}},
+	}
+	for _, indent := range []string{"", "  "} {
+		buffer := &bytes.Buffer{}
+		encoder := xml.NewEncoder(buffer)
+		encoder.Indent("", indent)
+		for _, token := range tokens {
+			require.NoError(t, encoder.EncodeToken(token))
+		}
+		require.NoError(t, encoder.Flush())
+		require.NoError(t, VerifyEncodedOutput(tokens, buffer.Bytes()), "Should verify encoder output %s", buffer)
+	}
+
+	encoded := []byte(`<Root><Child>a &lt; b</Child><!-- note --></Root>`)
+	tokens = []xml.Token{
+		xml.StartElement{Name: xml.Name{Local: "Root"}},
+		xml.StartElement{Name: xml.Name{Local: "Child"}},
+		xml.CharData("a < b"),
+		xml.EndElement{Name: xml.Name{Local: "Child"}},
+		xml.Comment(" note "),
+		xml.EndElement{Name: xml.Name{Local: "Root"}},
+	}
+	require.NoError(t, VerifyEncodedOutput(tokens, encoded))
+
+	err := VerifyEncodedOutput(tokens, []byte(`<Root><Child>a &lt; c</Child><!-- note --></Root>`))
+	require.Equal(t, XMLRoundtripError{Expected: xml.CharData("a < b"), Observed: xml.CharData("a < c")}, err, "Should report the first mismatching token")
+
+	err = VerifyEncodedOutput(tokens[:5], encoded)
+	require.Equal(t, XMLRoundtripError{Expected: tokens[4], Observed: xml.Comment(" note "), Overflow: []byte(`</Root>`)}, err, "Should report markup following the tokens")
+
+	err = VerifyEncodedOutput(tokens, encoded[:len(encoded)-7])
+	require.Equal(t, XMLRoundtripError{Expected: tokens[5]}, err, "Should report missing tokens")
+
+	err = VerifyEncodedOutput(tokens, []byte(`<Root><Child>]]></Child></Root>`))
+	require.IsType(t, &xml.SyntaxError{}, err, "Should return syntax errors")
+}
@@ -0,0 +1,106 @@
+package validator
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// EmptyNamesConfig configures CheckEmptyNames
+type EmptyNamesConfig struct {
+	CheckConfig
+}
+
+// WithEmptyNamesCheck enables and configures CheckEmptyNames
+func WithEmptyNamesCheck(cfg EmptyNamesConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckEmptyNames] = cfg.CheckConfig
+	}
+}
+
+// newEmptyNamesCheck creates the per-document state of CheckEmptyNames
+func newEmptyNamesCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			if part := emptyNamePart(t.Name); part != "" {
+				return fmt.Errorf("element name %q has an empty %s", qualifiedName(t.Name), part)
+			}
+			for _, attr := range t.Attr {
+				if part := emptyNamePart(attr.Name); part != "" {
+					return fmt.Errorf("attribute name %q has an empty %s", qualifiedName(attr.Name), part)
+				}
+			}
+		case xml.EndElement:
+			if part := emptyNamePart(t.Name); part != "" {
+				return fmt.Errorf("element name %q has an empty %s", qualifiedName(t.Name), part)
+			}
+		}
+		return nil
+	}
+}
+
+// emptyNamePart returns which part of a name, as written in the document,
+// is empty: its namespace prefix, local name or both; it returns an empty
+// string for names with neither part empty. Names are looked at as written
+// since Go versions split them into prefix and local name differently.
+func emptyNamePart(name xml.Name) string {
+	qualified := qualifiedName(name)
+	i := strings.Index(qualified, ":")
+	switch {
+	case qualified == "" || qualified == ":":
+		return "prefix and local name"
+	case i == 0:
+		return "prefix"
+	case i == len(qualified)-1:
+		return "local name"
+	}
+	return ""
+}
+
+// NameColonsConfig configures CheckNameColons
+type NameColonsConfig struct {
+	CheckConfig
+}
+
+// WithNameColonsCheck enables and configures CheckNameColons
+func WithNameColonsCheck(cfg NameColonsConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckNameColons] = cfg.CheckConfig
+	}
+}
+
+// newNameColonsCheck creates the per-document state of CheckNameColons
+func newNameColonsCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			if strings.Count(qualifiedName(t.Name), ":") > 1 {
+				return fmt.Errorf("element name %q has several colons", qualifiedName(t.Name))
+			}
+			for _, attr := range t.Attr {
+				if strings.Count(qualifiedName(attr.Name), ":") > 1 {
+					return fmt.Errorf("attribute name %q has several colons", qualifiedName(attr.Name))
+				}
+			}
+		case xml.EndElement:
+			if strings.Count(qualifiedName(t.Name), ":") > 1 {
+				return fmt.Errorf("element name %q has several colons", qualifiedName(t.Name))
+			}
+		}
+		return nil
+	}
+}
+
+// StrictLegacy restores the strictness of Go 1.16 and older, whose
+// encoding/xml mutated names with an empty namespace prefix or local name
+// and names with several colons, failing the round trip check: it enables
+// CheckEmptyNames and CheckNameColons, so such names fail validation
+// whichever Go version the program is built with, instead of passing
+// with Go 1.17 and newer
+func StrictLegacy() Option {
+	return func(v *Validator) {
+		WithEmptyNamesCheck(EmptyNamesConfig{})(v)
+		WithNameColonsCheck(NameColonsConfig{})(v)
+	}
+}
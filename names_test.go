@@ -0,0 +1,66 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// findingsOf returns the findings of a single check
+func findingsOf(errs []error, id CheckID) []error {
+	found := []error{}
+	for _, err := range errs {
+		if validationError, ok := err.(XMLValidationError); ok && validationError.Check == id {
+			found = append(found, err)
+		}
+	}
+	return found
+}
+
+func TestEmptyNamesCheck(t *testing.T) {
+	v := New(WithEmptyNamesCheck(EmptyNamesConfig{}))
+	require.Empty(t, findingsOf(v.ValidateAll(strings.NewReader(`<Root xmlns:x="urn:x" x:a="1"><x:Child/></Root>`)), CheckEmptyNames),
+		"Should pass names with a prefix and local name")
+
+	for doc, message := range map[string]string{
+		`<x:></x:>`:                    `element name "x:" has an empty local name`,
+		`<:Root/>`:                     `element name ":Root" has an empty prefix`,
+		`<Root :="value"/>`:            `attribute name ":" has an empty prefix and local name`,
+		`<Root x:="value"/>`:           `attribute name "x:" has an empty local name`,
+		`<Root xmlns:="urn:x"/>`:       `attribute name "xmlns:" has an empty local name`,
+		`<Root><Child :a="1"/></Root>`: `attribute name ":a" has an empty prefix`,
+	} {
+		errs := findingsOf(v.ValidateAll(strings.NewReader(doc)), CheckEmptyNames)
+		require.NotEmpty(t, errs, "Should report empty name parts in %s regardless of the Go version", doc)
+		require.Contains(t, errs[0].Error(), message, "Should describe the empty part")
+		require.Equal(t, SeverityError, SeverityOf(errs[0]), "Empty names should be errors by default")
+		require.Error(t, v.Validate(strings.NewReader(doc)), "Should fail validation of %s", doc)
+	}
+
+	require.Empty(t, findingsOf(New().ValidateAll(strings.NewReader(`<x:></x:>`)), CheckEmptyNames), "Should be disabled unless configured")
+}
+
+func TestStrictLegacy(t *testing.T) {
+	v := New(StrictLegacy())
+	for _, doc := range []string{`<x:></x:>`, `<Root :="value"/>`, `<Root x:="value"/>`, `<x::Root/>`, `<Root ::attr="x"/>`} {
+		require.Error(t, v.Validate(strings.NewReader(doc)), "Should fail validation of %s on every Go version", doc)
+	}
+	require.NoError(t, v.Validate(strings.NewReader(`<Root xmlns:x="urn:x" x:a="1"><x:Child/></Root>`)), "Should pass regular names")
+
+	check := newNameColonsCheck(v)
+	for _, c := range []struct {
+		token   xml.Token
+		message string
+	}{
+		{xml.EndElement{Name: xml.Name{Local: "x::Root"}}, `element name "x::Root" has several colons`},
+		{xml.EndElement{Name: xml.Name{Space: "x", Local: ":Root"}}, `element name "x::Root" has several colons`},
+		{xml.StartElement{Name: xml.Name{Local: "Root"}, Attr: []xml.Attr{{Name: xml.Name{Local: "a:b:c"}}}}, `attribute name "a:b:c" has several colons`},
+	} {
+		err := check(nil, c.token)
+		require.Error(t, err, "Should report names tokenized with several colons")
+		require.Equal(t, c.message, err.Error())
+	}
+	require.NoError(t, check(nil, xml.StartElement{Name: xml.Name{Space: "x", Local: "Root"}}))
+}
@@ -0,0 +1,479 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+const (
+	xmlPrefix   = "xml"
+	xmlnsPrefix = "xmlns"
+	xmlURL      = "http://www.w3.org/XML/1998/namespace"
+	xmlnsURL    = "http://www.w3.org/2000/xmlns/"
+)
+
+// namespaceBinding binds a prefix to a namespace URI; the empty prefix
+// holds the default namespace
+type namespaceBinding struct {
+	prefix, uri string
+}
+
+// declaredPrefix returns the prefix declared by a namespace declaration,
+// which is empty for the default namespace
+func declaredPrefix(attr xml.Attr) (string, bool) {
+	switch {
+	case attr.Name.Space == xmlnsPrefix:
+		return attr.Name.Local, true
+	case attr.Name.Space == "" && attr.Name.Local == xmlnsPrefix:
+		return "", true
+	}
+	return "", false
+}
+
+// pushNamespaces adds the bindings declared by a start element to the scope
+func (d *document) pushNamespaces(start xml.StartElement) {
+	d.scopes = append(d.scopes, len(d.bindings))
+	for _, attr := range start.Attr {
+		if prefix, ok := declaredPrefix(attr); ok {
+			d.bindings = append(d.bindings, namespaceBinding{prefix, attr.Value})
+		}
+	}
+}
+
+// popNamespaces removes the bindings declared by the innermost open element
+func (d *document) popNamespaces() {
+	if len(d.scopes) == 0 {
+		return
+	}
+	d.bindings = d.bindings[:d.scopes[len(d.scopes)-1]]
+	d.scopes = d.scopes[:len(d.scopes)-1]
+}
+
+// lookupNamespace returns the URI bound to prefix in the current scope
+func (d *document) lookupNamespace(prefix string) (string, bool) {
+	for i := len(d.bindings) - 1; i >= 0; i-- {
+		if d.bindings[i].prefix == prefix {
+			return d.bindings[i].uri, true
+		}
+	}
+	return "", false
+}
+
+// namespaceContext returns the bindings in the current scope, with inner
+// declarations shadowing outer ones
+func (d *document) namespaceContext() map[string]string {
+	context := make(map[string]string, len(d.bindings))
+	for _, binding := range d.bindings {
+		context[binding.prefix] = binding.uri
+	}
+	return context
+}
+
+// equivalent reports whether a token and its round trip have the same names
+// once resolved to their namespace URI, ignoring namespace declarations;
+// the round trip was decoded on its own, so its own declarations are looked
+// up before those in scope
+func (d *document) equivalent(before, after xml.Token) bool {
+	switch b := before.(type) {
+	case xml.StartElement:
+		a, ok := after.(xml.StartElement)
+		if !ok {
+			return false
+		}
+		declared := map[string]string{}
+		for _, attr := range a.Attr {
+			if prefix, ok := declaredPrefix(attr); ok {
+				declared[prefix] = attr.Value
+			}
+		}
+		resolveAfter := func(name xml.Name, isElementName bool) xml.Name {
+			if uri, ok := declared[name.Space]; ok && (name.Space != "" || isElementName) {
+				name.Space = uri
+				return name
+			}
+			return d.resolveName(name, isElementName)
+		}
+		if d.resolveName(b.Name, true) != resolveAfter(a.Name, true) {
+			return false
+		}
+		beforeAttrs, afterAttrs := []xml.Attr{}, []xml.Attr{}
+		for _, attr := range b.Attr {
+			if _, ok := declaredPrefix(attr); !ok {
+				beforeAttrs = append(beforeAttrs, xml.Attr{Name: d.resolveName(attr.Name, false), Value: attr.Value})
+			}
+		}
+		for _, attr := range a.Attr {
+			if _, ok := declaredPrefix(attr); !ok {
+				afterAttrs = append(afterAttrs, xml.Attr{Name: resolveAfter(attr.Name, false), Value: attr.Value})
+			}
+		}
+		if len(beforeAttrs) != len(afterAttrs) {
+			return false
+		}
+		for i := range beforeAttrs {
+			if beforeAttrs[i] != afterAttrs[i] {
+				return false
+			}
+		}
+		return true
+	case xml.EndElement:
+		a, ok := after.(xml.EndElement)
+		return ok && d.resolveName(b.Name, true) == d.resolveName(a.Name, true)
+	}
+	return false
+}
+
+// checkResolvedToken runs a token, with its names resolved the way
+// xml.Decoder.Token returns them, through xml.Encoder and xml.Decoder.Token,
+// returning an XMLRoundtripError if its names or attributes come back
+// different; the encoder declares namespaces of its own, so namespace
+// declarations are left out of both the token and its round trip. Only
+// start and end elements are checked, since resolution leaves other tokens
+// untouched.
+func checkResolvedToken(before xml.Token) error {
+	var start xml.StartElement
+	switch t := before.(type) {
+	case xml.StartElement:
+		start = withoutDeclarations(t)
+		before = start
+	case xml.EndElement:
+		start = xml.StartElement{Name: t.Name}
+	default:
+		return nil
+	}
+	encoded := &bytes.Buffer{}
+	encoder := xml.NewEncoder(encoded)
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if end, ok := before.(xml.EndElement); ok {
+		if err := encoder.EncodeToken(end); err != nil {
+			return err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	decoder := xml.NewDecoder(encoded)
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	after, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if _, ok := before.(xml.EndElement); ok {
+		if after, err = decoder.Token(); err != nil {
+			return err
+		}
+	}
+	if observed, ok := after.(xml.StartElement); ok {
+		after = withoutDeclarations(observed)
+	}
+	if !resolvedEquals(before, after) {
+		return XMLRoundtripError{Expected: before, Observed: after}
+	}
+	return nil
+}
+
+// resolvedEquals reports whether two resolved elements have the same names
+// and attributes, in the same order
+func resolvedEquals(before, after xml.Token) bool {
+	switch b := before.(type) {
+	case xml.StartElement:
+		a, ok := after.(xml.StartElement)
+		if !ok || a.Name != b.Name || len(a.Attr) != len(b.Attr) {
+			return false
+		}
+		for i := range b.Attr {
+			if a.Attr[i] != b.Attr[i] {
+				return false
+			}
+		}
+		return true
+	case xml.EndElement:
+		a, ok := after.(xml.EndElement)
+		return ok && a.Name == b.Name
+	}
+	return false
+}
+
+// withoutDeclarations returns a copy of a start element without its
+// namespace declarations
+func withoutDeclarations(start xml.StartElement) xml.StartElement {
+	attrs := []xml.Attr{}
+	for _, attr := range start.Attr {
+		if _, ok := declaredPrefix(attr); !ok {
+			attrs = append(attrs, attr)
+		}
+	}
+	start.Attr = attrs
+	return start
+}
+
+// resolveName translates a raw name into its namespace URI the same way
+// xml.Decoder.Token does, leaving unbound prefixes untouched
+func (d *document) resolveName(name xml.Name, isElementName bool) xml.Name {
+	switch {
+	case name.Space == xmlnsPrefix:
+		return name
+	case name.Space == "" && !isElementName:
+		return name
+	case name.Space == xmlPrefix:
+		name.Space = xmlURL
+		return name
+	case name.Space == "" && name.Local == xmlnsPrefix:
+		return name
+	}
+	if uri, ok := d.lookupNamespace(name.Space); ok {
+		name.Space = uri
+	}
+	return name
+}
+
+// resolveToken returns a copy of the token with element and attribute
+// names translated into namespace URIs
+func (d *document) resolveToken(token xml.Token) xml.Token {
+	switch t := token.(type) {
+	case xml.StartElement:
+		t = t.Copy()
+		t.Name = d.resolveName(t.Name, true)
+		for i := range t.Attr {
+			t.Attr[i].Name = d.resolveName(t.Attr[i].Name, false)
+		}
+		return t
+	case xml.EndElement:
+		t.Name = d.resolveName(t.Name, true)
+		return t
+	}
+	return xml.CopyToken(token)
+}
+
+// XMLNamespaceError is returned when a token uses or declares a namespace
+// prefix in a way parsers disagree on
+type XMLNamespaceError struct {
+	// Prefix is the offending prefix; it is empty for the default namespace
+	Prefix string
+	// Reason describes the problem
+	Reason string
+}
+
+func (err XMLNamespaceError) Error() string {
+	if err.Prefix == "" {
+		return fmt.Sprintf("default namespace %s", err.Reason)
+	}
+	return fmt.Sprintf("namespace prefix %q %s", err.Prefix, err.Reason)
+}
+
+// UndeclaredPrefixConfig configures CheckUndeclaredPrefix
+type UndeclaredPrefixConfig struct {
+	CheckConfig
+}
+
+// WithUndeclaredPrefixCheck enables and configures CheckUndeclaredPrefix
+func WithUndeclaredPrefixCheck(cfg UndeclaredPrefixConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckUndeclaredPrefix] = cfg.CheckConfig
+	}
+}
+
+// newUndeclaredPrefixCheck creates the per-document state of CheckUndeclaredPrefix
+func newUndeclaredPrefixCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		for _, attr := range start.Attr {
+			if err := checkNamespaceDeclaration(attr); err != nil {
+				return err
+			}
+		}
+		if err := d.checkPrefixDeclared(start.Name.Space); err != nil {
+			return err
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Space == xmlnsPrefix {
+				continue
+			}
+			if err := d.checkPrefixDeclared(attr.Name.Space); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// checkNamespaceDeclaration makes sure a namespace declaration doesn't
+// abuse the reserved xml and xmlns prefixes and namespaces
+func checkNamespaceDeclaration(attr xml.Attr) error {
+	prefix, ok := declaredPrefix(attr)
+	if !ok {
+		return nil
+	}
+	switch {
+	case prefix == xmlnsPrefix:
+		return XMLNamespaceError{prefix, "must not be declared"}
+	case prefix == xmlPrefix && attr.Value != xmlURL:
+		return XMLNamespaceError{prefix, fmt.Sprintf("must not be bound to anything but %s", xmlURL)}
+	case prefix != xmlPrefix && (attr.Value == xmlURL || attr.Value == xmlnsURL):
+		return XMLNamespaceError{prefix, fmt.Sprintf("must not be bound to the reserved namespace %s", attr.Value)}
+	case prefix != "" && attr.Value == "":
+		return XMLNamespaceError{prefix, "must not be undeclared"}
+	}
+	return nil
+}
+
+// checkPrefixDeclared makes sure a prefix used in a name is declared in scope
+func (d *document) checkPrefixDeclared(prefix string) error {
+	switch prefix {
+	case "", xmlPrefix:
+		return nil
+	case xmlnsPrefix:
+		return XMLNamespaceError{prefix, "is reserved for namespace declarations"}
+	}
+	if _, ok := d.lookupNamespace(prefix); !ok {
+		return XMLNamespaceError{prefix, "is not declared"}
+	}
+	return nil
+}
+
+// NamespaceURIConfig configures CheckNamespaceURI
+type NamespaceURIConfig struct {
+	CheckConfig
+}
+
+// WithNamespaceURICheck configures CheckNamespaceURI
+func WithNamespaceURICheck(cfg NamespaceURIConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckNamespaceURI] = cfg.CheckConfig
+	}
+}
+
+// newNamespaceURICheck creates the per-document state of CheckNamespaceURI
+func newNamespaceURICheck(v *Validator) tokenCheck {
+	// seen maps normalized namespace URIs to the first spelling declared
+	seen := map[string]string{}
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		for _, attr := range start.Attr {
+			prefix, ok := declaredPrefix(attr)
+			if !ok {
+				continue
+			}
+			normalized := normalizeNamespaceURI(attr.Value)
+			first, ok := seen[normalized]
+			if !ok {
+				seen[normalized] = attr.Value
+				continue
+			}
+			if first != attr.Value {
+				return XMLNamespaceError{prefix, fmt.Sprintf("is bound to %q, which only differs from %q by case, percent-encoding or a trailing slash", attr.Value, first)}
+			}
+		}
+		return nil
+	}
+}
+
+// DuplicateDeclarationsConfig configures CheckDuplicateDeclarations
+type DuplicateDeclarationsConfig struct {
+	CheckConfig
+}
+
+// WithDuplicateDeclarationsCheck enables and configures CheckDuplicateDeclarations
+func WithDuplicateDeclarationsCheck(cfg DuplicateDeclarationsConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckDuplicateDeclarations] = cfg.CheckConfig
+	}
+}
+
+// newDuplicateDeclarationsCheck creates the per-document state of
+// CheckDuplicateDeclarations
+func newDuplicateDeclarationsCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		var declared map[string]string
+		for _, attr := range start.Attr {
+			prefix, ok := declaredPrefix(attr)
+			if !ok {
+				continue
+			}
+			if declared == nil {
+				declared = map[string]string{}
+			}
+			first, ok := declared[prefix]
+			switch {
+			case ok && first == attr.Value:
+				return XMLNamespaceError{prefix, "is declared more than once on the element"}
+			case ok:
+				return XMLNamespaceError{prefix, fmt.Sprintf("is declared as both %q and %q on the element", first, attr.Value)}
+			}
+			declared[prefix] = attr.Value
+		}
+		return nil
+	}
+}
+
+// normalizeNamespaceURI loosely normalizes a namespace URI the way some
+// consumers compare them, while the namespaces spec compares them strictly
+func normalizeNamespaceURI(uri string) string {
+	if unescaped, err := url.PathUnescape(uri); err == nil {
+		uri = unescaped
+	}
+	return strings.TrimRight(strings.ToLower(uri), "/")
+}
+
+// DefaultScopeAttributesConfig configures CheckDefaultScopeAttributes
+type DefaultScopeAttributesConfig struct {
+	CheckConfig
+	// Names lists the local names of the attributes to report; by default,
+	// the ID attributes used by XML signatures and SAML are reported
+	Names []string
+}
+
+// WithDefaultScopeAttributesCheck enables and configures
+// CheckDefaultScopeAttributes
+func WithDefaultScopeAttributesCheck(cfg DefaultScopeAttributesConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckDefaultScopeAttributes] = cfg.CheckConfig
+		v.defaultScopeAttributes = cfg
+	}
+}
+
+// newDefaultScopeAttributesCheck creates the per-document state of
+// CheckDefaultScopeAttributes
+func newDefaultScopeAttributesCheck(v *Validator) tokenCheck {
+	names := v.defaultScopeAttributes.Names
+	if names == nil {
+		names = []string{"ID", "Id", "id"}
+	}
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Space != "" {
+			return nil
+		}
+		uri, ok := d.lookupNamespace("")
+		if !ok || uri == "" {
+			return nil
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Space != "" {
+				continue
+			}
+			for _, name := range names {
+				if attr.Name.Local == name {
+					return XMLNamespaceError{"", fmt.Sprintf("%q doesn't apply to attribute %s of element %s, which is in no namespace", uri, name, start.Name.Local)}
+				}
+			}
+		}
+		return nil
+	}
+}
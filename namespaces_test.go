@@ -0,0 +1,222 @@
+package validator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUndeclaredPrefix(t *testing.T) {
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<x:Root/>`)), "Undeclared prefixes should be allowed by default")
+
+	v := New(WithUndeclaredPrefixCheck(UndeclaredPrefixConfig{}))
+	valid := []string{
+		`<Root></Root>`,
+		`<x:Root xmlns:x="urn:x"><x:Child x:attr="1" xml:lang="en"/></x:Root>`,
+		`<Root xmlns:x="urn:x"><Child x:attr="1"/></Root>`,
+		`<Root xmlns:xml="http://www.w3.org/XML/1998/namespace" xmlns="urn:default"/>`,
+	}
+	for _, doc := range valid {
+		require.Empty(t, v.ValidateAll(strings.NewReader(doc)), "Should pass on documents declaring every prefix: %s", doc)
+	}
+
+	invalid := map[string]XMLNamespaceError{
+		`<x:Root/>`: {"x", "is not declared"},
+		`<Root><x:Child xmlns:y="urn:y"/></Root>`:                {"x", "is not declared"},
+		`<Root x:attr="1"/>`:                                     {"x", "is not declared"},
+		`<Root><Child xmlns:x="urn:x"/><x:Child/></Root>`:        {"x", "is not declared"},
+		`<xmlns:Root/>`:                                          {"xmlns", "is reserved for namespace declarations"},
+		`<Root xmlns:xmlns="urn:x"/>`:                            {"xmlns", "must not be declared"},
+		`<Root xmlns:xml="urn:x"/>`:                              {"xml", "must not be bound to anything but http://www.w3.org/XML/1998/namespace"},
+		`<Root xmlns:x="http://www.w3.org/XML/1998/namespace"/>`: {"x", "must not be bound to the reserved namespace http://www.w3.org/XML/1998/namespace"},
+		`<Root xmlns="http://www.w3.org/2000/xmlns/"/>`:          {"", "must not be bound to the reserved namespace http://www.w3.org/2000/xmlns/"},
+		`<Root xmlns:x="urn:x"><x:Child xmlns:x=""/></Root>`:     {"x", "must not be undeclared"},
+	}
+	for doc, expected := range invalid {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report exactly one error in %s", doc)
+		require.Equal(t, CheckUndeclaredPrefix, errs[0].(XMLValidationError).Check, "Finding should be reported by the undeclared prefix check")
+		require.Equal(t, SeverityError, SeverityOf(errs[0]), "Undeclared prefixes should be errors once enabled")
+		namespaceError := XMLNamespaceError{}
+		require.True(t, errors.As(errs[0], &namespaceError), "Error should be an XMLNamespaceError")
+		require.Equal(t, expected, namespaceError, "Error should describe the problem in %s", doc)
+	}
+
+	require.Equal(t, `namespace prefix "x" is not declared`, XMLNamespaceError{"x", "is not declared"}.Error(),
+		"Namespace error message should match expectation")
+	require.Equal(t, `default namespace must not be bound to the reserved namespace urn:x`, XMLNamespaceError{"", "must not be bound to the reserved namespace urn:x"}.Error(),
+		"Namespace error message for the default namespace should match expectation")
+}
+
+func TestNamespaceURI(t *testing.T) {
+	valid := []string{
+		`<Root xmlns="http://example.com/ns"><x:Child xmlns:x="http://example.com/ns"/></Root>`,
+		`<Root xmlns:a="urn:a" xmlns:b="urn:b"/>`,
+	}
+	for _, doc := range valid {
+		require.Empty(t, New().ValidateAll(strings.NewReader(doc)), "Should pass on documents with consistent namespace URIs: %s", doc)
+	}
+
+	invalid := map[string]XMLNamespaceError{
+		`<Root xmlns="http://example.com/ns"><x:Child xmlns:x="http://example.com/NS/"/></Root>`: {"x", `is bound to "http://example.com/NS/", which only differs from "http://example.com/ns" by case, percent-encoding or a trailing slash`},
+		`<Root xmlns:a="urn:a:b" xmlns:b="urn:a%3Ab"/>`:                                          {"b", `is bound to "urn:a%3Ab", which only differs from "urn:a:b" by case, percent-encoding or a trailing slash`},
+		`<Root xmlns:a="urn:a"><Child xmlns="urn:a/"/></Root>`:                                   {"", `is bound to "urn:a/", which only differs from "urn:a" by case, percent-encoding or a trailing slash`},
+	}
+	for doc, expected := range invalid {
+		errs := New().ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report exactly one finding in %s", doc)
+		require.Equal(t, CheckNamespaceURI, errs[0].(XMLValidationError).Check, "Finding should be reported by the namespace URI check")
+		require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Similar namespace URIs should be warnings by default")
+		namespaceError := XMLNamespaceError{}
+		require.True(t, errors.As(errs[0], &namespaceError), "Error should be an XMLNamespaceError")
+		require.Equal(t, expected, namespaceError, "Error should describe the problem in %s", doc)
+	}
+}
+
+func TestDuplicateDeclarations(t *testing.T) {
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Root xmlns="urn:a" xmlns="urn:b"/>`)), "Duplicate declarations should be allowed by default")
+
+	v := New(WithDuplicateDeclarationsCheck(DuplicateDeclarationsConfig{}))
+	valid := []string{
+		`<Root xmlns="urn:a" xmlns:a="urn:a"><Child xmlns="urn:b" xmlns:a="urn:b"/></Root>`,
+		`<a:Root xmlns:a="urn:a" xmlns:b="urn:a"/>`,
+	}
+	for _, doc := range valid {
+		require.Empty(t, v.ValidateAll(strings.NewReader(doc)), "Should pass on prefixes declared once per element: %s", doc)
+	}
+
+	invalid := map[string]XMLNamespaceError{
+		`<Root xmlns="http://example.com/1" xmlns="http://example.com/2"></Root>`: {"", `is declared as both "http://example.com/1" and "http://example.com/2" on the element`},
+		`<Root><x:Child xmlns:x="urn:a" xmlns:x="urn:b"/></Root>`:                 {"x", `is declared as both "urn:a" and "urn:b" on the element`},
+		`<Root xmlns:x="urn:a" xmlns:y="urn:b" xmlns:x="urn:a"/>`:                 {"x", "is declared more than once on the element"},
+	}
+	for doc, expected := range invalid {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report exactly one finding in %s", doc)
+		require.Equal(t, CheckDuplicateDeclarations, errs[0].(XMLValidationError).Check, "Finding should be reported by the duplicate declarations check")
+		require.Equal(t, SeverityError, SeverityOf(errs[0]), "Duplicate declarations should be errors once enabled")
+		namespaceError := XMLNamespaceError{}
+		require.True(t, errors.As(errs[0], &namespaceError), "Error should be an XMLNamespaceError")
+		require.Equal(t, expected, namespaceError, "Error should describe the problem in %s", doc)
+	}
+}
+
+func TestDefaultScopeAttributes(t *testing.T) {
+	v := New(WithDefaultScopeAttributesCheck(DefaultScopeAttributesConfig{}))
+	for _, doc := range []string{
+		`<Response ID="1"><Assertion ID="2"/></Response>`,
+		`<samlp:Response xmlns:samlp="urn:p" ID="1"/>`,
+		`<Response xmlns="urn:p" Version="2.0"/>`,
+		`<Response xmlns="urn:p"><Other xmlns="" ID="1"/></Response>`,
+		`<Response xmlns="urn:p" xmlns:p="urn:p" p:ID="1"/>`,
+	} {
+		require.Empty(t, v.ValidateAll(strings.NewReader(doc)), "Should pass %s", doc)
+	}
+
+	errs := v.ValidateAll(strings.NewReader(`<samlp:Response xmlns:samlp="urn:p"><Assertion xmlns="urn:a" ID="1"/></samlp:Response>`))
+	require.Len(t, errs, 1, "Should report ID attributes on elements in a default namespace")
+	require.Equal(t, CheckDefaultScopeAttributes, errs[0].(XMLValidationError).Check, "Finding should be reported by the default scope attributes check")
+	require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Should be a warning by default")
+	namespaceError := XMLNamespaceError{}
+	require.True(t, errors.As(errs[0], &namespaceError), "Should return a namespace error")
+	require.Equal(t, `default namespace "urn:a" doesn't apply to attribute ID of element Assertion, which is in no namespace`, namespaceError.Error(), "Should describe the attribute")
+
+	v = New(WithDefaultScopeAttributesCheck(DefaultScopeAttributesConfig{Names: []string{"Destination"}}))
+	require.Len(t, v.ValidateAll(strings.NewReader(`<Response xmlns="urn:p" ID="1" Destination="x"/>`)), 1, "Should only report configured attributes")
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Response xmlns="urn:p" ID="1"/>`)), "Should be disabled unless configured")
+}
+
+func TestRoundtripNamespaces(t *testing.T) {
+	registerTestCheck(t, "test-mismatch", CategoryRoundtrip, SeverityError, func(d *document, token xml.Token) error {
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Mismatch" {
+			return XMLRoundtripError{Expected: start, Observed: start}
+		}
+		return nil
+	})
+	doc := `<Root xmlns="urn:default" xmlns:a="urn:a1"><a:Child xmlns:a="urn:a2" xmlns:b="urn:b">` +
+		`<Mismatch/></a:Child><Mismatch/></Root>`
+
+	errs := New().ValidateAll(strings.NewReader(doc))
+	require.Len(t, errs, 2)
+	require.Equal(t, map[string]string{"": "urn:default", "a": "urn:a2", "b": "urn:b"}, errs[0].(XMLValidationError).Namespaces,
+		"Inner declarations should shadow outer ones")
+	require.Equal(t, map[string]string{"": "urn:default", "a": "urn:a1"}, errs[1].(XMLValidationError).Namespaces,
+		"Declarations should go out of scope with their element")
+
+	registerTestCheck(t, "test-start", "test", SeverityError, onStartElements)
+	for _, err := range New().ValidateAll(strings.NewReader(doc)) {
+		if err.(XMLValidationError).Check == "test-start" {
+			require.Nil(t, err.(XMLValidationError).Namespaces, "Only round trip mismatches should capture namespaces")
+		}
+	}
+
+	data, err := json.Marshal(errs[1])
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"namespaces":{"":"urn:default","a":"urn:a1"}`)
+}
+
+func TestEquivalentTokens(t *testing.T) {
+	d := New().newDocumentBytes([]byte(`<Root xmlns="urn:default" xmlns:a="urn:a"><a:Child a:x="1" y="2"></a:Child></Root>`))
+	for i := 0; i < 2; i++ {
+		_, _, err := d.next()
+		require.NoError(t, err)
+	}
+	child := xml.StartElement{
+		Name: xml.Name{Space: "a", Local: "Child"},
+		Attr: []xml.Attr{{Name: xml.Name{Space: "a", Local: "x"}, Value: "1"}, {Name: xml.Name{Local: "y"}, Value: "2"}},
+	}
+	equivalent := []xml.Token{
+		xml.StartElement{
+			Name: xml.Name{Space: "b", Local: "Child"},
+			Attr: []xml.Attr{{Name: xml.Name{Space: "xmlns", Local: "b"}, Value: "urn:a"}, {Name: xml.Name{Space: "b", Local: "x"}, Value: "1"}, {Name: xml.Name{Local: "y"}, Value: "2"}},
+		},
+		xml.StartElement{
+			Name: xml.Name{Local: "Child"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: "urn:a"}, {Name: xml.Name{Space: "a", Local: "x"}, Value: "1"}, {Name: xml.Name{Local: "y"}, Value: "2"}},
+		},
+	}
+	for _, after := range equivalent {
+		require.True(t, d.equivalent(child, after), "Names resolving to the same URI should be equivalent: %v", after)
+	}
+	different := []xml.Token{
+		xml.StartElement{
+			Name: xml.Name{Local: "Child"},
+			Attr: []xml.Attr{{Name: xml.Name{Space: "a", Local: "x"}, Value: "1"}, {Name: xml.Name{Local: "y"}, Value: "2"}},
+		},
+		xml.StartElement{
+			Name: xml.Name{Space: "a", Local: "Child"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "x"}, Value: "1"}, {Name: xml.Name{Local: "y"}, Value: "2"}},
+		},
+		xml.StartElement{
+			Name: xml.Name{Space: "a", Local: "Child"},
+			Attr: []xml.Attr{{Name: xml.Name{Space: "a", Local: "x"}, Value: "1"}},
+		},
+		xml.EndElement{Name: xml.Name{Space: "a", Local: "Child"}},
+	}
+	for _, after := range different {
+		require.False(t, d.equivalent(child, after), "Names resolving differently shouldn't be equivalent: %v", after)
+	}
+	require.True(t, d.equivalent(xml.EndElement{Name: xml.Name{Local: "Root"}}, xml.EndElement{Name: xml.Name{Space: "urn:default", Local: "Root"}}),
+		"End elements should be compared by their resolved names")
+	require.False(t, d.equivalent(xml.CharData("x"), xml.CharData("x")), "Only names can be equivalent")
+}
+
+func TestResolveNamespaces(t *testing.T) {
+	doc := `<a:Root xmlns:a="urn:a" xmlns="urn:d" a:x="1" xml:lang="en" y="2"><Child a:z="3"/><a:xmlns/></a:Root>`
+	require.NoError(t, New().Validate(strings.NewReader(doc)), "Raw round trips shouldn't mutate the document")
+
+	errs := New(WithRoundtripCheck(RoundtripConfig{ResolveNamespaces: true})).ValidateAll(strings.NewReader(doc))
+	require.Len(t, errs, 2, "Should report the element whose resolved name is mutated, and its end")
+	var roundtripError XMLRoundtripError
+	require.True(t, errors.As(errs[0], &roundtripError))
+	require.Equal(t, xml.StartElement{Name: xml.Name{Space: "urn:a", Local: "xmlns"}, Attr: []xml.Attr{}}, roundtripError.Expected,
+		"Should report the resolved token")
+	require.Equal(t, xml.Name{Local: "xmlns"}, roundtripError.Observed.(xml.StartElement).Name,
+		"Should report the token as resolved again")
+	require.True(t, errors.As(errs[1], &roundtripError))
+	require.Equal(t, xml.EndElement{Name: xml.Name{Space: "urn:a", Local: "xmlns"}}, roundtripError.Expected)
+}
@@ -0,0 +1,138 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// Validator validates XML documents with a fixed set of options; it is
+// safe for concurrent use once created
+type Validator struct {
+	failOn       Severity
+	roundtrip    RoundtripConfig
+	checks       map[CheckID]CheckConfig
+	perCategory  bool
+	xmlBase      XMLBaseConfig
+	cdata        CDATAConfig
+	tokenKinds   TokenKindsConfig
+	allowedRoots []xml.Name
+	// defaultScopeAttributes configures CheckDefaultScopeAttributes
+	defaultScopeAttributes DefaultScopeAttributesConfig
+	metrics                Metrics
+	sinks                  []FindingSink
+	sampling               *SamplingConfig
+	maxDecompressed        int64
+	maxRatio               int64
+	autoDecompression      bool
+	maxBytes               int64
+	maxTokenBytes          int64
+	charset                string
+	maxErrors              int
+	snippetContext         int
+	redaction              bool
+	// emulation is the encoding/xml semantics assumed by Emulate, if any
+	emulation Semantics
+	// namespaceDeclarations configures CheckNamespaceDeclarations
+	namespaceDeclarations NamespaceDeclarationsConfig
+	children              ChildrenConfig
+	depth                 DepthConfig
+	attributes            AttributesConfig
+	attributeLength       AttributeLengthConfig
+	differential          DifferentialConfig
+	anomaly               AnomalyConfig
+	doctype               DoctypeConfig
+	entityExpansion       EntityExpansionConfig
+	procInsts             ProcInstsConfig
+	stats                 *statsCounter
+}
+
+// Option configures a Validator
+type Option func(*Validator)
+
+// New creates a Validator configured with the given options
+func New(opts ...Option) *Validator {
+	v := &Validator{
+		failOn:          SeverityError,
+		checks:          map[CheckID]CheckConfig{},
+		maxDecompressed: DefaultMaxDecompressedSize,
+		maxRatio:        DefaultMaxDecompressionRatio,
+		stats:           &statsCounter{stats: map[CheckID]CheckStats{}},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+var defaultValidator atomic.Value
+
+func init() {
+	defaultValidator.Store(New())
+}
+
+// Default returns the Validator used by the package-level functions, such
+// as Validate and ValidateAll; it is created with New unless replaced by
+// SetDefault
+func Default() *Validator {
+	return defaultValidator.Load().(*Validator)
+}
+
+// SetDefault replaces the Validator used by the package-level functions,
+// letting programs configure existing call sites, typically at init.
+// It is safe to call concurrently with validation; validations in progress
+// finish with the previous Validator.
+func SetDefault(v *Validator) {
+	defaultValidator.Store(v)
+}
+
+// WithFailOn sets the lowest severity that causes validation to fail;
+// findings below it are still reported by ValidateAll, but don't make
+// Validate return an error
+func WithFailOn(s Severity) Option {
+	return func(v *Validator) {
+		v.failOn = s
+	}
+}
+
+// WithOnePerCategory makes ValidateAll and Report return at most one finding
+// per check category, and stop reading the document as soon as every
+// enabled category has been reported; this gives a useful overview of
+// very noisy documents at close to the cost of Validate. Findings that
+// don't fail validation don't use up their category: a later finding
+// failing validation is still reported, so a category may have two. Validate
+// is unaffected.
+func WithOnePerCategory() Option {
+	return func(v *Validator) {
+		v.perCategory = true
+	}
+}
+
+// ErrTooManyErrors ends the findings of ValidateAll and Report when they
+// stopped reading a document after the maximum number of errors set with
+// WithMaxErrors; it fails validation regardless of the findings before it
+var ErrTooManyErrors = errors.New("too many errors, validation stopped")
+
+// WithMaxErrors makes ValidateAll and Report stop reading a document after
+// n findings, so crafted documents with countless bad tokens can't make
+// them consume unbounded memory and time; zero or less removes the limit.
+// Validate is unaffected, as it already stops at the first failing finding.
+func WithMaxErrors(n int) Option {
+	return func(v *Validator) {
+		v.maxErrors = n
+	}
+}
+
+// ValidateWithOptions is like Validate, but validates the document with a
+// Validator configured with the given options; callers validating many
+// documents with the same options should create a Validator with New
+func ValidateWithOptions(xmlReader io.Reader, opts ...Option) error {
+	return New(opts...).Validate(xmlReader)
+}
+
+// ValidateAllWithOptions is like ValidateAll, but validates the document
+// with a Validator configured with the given options
+func ValidateAllWithOptions(xmlReader io.Reader, opts ...Option) []error {
+	return New(opts...).ValidateAll(xmlReader)
+}
@@ -0,0 +1,112 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// qualifiedName renders a raw token name as it appears in the document
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// matchPath reports whether an element path matches a path pattern.
+//
+// Patterns are a small subset of XPath: steps are separated by "/", an
+// empty step ("//") matches any number of intermediate elements and "*"
+// matches any single element. Names are compared as written in the
+// document, including their namespace prefix, e.g. "/samlp:Response/saml:Assertion"
+// or "//ds:Signature". Patterns that don't start with "/" are relative and
+// behave as if they were prefixed with "//".
+func matchPath(pattern string, path []xml.Name) bool {
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "//" + pattern
+	}
+	return matchSteps(strings.Split(pattern[1:], "/"), path)
+}
+
+func matchSteps(steps []string, path []xml.Name) bool {
+	if len(steps) == 0 {
+		return len(path) == 0
+	}
+	if steps[0] == "" {
+		// descendant step; try skipping any number of elements
+		for i := 0; i <= len(path); i++ {
+			if matchSteps(steps[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if steps[0] != "*" && steps[0] != qualifiedName(path[0]) {
+		return false
+	}
+	return matchSteps(steps[1:], path[1:])
+}
+
+// inScope reports whether the element path, or any of its ancestors,
+// matches one of the patterns; an empty pattern list matches everything
+func inScope(patterns []string, path []xml.Name) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for i := len(path); i > 0; i-- {
+		for _, pattern := range patterns {
+			if matchPath(pattern, path[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pushIndex records the position of a start element among its siblings of
+// the same name
+func (d *document) pushIndex(name xml.Name) {
+	depth := len(d.indexes)
+	if depth == len(d.siblings) {
+		d.siblings = append(d.siblings, map[xml.Name]int{})
+	}
+	counts := d.siblings[depth]
+	counts[name]++
+	d.indexes = append(d.indexes, counts[name])
+}
+
+// popIndex forgets the children of the element being closed
+func (d *document) popIndex() {
+	depth := len(d.indexes)
+	if depth < len(d.siblings) {
+		for name := range d.siblings[depth] {
+			delete(d.siblings[depth], name)
+		}
+	}
+	d.indexes = d.indexes[:depth-1]
+}
+
+// elements returns the names of the open elements, resolved to their
+// namespace URI
+func (d *document) elements() []xml.Name {
+	return append([]xml.Name(nil), d.names...)
+}
+
+// xpath renders the path of the current element as an XPath expression
+// with the position of every element among its siblings of the same name,
+// e.g. "/samlp:Response[1]/saml:Assertion[1]/ds:Signature[1]"
+func (d *document) xpath() string {
+	path := &strings.Builder{}
+	for i, name := range d.path {
+		path.WriteString("/")
+		path.WriteString(qualifiedName(name))
+		path.WriteString("[")
+		path.WriteString(strconv.Itoa(d.indexes[i]))
+		path.WriteString("]")
+	}
+	return path.String()
+}
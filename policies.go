@@ -0,0 +1,81 @@
+package validator
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Error codes of findings reported by policy checks
+const (
+	// ErrCodeComment is used for comments in documents that aren't allowed
+	// any, see DisallowComments
+	ErrCodeComment ErrorCode = "comment"
+	// ErrCodeProcInst is used for processing instructions with targets
+	// that aren't allowed, see WithProcInstsCheck
+	ErrCodeProcInst ErrorCode = "procinst"
+	// ErrCodeCDATASection is used for CDATA sections in documents that
+	// aren't allowed any, see CDATAConfig
+	ErrCodeCDATASection ErrorCode = "cdata-section"
+)
+
+// DisallowComments enables CheckComments, failing documents holding any
+// comment. Comments are legitimate XML, but SAML implementations have
+// been tricked into truncating NameIDs and other values around comments,
+// so integrators often ban them outright; WithCheck limits the ban to some
+// elements, such as assertions, through Paths.
+func DisallowComments() Option {
+	return func(v *Validator) {
+		v.checks[CheckComments] = CheckConfig{}
+	}
+}
+
+// newCommentsCheck creates the per-document state of CheckComments
+func newCommentsCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		if _, ok := token.(xml.Comment); ok {
+			return XMLPolicyError{ErrCodeComment, d.location()}
+		}
+		return nil
+	}
+}
+
+// ProcInstsConfig configures CheckProcInsts
+type ProcInstsConfig struct {
+	CheckConfig
+	// AllowedTargets holds the targets of the processing instructions
+	// allowed in documents, matched case-sensitively; by default none are.
+	// The XML declaration is allowed regardless, since CheckXMLDeclaration
+	// already covers it.
+	AllowedTargets []string
+}
+
+// WithProcInstsCheck enables and configures CheckProcInsts
+func WithProcInstsCheck(cfg ProcInstsConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckProcInsts] = cfg.CheckConfig
+		v.procInsts = cfg
+	}
+}
+
+// newProcInstsCheck creates the per-document state of CheckProcInsts
+func newProcInstsCheck(v *Validator) tokenCheck {
+	allowed := map[string]bool{"xml": true}
+	for _, target := range v.procInsts.AllowedTargets {
+		allowed[target] = true
+	}
+	return func(d *document, token xml.Token) error {
+		if procInst, ok := token.(xml.ProcInst); ok && !allowed[procInst.Target] {
+			return XMLPolicyError{ErrCodeProcInst, fmt.Sprintf("target %q %s", procInst.Target, d.location())}
+		}
+		return nil
+	}
+}
+
+// location describes where the current token is, for the details of
+// policy errors
+func (d *document) location() string {
+	if len(d.path) == 0 {
+		return "outside the root element"
+	}
+	return fmt.Sprintf("in element %s", qualifiedName(d.path[len(d.path)-1]))
+}
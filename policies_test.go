@@ -0,0 +1,52 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisallowComments(t *testing.T) {
+	injected := `<Response><Assertion><NameID>admin@example.com<!---->.evil.com</NameID></Assertion></Response>`
+
+	require.NoError(t, New().Validate(strings.NewReader(injected)), "Should allow comments unless configured")
+
+	v := New(DisallowComments())
+	require.NoError(t, v.Validate(strings.NewReader(`<Root>text</Root>`)))
+	err := v.Validate(strings.NewReader(injected))
+	require.True(t, errors.Is(err, ErrCodeComment), "Should reject comments")
+	var policyError XMLPolicyError
+	require.True(t, errors.As(err, &policyError), "Should report a policy error")
+	require.Equal(t, XMLPolicyError{ErrCodeComment, "in element NameID"}, policyError)
+	require.Equal(t, "/Response[1]/Assertion[1]/NameID[1]", FindingOf(err).Path)
+	require.Contains(t, New(DisallowComments()).Validate(strings.NewReader(`<!-- c --><Root/>`)).Error(),
+		"comment not allowed: outside the root element")
+
+	v = New(WithCheck(CheckComments, CheckConfig{Paths: []string{"//Assertion"}}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Response><!-- c --><Assertion/></Response>`)), "Should honor paths")
+	require.True(t, errors.Is(v.Validate(strings.NewReader(injected)), ErrCodeComment), "Should honor paths")
+}
+
+func TestProcInsts(t *testing.T) {
+	stylesheet := `<?xml version="1.0"?><?xml-stylesheet href="style.xsl"?><Root><?app data?></Root>`
+
+	require.NoError(t, New().Validate(strings.NewReader(stylesheet)), "Should allow processing instructions unless configured")
+
+	v := New(WithProcInstsCheck(ProcInstsConfig{}))
+	require.NoError(t, v.Validate(strings.NewReader(`<?xml version="1.0"?><Root/>`)), "Should allow the XML declaration")
+	errs := v.ValidateAll(strings.NewReader(stylesheet))
+	require.Len(t, errs, 2, "Should reject every other processing instruction")
+	require.True(t, errors.Is(errs[0], ErrCodeProcInst))
+	require.Contains(t, errs[0].Error(), `procinst not allowed: target "xml-stylesheet" outside the root element`)
+	require.Contains(t, errs[1].Error(), `procinst not allowed: target "app" in element Root`)
+
+	v = New(WithProcInstsCheck(ProcInstsConfig{AllowedTargets: []string{"app"}}))
+	errs = v.ValidateAll(strings.NewReader(stylesheet))
+	require.Len(t, errs, 1, "Should allow the configured targets")
+	var policyError XMLPolicyError
+	require.True(t, errors.As(errs[0], &policyError))
+	require.Equal(t, XMLPolicyError{ErrCodeProcInst, `target "xml-stylesheet" outside the root element`}, policyError)
+	require.Error(t, v.Validate(strings.NewReader(`<Root><?App?></Root>`)), "Should match targets case-sensitively")
+}
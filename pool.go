@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sync"
+)
+
+// maxPooledBufferSize keeps the pool from holding on to the buffers of
+// unusually large documents
+const maxPooledBufferSize = 1 << 20
+
+// bufferPool holds the scratch buffers of finished validation runs, so busy
+// services don't allocate them anew for every document
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &bytes.Buffer{}
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// bufferReleased is called by putBuffer if set, so tests can check that
+// validation runs release their buffers; sync.Pool may drop what it's given
+var bufferReleased func(*bytes.Buffer)
+
+// putBuffer returns a buffer to the pool; it must no longer be used, nor
+// any slice of its contents
+func putBuffer(buffer *bytes.Buffer) {
+	if bufferReleased != nil {
+		bufferReleased(buffer)
+	}
+	if buffer.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buffer)
+	}
+}
+
+// tokenEncoder is an xml.Encoder writing to its own buffer
+type tokenEncoder struct {
+	*xml.Encoder
+	buffer *bytes.Buffer
+}
+
+// encoderPool holds tokenEncoders with no open elements and an empty
+// buffer, since allocating an xml.Encoder allocates its write buffer too
+var encoderPool = sync.Pool{
+	New: func() interface{} {
+		return newTokenEncoder()
+	},
+}
+
+func newTokenEncoder() *tokenEncoder {
+	buffer := &bytes.Buffer{}
+	return &tokenEncoder{Encoder: xml.NewEncoder(buffer), buffer: buffer}
+}
+
+func getEncoder() *tokenEncoder {
+	return encoderPool.Get().(*tokenEncoder)
+}
+
+// putEncoder closes the element left open by encoding a start element, if
+// any, and returns the encoder to the pool; encoders that failed are
+// dropped, since xml.Encoder errors are sticky
+func putEncoder(e *tokenEncoder, open *xml.StartElement) {
+	if open != nil {
+		if err := e.EncodeToken(open.End()); err != nil {
+			return
+		}
+	}
+	if err := e.Flush(); err != nil || e.buffer.Cap() > maxPooledBufferSize {
+		return
+	}
+	e.buffer.Reset()
+	encoderPool.Put(e)
+}
@@ -0,0 +1,75 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPooledEncoders(t *testing.T) {
+	doc := `<?xml version="1.0"?><x:Root xmlns:x="urn:x" xmlns="urn:default" x:attr="1"><Child xmlns:y="urn:y" y:attr="&quot;">text</Child>` +
+		`<!-- comment --><y:Other/><![CDATA[<data>]]><?target inst?><!DOCTYPE x></x:Root>`
+	tokens := []xml.Token{xml.ProcInst{Target: "x", Inst: []byte("?>")}}
+	for _, next := range []func(*xml.Decoder) (xml.Token, error){(*xml.Decoder).RawToken, (*xml.Decoder).Token} {
+		decoder := xml.NewDecoder(strings.NewReader(doc))
+		for {
+			token, err := next(decoder)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			tokens = append(tokens, xml.CopyToken(token))
+		}
+	}
+
+	// encode every token twice, so pooled encoders get reused
+	for _, token := range append(tokens, tokens...) {
+		fresh, freshErr := SerializeToken(token)
+		encoder := getEncoder()
+		encoded, start, err := encodeToken(token, encoder)
+		require.Equal(t, freshErr, err, "Pooled encoders should fail like fresh ones on %#v", token)
+		if err == nil {
+			require.Equal(t, string(fresh), string(encoded[start:]), "Pooled encoders should encode %#v like fresh ones", token)
+		}
+		var open *xml.StartElement
+		if start, ok := token.(xml.StartElement); ok {
+			open = &start
+		}
+		putEncoder(encoder, open)
+	}
+}
+
+func TestPooledBuffers(t *testing.T) {
+	var released []*bytes.Buffer
+	bufferReleased = func(buffer *bytes.Buffer) {
+		released = append(released, buffer)
+	}
+	defer func() {
+		bufferReleased = nil
+	}()
+	doc := `<Root xmlns:x="urn:x"><x:Child>text</x:Child></Root>`
+	v := New()
+	for name, validate := range map[string]func(r io.Reader) error{
+		"Stream": func(r io.Reader) error {
+			return v.Stream(r, &recordingHandler{})
+		},
+		"Tokens": func(r io.Reader) error {
+			_, err := v.Tokens(r)
+			return err
+		},
+		"Namespaces": func(r io.Reader) error {
+			_, err := v.Namespaces(r)
+			return err
+		},
+	} {
+		released = nil
+		require.NoError(t, validate(strings.NewReader(doc)))
+		require.Len(t, released, 1, "%s should return its buffer to the pool", name)
+		require.NotNil(t, released[0], "%s should return its buffer to the pool", name)
+	}
+}
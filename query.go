@@ -0,0 +1,100 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Findings is a list of findings that can be narrowed down with chained
+// filters, e.g.
+//
+//	report.Findings().In("//saml:Assertion").WithCode(ErrCodeColonInName)
+//
+// Filters return a new list, leaving the original untouched.
+type Findings []Finding
+
+// Findings describes the report's errors as findings
+func (report *ValidationReport) Findings() Findings {
+	findings := make(Findings, 0, len(report.Errors))
+	for _, err := range report.Errors {
+		findings = append(findings, FindingOf(err))
+	}
+	return findings
+}
+
+// filter returns the findings for which keep returns true
+func (findings Findings) filter(keep func(finding Finding) bool) Findings {
+	kept := Findings{}
+	for _, finding := range findings {
+		if keep(finding) {
+			kept = append(kept, finding)
+		}
+	}
+	return kept
+}
+
+// In returns the findings in the subtrees of elements matching the path
+// pattern, using the syntax of CheckConfig.Paths; since names are matched
+// with the prefixes written in the document, prefer InElement when the
+// document's author chooses its prefixes
+func (findings Findings) In(pattern string) Findings {
+	patterns := []string{pattern}
+	return findings.filter(func(finding Finding) bool {
+		return finding.Path != "" && inScope(patterns, parseXPath(finding.Path))
+	})
+}
+
+// InElement returns the findings in the subtrees of elements with the given
+// name, matched on their namespace URI and local name; like with
+// WithAllowedRoots, a name without a namespace matches elements in any
+// namespace
+func (findings Findings) InElement(name xml.Name) Findings {
+	return findings.filter(func(finding Finding) bool {
+		for _, element := range finding.Elements {
+			if element.Local == name.Local && (name.Space == "" || element.Space == name.Space) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// InNamespace returns the findings whose innermost element is in the
+// namespace with the given URI
+func (findings Findings) InNamespace(uri string) Findings {
+	return findings.filter(func(finding Finding) bool {
+		return len(finding.Elements) > 0 && finding.Elements[len(finding.Elements)-1].Space == uri
+	})
+}
+
+// WithCode returns the findings with the given error code
+func (findings Findings) WithCode(code ErrorCode) Findings {
+	return findings.filter(func(finding Finding) bool {
+		return finding.Code == code
+	})
+}
+
+// WithSeverity returns the findings with the given severity
+func (findings Findings) WithSeverity(severity Severity) Findings {
+	return findings.filter(func(finding Finding) bool {
+		return finding.Severity == severity
+	})
+}
+
+// parseXPath parses a path rendered by document.xpath back into the raw
+// names of its elements
+func parseXPath(path string) []xml.Name {
+	steps := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	names := make([]xml.Name, 0, len(steps))
+	for _, step := range steps {
+		if i := strings.LastIndex(step, "["); i >= 0 {
+			step = step[:i]
+		}
+		name := xml.Name{Local: step}
+		if i := strings.Index(step, ":"); i >= 0 {
+			name = xml.Name{Space: step[:i], Local: step[i+1:]}
+		}
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,45 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindingsQuery(t *testing.T) {
+	registerTestCheck(t, "test-start", "test", SeverityWarning, onStartElements)
+	doc := `<samlp:Response xmlns:samlp="urn:p" xmlns:saml="urn:a"><saml:Assertion><Subject/></saml:Assertion>` +
+		`<evil:Assertion xmlns:evil="urn:a"><evil:Subject/></evil:Assertion></samlp:Response>`
+	report, err := New().Report(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	messages := func(findings Findings) []string {
+		messages := []string{}
+		for _, finding := range findings {
+			messages = append(messages, finding.Message)
+		}
+		return messages
+	}
+	findings := report.Findings()
+	require.Len(t, findings, 5)
+	require.Equal(t, []string{"found Assertion", "found Subject"}, messages(findings.In("//saml:Assertion")),
+		"Patterns should match prefixed names")
+	require.Equal(t, []string{"found Subject"}, messages(findings.In("/samlp:Response/saml:Assertion/Subject")))
+	require.Equal(t, []string{"found Assertion", "found Subject", "found Assertion", "found Subject"},
+		messages(findings.InElement(xml.Name{Space: "urn:a", Local: "Assertion"})),
+		"Names should be matched regardless of their prefix")
+	require.Equal(t, []string{"found Assertion", "found Assertion", "found Subject"}, messages(findings.InNamespace("urn:a")),
+		"Only findings whose innermost element is in the namespace should match")
+	require.Len(t, findings.WithCode("test-start"), 5)
+	require.Empty(t, findings.WithCode(ErrCodeColonInName))
+	require.Len(t, findings.WithSeverity(SeverityWarning).In("//Subject"), 1, "Filters should chain")
+	require.Empty(t, findings.WithSeverity(SeverityError))
+	require.Len(t, findings, 5, "Filters shouldn't modify the original findings")
+}
+
+func TestParseXPath(t *testing.T) {
+	require.Equal(t, []xml.Name{{Space: "samlp", Local: "Response"}, {Local: "Assertion"}},
+		parseXPath("/samlp:Response[1]/Assertion[12]"))
+}
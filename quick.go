@@ -0,0 +1,204 @@
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Tier identifies the validation tier that produced a verdict
+type Tier int
+
+const (
+	// TierQuick is the byte-level prescan run by QuickValidate
+	TierQuick Tier = iota + 1
+	// TierFull is regular validation, as run by Validate
+	TierFull
+)
+
+func (t Tier) String() string {
+	switch t {
+	case TierQuick:
+		return "quick"
+	case TierFull:
+		return "full"
+	}
+	return "unknown"
+}
+
+// QuickValidate runs a cheap byte-level prescan of the document instead of
+// tokenizing and re-encoding it, returning an error reported by CheckPrescan
+// if it contains any construct that round trip issues have historically
+// been found in: directives, misplaced processing instructions, "]]>" in
+// character data, and names with empty or multiple namespace prefixes.
+//
+// The prescan isn't a parser: passing it doesn't mean the document is
+// well-formed, and failing it doesn't mean the document is malicious.
+// Use ValidateTiered to only fully validate documents failing it.
+func (v *Validator) QuickValidate(xmlReader io.Reader) error {
+	xmlBytes, err := readAll(v.limitBytes(xmlReader))
+	if err != nil {
+		return err
+	}
+	return prescan(xmlBytes)
+}
+
+// ValidateTiered runs QuickValidate on the document, and escalates to full
+// validation only if the prescan finds suspicious constructs; the tier
+// producing the verdict is returned along with it. Validators enabling
+// checks that can fail validation on constructs the prescan doesn't look
+// for, such as policy and limit checks, always escalate, so the verdict is
+// the one Validate would return.
+func (v *Validator) ValidateTiered(xmlReader io.Reader) (Tier, error) {
+	xmlBytes, err := readAll(v.limitBytes(xmlReader))
+	if err != nil {
+		return TierQuick, err
+	}
+	if v.prescanSuffices() && prescan(xmlBytes) == nil {
+		return TierQuick, nil
+	}
+	return TierFull, v.Validate(bytes.NewReader(xmlBytes))
+}
+
+// prescanChecks are the checks whose failing findings are only reported
+// on constructs the prescan flags as suspicious
+var prescanChecks = map[CheckID]bool{
+	CheckRoundtrip:      true,
+	CheckKnownAttacks:   true,
+	CheckXMLDeclaration: true,
+}
+
+// prescanSuffices reports whether passing the prescan means passing
+// validation with the Validator, which is the case if every enabled check
+// that can fail validation is one of prescanChecks, and tokens aren't
+// limited in size
+func (v *Validator) prescanSuffices() bool {
+	if v.maxTokenBytes > 0 {
+		return false
+	}
+	for _, c := range v.activeChecks() {
+		if !prescanChecks[c.id] && c.severity >= v.failOn {
+			return false
+		}
+	}
+	return true
+}
+
+// prescan looks for suspicious constructs in the document's bytes
+func prescan(xmlBytes []byte) error {
+	suspicious := func(start, end int, reason string) error {
+		line, column := position(xmlBytes, int64(start))
+		endLine, endColumn := position(xmlBytes, int64(end))
+		return XMLValidationError{
+			Start:     int64(start),
+			End:       int64(end),
+			Line:      line,
+			Column:    column,
+			EndLine:   endLine,
+			EndColumn: endColumn,
+			Severity:  SeverityError,
+			Check:     CheckPrescan,
+			err:       errors.New(reason),
+		}
+	}
+	for i := 0; i < len(xmlBytes); {
+		rest := xmlBytes[i:]
+		var end int
+		switch {
+		case bytes.HasPrefix(rest, []byte("]]>")):
+			return suspicious(i, i+3, "\"]]>\" in character data")
+		case rest[0] != '<':
+			i++
+			continue
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end = skipPast(rest, 4, "-->")
+			if end < 0 {
+				return suspicious(i, len(xmlBytes), "unterminated comment")
+			}
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			end = skipPast(rest, 9, "]]>")
+			if end < 0 {
+				return suspicious(i, len(xmlBytes), "unterminated CDATA section")
+			}
+		case bytes.HasPrefix(rest, []byte("<!")):
+			return suspicious(i, i+2, "directive")
+		case bytes.HasPrefix(rest, []byte("<?")):
+			end = skipPast(rest, 2, "?>")
+			declaration := i == 0 || (i == 3 && bytes.HasPrefix(xmlBytes, utf8BOM))
+			if end < 0 || !declaration || !isXMLDeclaration(rest) {
+				return suspicious(i, i+2, "processing instruction")
+			}
+		default:
+			var reason string
+			end, reason = scanTag(rest, suspiciousName)
+			if reason != "" {
+				return suspicious(i, i+end, reason)
+			}
+		}
+		i += end
+	}
+	return nil
+}
+
+// isXMLDeclaration reports whether markup starts with an XML declaration
+func isXMLDeclaration(markup []byte) bool {
+	return len(markup) > 5 && bytes.HasPrefix(markup, []byte("<?xml")) &&
+		bytes.IndexByte([]byte(" \t\r\n"), markup[5]) >= 0
+}
+
+// skipPast returns the offset just past the first occurrence of delim in
+// markup after its opening delimiter of length open, or -1 if there is none
+func skipPast(markup []byte, open int, delim string) int {
+	end := bytes.Index(markup[open:], []byte(delim))
+	if end < 0 {
+		return -1
+	}
+	return open + end + len(delim)
+}
+
+// suspiciousName returns the reason a name found by the prescan is
+// suspicious, if any
+func suspiciousName(name []byte) string {
+	colons := bytes.Count(name, []byte(":"))
+	if colons > 1 || (colons == 1 && (name[0] == ':' || name[len(name)-1] == ':')) {
+		return "name with empty or multiple namespace prefixes"
+	}
+	return ""
+}
+
+// scanTag returns the length of the start or end tag at the start of markup,
+// passing the element and attribute names it contains to checkName, along
+// with the reason the tag is suspicious, if any
+func scanTag(markup []byte, checkName func(name []byte) string) (int, string) {
+	nameStart := -1
+	for i := 1; i < len(markup); i++ {
+		c := markup[i]
+		isNameByte := c != '>' && c != '/' && c != '=' && c != '"' && c != '\'' && c != '<' &&
+			c != ' ' && c != '\t' && c != '\r' && c != '\n'
+		if isNameByte {
+			if nameStart < 0 {
+				nameStart = i
+			}
+			continue
+		}
+		if nameStart >= 0 {
+			if reason := checkName(markup[nameStart:i]); reason != "" {
+				return i, reason
+			}
+			nameStart = -1
+		}
+		switch c {
+		case '>':
+			return i + 1, ""
+		case '<':
+			return i, "unterminated tag"
+		case '"', '\'':
+			end := bytes.IndexByte(markup[i+1:], c)
+			if end < 0 {
+				return len(markup), "unterminated attribute value"
+			}
+			i += end + 1
+		}
+	}
+	return len(markup), "unterminated tag"
+}
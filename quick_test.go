@@ -0,0 +1,80 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickValidate(t *testing.T) {
+	v := New()
+	for _, doc := range []string{
+		`<Root/>`,
+		`<?xml version="1.0" encoding="UTF-8"?><Root/>`,
+		"\xef\xbb\xbf<?xml version=\"1.0\"?><Root/>",
+		`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_1"><saml:Issuer>a &lt; b</saml:Issuer></saml:Assertion>`,
+		`<Root attr="a > b ]]> c::d"><!-- <!DOCTYPE x> --><![CDATA[<x::y>]]></Root>`,
+	} {
+		require.NoError(t, v.QuickValidate(strings.NewReader(doc)), "Should pass on unsuspicious documents: %s", doc)
+	}
+
+	for doc, offset := range map[string]int64{
+		`<Root>]]></Root>`:                       6,
+		`<Root><!DOCTYPE x></Root>`:              6,
+		`<Root><?xml version="1.0"?></Root>`:     6,
+		`<Root><?target?></Root>`:                6,
+		`<Root><x::Element/></Root>`:             6,
+		`<Root><:Element/></Root>`:               6,
+		`<Root><Element :attr="x"/></Root>`:      6,
+		`<Root><Element x:="x"/></Root>`:         6,
+		`<Root></x:></Root>`:                     6,
+		`<Root><!-- unterminated</Root>`:         6,
+		`<Root><Element attr="unterminated/>`:    6,
+		`<Root><![CDATA[ unterminated ]></Root>`: 6,
+	} {
+		err := v.QuickValidate(strings.NewReader(doc))
+		require.Error(t, err, "Should error on suspicious documents: %s", doc)
+		require.Equal(t, CheckPrescan, err.(XMLValidationError).Check, "Should report suspicious constructs with CheckPrescan")
+		require.Equal(t, offset, err.(XMLValidationError).Start, "Should report the offset of suspicious constructs: %s", doc)
+	}
+}
+
+func TestValidateTiered(t *testing.T) {
+	v := New()
+
+	tier, err := v.ValidateTiered(strings.NewReader(`<Root><Element attr="value"/></Root>`))
+	require.NoError(t, err, "Should pass on valid documents")
+	require.Equal(t, TierQuick, tier, "Should accept unsuspicious documents in the quick tier")
+
+	tier, err = v.ValidateTiered(strings.NewReader(`<Root><![CDATA[x]]><?target?></Root>`))
+	require.NoError(t, err, "Should pass on suspicious, but valid documents")
+	require.Equal(t, TierFull, tier, "Should escalate suspicious documents to full validation")
+
+	tier, err = v.ValidateTiered(strings.NewReader(`<Root>]]></Root>`))
+	require.Error(t, err, "Should error on invalid documents")
+	require.Equal(t, TierFull, tier, "Should reject documents in the full tier")
+	require.IsType(t, &xml.SyntaxError{}, err, "Should return the full validation error")
+}
+
+func TestValidateTieredPolicies(t *testing.T) {
+	for doc, v := range map[string]*Validator{
+		`<Root><a><b/></a><!-- c --></Root>`:      New(DisallowComments(), WithAllowedRoots(xml.Name{Local: "Root"}), WithDepthCheck(DepthConfig{Max: 2})),
+		`<Root><a><b/></a></Root>`:                New(WithDepthCheck(DepthConfig{Max: 2})),
+		`<Root xmlns="a" xmlns="b"/>`:             New(WithDuplicateDeclarationsCheck(DuplicateDeclarationsConfig{})),
+		`<Root a="1" a="2"/>`:                     New(WithDuplicateAttributesCheck(DuplicateAttributesConfig{})),
+		`<Root>text</Root>`:                       New(WithMaxTokenBytes(4)),
+		`<Root xmlns:a="urn:a" xmlns:b="urn:A"/>`: New(WithCheck(CheckNamespaceURI, CheckConfig{Severity: SeverityError})),
+	} {
+		require.Error(t, v.Validate(strings.NewReader(doc)), "Should fail validation: %s", doc)
+		tier, err := v.ValidateTiered(strings.NewReader(doc))
+		require.Error(t, err, "Should reach the verdict of Validate: %s", doc)
+		require.Equal(t, TierFull, tier, "Should escalate when checks aren't covered by the prescan: %s", doc)
+	}
+
+	v := New(WithCheck(CheckNamespaceURI, CheckConfig{Severity: SeverityWarning}))
+	tier, err := v.ValidateTiered(strings.NewReader(`<Root xmlns:a="urn:a" xmlns:b="urn:A"/>`))
+	require.NoError(t, err, "Checks that can't fail validation shouldn't matter")
+	require.Equal(t, TierQuick, tier, "Checks that can't fail validation shouldn't prevent the quick tier")
+}
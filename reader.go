@@ -0,0 +1,174 @@
+package validator
+
+import (
+	"errors"
+	"io"
+)
+
+var errReaderClosed = errors.New("validating reader closed")
+
+// validatingReader passes a document through to its consumer while a
+// separate goroutine validates it; every chunk read from the underlying
+// reader is handed over to the validator, and only returned to the
+// consumer once the validator has checked every token it completes
+type validatingReader struct {
+	r io.Reader
+	// requests is signalled by the validator when it needs more input,
+	// and chunks carries that input; closing chunks signals EOF
+	requests chan struct{}
+	chunks   chan []byte
+	// done is closed when validation finishes, after setting err
+	done chan struct{}
+	err  error
+	// abort is closed when the consumer gives up on the document
+	abort chan struct{}
+	// waiting is set if the validator is waiting for a chunk
+	waiting bool
+	// final is the error returned by every Read after validation finished
+	final error
+}
+
+// NewValidatingReader returns a reader passing the document read from r
+// through, while validating it in a single pass. Validation keeps pace with
+// the consumer: Read returns the first finding that fails validation as
+// soon as the offending token is complete, and returns it instead of io.EOF
+// at the end of the document, so a consumer reading to EOF can never
+// succeed on an invalid document. Consumers that stop reading early must
+// call Close to release the validating goroutine.
+func (v *Validator) NewValidatingReader(r io.Reader) io.ReadCloser {
+	vr := &validatingReader{
+		r:        r,
+		requests: make(chan struct{}),
+		chunks:   make(chan []byte),
+		done:     make(chan struct{}),
+		abort:    make(chan struct{}),
+	}
+	go func() {
+		defer close(vr.done)
+		vr.err = v.Validate(&feeder{vr: vr})
+	}()
+	return vr
+}
+
+// NewValidatingReader is like Validator.NewValidatingReader, using Default:
+// reads fail on findings that fail Validate, such as roundtrip errors, and
+// pass everything else through.
+// Reading the returned reader to EOF replaces buffering a document to call
+// Validate before decoding it.
+func NewValidatingReader(r io.Reader) io.ReadCloser {
+	return Default().NewValidatingReader(r)
+}
+
+func (vr *validatingReader) Read(p []byte) (int, error) {
+	if vr.final != nil {
+		return 0, vr.final
+	}
+	n, err := vr.r.Read(p)
+	if n > 0 {
+		chunk := make([]byte, n)
+		copy(chunk, p[:n])
+		if ferr := vr.feed(chunk); ferr != nil {
+			vr.final = ferr
+			return 0, ferr
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		if ferr := vr.finish(); ferr != nil {
+			vr.final = ferr
+			return 0, ferr
+		}
+		vr.final = err
+	} else if err != nil {
+		vr.Close()
+		err = IOError{err}
+		vr.final = err
+	}
+	return n, err
+}
+
+// feed hands a chunk to the validator and waits until it has processed
+// every token the chunk completes
+func (vr *validatingReader) feed(chunk []byte) error {
+	if !vr.waiting {
+		select {
+		case <-vr.requests:
+		case <-vr.done:
+			return vr.result()
+		}
+	}
+	vr.chunks <- chunk
+	vr.waiting = false
+	select {
+	case <-vr.requests:
+		vr.waiting = true
+		return nil
+	case <-vr.done:
+		return vr.result()
+	}
+}
+
+// finish signals the end of the document and waits for the verdict
+func (vr *validatingReader) finish() error {
+	if !vr.waiting {
+		select {
+		case <-vr.requests:
+		case <-vr.done:
+			return vr.result()
+		}
+	}
+	close(vr.chunks)
+	vr.waiting = false
+	<-vr.done
+	return vr.result()
+}
+
+// result returns the validation error once validation finished
+func (vr *validatingReader) result() error {
+	if errors.Is(vr.err, errReaderClosed) {
+		return nil
+	}
+	return vr.err
+}
+
+// Close stops validation and waits for the validating goroutine to exit;
+// it doesn't close the underlying reader
+func (vr *validatingReader) Close() error {
+	select {
+	case <-vr.abort:
+	default:
+		close(vr.abort)
+	}
+	<-vr.done
+	if vr.final == nil {
+		vr.final = errReaderClosed
+	}
+	return nil
+}
+
+// feeder is the reader consumed by the validating goroutine
+type feeder struct {
+	vr     *validatingReader
+	buffer []byte
+}
+
+func (f *feeder) Read(p []byte) (int, error) {
+	if len(f.buffer) == 0 {
+		select {
+		case f.vr.requests <- struct{}{}:
+		case <-f.vr.abort:
+			return 0, errReaderClosed
+		}
+		select {
+		case chunk, ok := <-f.vr.chunks:
+			if !ok {
+				return 0, io.EOF
+			}
+			f.buffer = chunk
+		case <-f.vr.abort:
+			return 0, errReaderClosed
+		}
+	}
+	n := copy(p, f.buffer)
+	f.buffer = f.buffer[n:]
+	return n, nil
+}
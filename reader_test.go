@@ -3,7 +3,13 @@ package validator
 import (
 	"bytes"
 	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/xml"
+	"errors"
 	"io"
+	"io/ioutil"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,3 +47,152 @@ func TestValidateZippedReader(t *testing.T) {
 	err = Validate(zipped)
 	assert.Error(t, err, "Should error on an invalid XML document")
 }
+
+// chunkedReader returns its chunks one Read at a time
+type chunkedReader struct {
+	chunks []string
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks[0] = r.chunks[0][n:]
+	if r.chunks[0] == "" {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+func TestValidatingReader(t *testing.T) {
+	doc := `<Root><Child attr="value">text</Child></Root>`
+	r := New().NewValidatingReader(&chunkedReader{[]string{`<Root><Chi`, `ld attr="value">te`, `xt</Child></Root>`}})
+	passed, err := ioutil.ReadAll(r)
+	require.NoError(t, err, "Should pass on valid XML documents")
+	require.Equal(t, doc, string(passed), "Should pass the document through unchanged")
+
+	r = New().NewValidatingReader(&chunkedReader{[]string{`<Root>`, `]]>`, `</Root>`}})
+	buffer := make([]byte, 64)
+	n, err := r.Read(buffer)
+	require.NoError(t, err, "Should pass through valid chunks")
+	require.Equal(t, `<Root>`, string(buffer[:n]), "Should pass through valid chunks")
+	_, err = r.Read(buffer)
+	require.Error(t, err, "Should error as soon as the invalid chunk is read")
+	require.IsType(t, &xml.SyntaxError{}, err, "Error should be an &xml.SyntaxError")
+	_, err = r.Read(buffer)
+	require.Error(t, err, "Should keep returning the error")
+
+	registerTestCheck(t, "test-forbidden", "test", SeverityError, func(d *document, token xml.Token) error {
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Forbidden" {
+			return errors.New("forbidden element")
+		}
+		return nil
+	})
+	r = New().NewValidatingReader(&chunkedReader{[]string{`<Root>`, `<Forbidden>`, `</Forbidden></Root>`}})
+	passed, err = ioutil.ReadAll(r)
+	require.Error(t, err, "Should error on findings failing validation")
+	require.Equal(t, CheckID("test-forbidden"), err.(XMLValidationError).Check, "Should return the failing finding")
+	require.Equal(t, `<Root>`, string(passed), "Shouldn't pass through the chunk completing the failing token")
+
+	r = New().NewValidatingReader(&chunkedReader{[]string{`<Root>`, `<Child>`}})
+	_, err = r.Read(buffer)
+	require.NoError(t, err, "Should pass through valid chunks")
+	require.NoError(t, r.Close(), "Should close without reading the rest of the document")
+	_, err = r.Read(buffer)
+	require.Error(t, err, "Should error after being closed")
+
+	readErr := errors.New("connection reset")
+	_, err = ioutil.ReadAll(New().NewValidatingReader(&failingReader{readErr}))
+	require.True(t, errors.Is(err, readErr), "Read errors should be returned")
+}
+
+func TestNewValidatingReader(t *testing.T) {
+	var root struct {
+		Child string `xml:"Child"`
+	}
+	r := NewValidatingReader(strings.NewReader(`<Root><Child>text</Child></Root>`))
+	require.NoError(t, xml.NewDecoder(r).Decode(&root), "Should pass valid documents through to the decoder")
+	require.Equal(t, "text", root.Child)
+	require.NoError(t, r.Close())
+
+	r = NewValidatingReader(strings.NewReader(`<Root><Child>text</Child><x::Child>injected</x::Child></Root>`))
+	_, err := ioutil.ReadAll(r)
+	require.Equal(t, Validate(strings.NewReader(`<Root><Child>text</Child><x::Child>injected</x::Child></Root>`)), err, "Should fail like Validate")
+}
+
+func TestValidateCompressed(t *testing.T) {
+	compress := func(encoding, input string) io.Reader {
+		var compressed bytes.Buffer
+		var w io.WriteCloser
+		switch encoding {
+		case "gzip":
+			w = gzip.NewWriter(&compressed)
+		case "deflate":
+			w = zlib.NewWriter(&compressed)
+		default:
+			fw, err := flate.NewWriter(&compressed, flate.DefaultCompression)
+			require.NoError(t, err)
+			w = fw
+		}
+		_, err := w.Write([]byte(input))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return &compressed
+	}
+	for _, encoding := range []string{"gzip", "deflate", "raw-deflate", "GZIP"} {
+		require.NoError(t, New().ValidateCompressed(compress(strings.ToLower(encoding), `<foo></foo>`), encoding), "Should pass on valid %s documents", encoding)
+		require.Error(t, New().ValidateCompressed(compress(strings.ToLower(encoding), `<Root>]]></Root>`), encoding), "Should error on invalid %s documents", encoding)
+	}
+	require.NoError(t, New().ValidateCompressed(strings.NewReader(`<foo></foo>`), ""), "Should default to identity")
+	require.Error(t, New().ValidateCompressed(strings.NewReader(`<foo></foo>`), "gzip"), "Should error on corrupt input")
+	require.Error(t, New().ValidateCompressed(strings.NewReader(`<foo></foo>`), "compress"), "Should error on unsupported encodings")
+
+	RegisterDecompressor("test", func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil })
+	defer func() {
+		decompressorsMu.Lock()
+		delete(decompressors, "test")
+		decompressorsMu.Unlock()
+	}()
+	require.NoError(t, New().ValidateCompressed(compress("raw-deflate", `<foo></foo>`), "Test"), "Should use registered decompressors")
+
+	bomb := `<foo>` + strings.Repeat(`<bar/>`, 1000) + `</foo>`
+	v := New(WithMaxDecompressedSize(1024))
+	err := v.ValidateCompressed(compress("gzip", bomb), "gzip")
+	require.True(t, errors.Is(err, ErrDecompressedTooLarge), "Should stop decompressing past the limit")
+	require.NoError(t, v.ValidateCompressed(compress("gzip", `<foo>`+strings.Repeat(`<bar/>`, 100)+`</foo>`), "gzip"), "Should pass documents within the limit")
+	require.NoError(t, New(WithMaxDecompressedSize(0)).ValidateCompressed(compress("gzip", bomb), "gzip"), "Should disable the limit")
+	err = New().ValidateCompressed(strings.NewReader(`<foo></foo>`), "compress")
+	require.True(t, errors.Is(err, ErrUnsupportedEncoding), "Should report unsupported encodings")
+}
+
+func TestAutoDecompression(t *testing.T) {
+	compress := func(w io.WriteCloser, input string) {
+		_, err := w.Write([]byte(input))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+	var gzipped, zlibbed bytes.Buffer
+	compress(gzip.NewWriter(&gzipped), "<Root>\n<!DOCTYPE x SYSTEM \"http://example.com/x.dtd\"></Root>")
+	compress(zlib.NewWriter(&zlibbed), `<Root/>`)
+
+	v := New(WithAutoDecompression())
+	require.NoError(t, v.Validate(bytes.NewReader(zlibbed.Bytes())), "Should decompress zlib documents")
+	require.NoError(t, v.Validate(strings.NewReader(`<Root/>`)), "Should pass uncompressed documents through")
+	require.NoError(t, v.Validate(strings.NewReader(``)), "Should pass short documents through")
+	for _, errs := range [][]error{v.ValidateAll(bytes.NewReader(gzipped.Bytes())), v.ValidateAllBytes(gzipped.Bytes())} {
+		require.Len(t, errs, 1, "Should validate decompressed gzip documents")
+		require.Equal(t, int64(2), errs[0].(XMLValidationError).Line, "Should locate findings in the decompressed document")
+	}
+	require.Error(t, New().Validate(bytes.NewReader(zlibbed.Bytes())), "Should be disabled unless configured")
+	err := v.Validate(bytes.NewReader([]byte{0x28, 0xb5, 0x2f, 0xfd, 0}))
+	require.True(t, errors.Is(err, ErrUnsupportedEncoding), "Should detect zstd documents")
+
+	var bomb bytes.Buffer
+	compress(gzip.NewWriter(&bomb), `<Root>`+strings.Repeat(" ", 2<<20)+`</Root>`)
+	err = v.Validate(bytes.NewReader(bomb.Bytes()))
+	require.True(t, errors.Is(err, ErrDecompressionRatio), "Should stop decompressing past the ratio limit")
+	require.NoError(t, New(WithAutoDecompression(), WithMaxDecompressionRatio(0)).Validate(bytes.NewReader(bomb.Bytes())), "Should disable the ratio limit")
+	err = New().ValidateCompressed(bytes.NewReader(bomb.Bytes()), "gzip")
+	require.True(t, errors.Is(err, ErrDecompressionRatio), "Should enforce the ratio limit on every compressed document")
+}
@@ -0,0 +1,86 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+// redacted replaces values left out of findings by WithRedaction
+const redacted = "[redacted]"
+
+// WithRedaction keeps attribute values and character data out of findings,
+// so findings on documents holding credentials or personal data can be
+// logged safely: round trip mismatches and parser differentials carry
+// tokens with their attribute values, character data, comments and
+// instructions replaced by "[redacted]", messages leave out the values they
+// would quote, and snippets aren't captured. Names, structure and positions
+// are kept, as are namespace URIs and the external declarations reported
+// by CheckKnownAttacks, which describe structure rather than data.
+func WithRedaction() Option {
+	return func(v *Validator) {
+		v.redaction = true
+	}
+}
+
+// redact returns value, or a placeholder if redaction is enabled
+func (d *document) redact(value string) string {
+	if d.v.redaction {
+		return redacted
+	}
+	return value
+}
+
+// redactError redacts the tokens of round trip mismatches and parser
+// differentials returned by checks
+func redactError(err error) error {
+	switch e := err.(type) {
+	case XMLRoundtripError:
+		return e.redacted()
+	case XMLDifferentialError:
+		e.Token = redactToken(e.Token)
+		e.Other = redactToken(e.Other)
+		// parsers quote the content they fail on
+		if e.Err != nil {
+			e.Err = errors.New(redacted)
+		}
+		return e
+	}
+	return err
+}
+
+// redacted returns a copy of the mismatch with the values of its tokens
+// replaced
+func (err XMLRoundtripError) redacted() XMLRoundtripError {
+	err.Expected = redactToken(err.Expected)
+	err.Observed = redactToken(err.Observed)
+	if len(err.Overflow) > 0 {
+		err.Overflow = []byte(redacted)
+	}
+	return err
+}
+
+// redactToken returns a copy of the token with its attribute values and
+// content replaced, keeping namespace declarations
+func redactToken(token xml.Token) xml.Token {
+	switch t := token.(type) {
+	case xml.StartElement:
+		attrs := make([]xml.Attr, len(t.Attr))
+		for i, attr := range t.Attr {
+			if _, ok := declaredPrefix(attr); !ok {
+				attr.Value = redacted
+			}
+			attrs[i] = attr
+		}
+		t.Attr = attrs
+		return t
+	case xml.CharData:
+		return xml.CharData(redacted)
+	case xml.Comment:
+		return xml.Comment(redacted)
+	case xml.ProcInst:
+		return xml.ProcInst{Target: t.Target, Inst: []byte(redacted)}
+	case xml.Directive:
+		return xml.Directive(redacted)
+	}
+	return token
+}
@@ -0,0 +1,59 @@
+package validator
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedaction(t *testing.T) {
+	registerTestCheck(t, "test-mismatch", CategoryRoundtrip, SeverityError, func(d *document, token xml.Token) error {
+		if _, ok := token.(xml.StartElement); ok {
+			return XMLRoundtripError{Expected: token, Observed: token, Overflow: []byte("secret")}
+		}
+		return nil
+	})
+	doc := `<Root xmlns:a="urn:a" password="hunter2" xml:base="http://secret.example.com/">` +
+		`<Child ID="alice@example.com"/><Child ID="alice@example.com"/></Root>`
+	v := New(WithRedaction(), WithSnippets(10), WithXMLBaseCheck(XMLBaseConfig{FlagAll: true}))
+
+	errs := v.ValidateAll(strings.NewReader(doc))
+	require.NotEmpty(t, errs)
+	checks := map[CheckID]bool{}
+	for _, err := range errs {
+		validationError := err.(XMLValidationError)
+		checks[validationError.Check] = true
+		data, marshalErr := json.Marshal(err)
+		require.NoError(t, marshalErr)
+		for _, secret := range []string{"hunter2", "secret", "alice"} {
+			require.NotContains(t, err.Error(), secret, "Messages should be redacted")
+			require.NotContains(t, string(data), secret, "JSON should be redacted")
+		}
+		require.Empty(t, validationError.Snippet, "Snippets shouldn't be captured")
+	}
+	require.True(t, checks[CheckXMLBase] && checks[CheckKnownAttacks] && checks["test-mismatch"], "Checks quoting values should fire: %v", errs)
+
+	roundtripError := XMLRoundtripError{}
+	for _, err := range errs {
+		if errors.As(err, &roundtripError) {
+			break
+		}
+	}
+	start := roundtripError.Expected.(xml.StartElement)
+	require.Equal(t, "Root", start.Name.Local, "Names should be kept")
+	require.Equal(t, "urn:a", start.Attr[0].Value, "Namespace declarations should be kept")
+	require.Equal(t, redacted, start.Attr[1].Value, "Attribute values should be redacted")
+
+	require.Equal(t, xml.CharData(redacted), redactToken(xml.CharData("secret")))
+	require.Equal(t, xml.ProcInst{Target: "x", Inst: []byte(redacted)}, redactToken(xml.ProcInst{Target: "x", Inst: []byte("secret")}))
+
+	for _, err := range New(WithSnippets(10)).ValidateAll(strings.NewReader(doc)) {
+		if err.(XMLValidationError).Check == CheckKnownAttacks {
+			require.Contains(t, err.Error(), "alice", "Values should only be redacted if enabled")
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package validator
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ValidationReport describes the outcome of validating a single document
+type ValidationReport struct {
+	// Errors holds every finding in document order, including syntax
+	// errors and ErrTooManyErrors if they stopped validation early
+	Errors []error
+	// Failed is set if any of the findings is severe enough to fail validation
+	Failed bool
+	// Stats counts how many times each check fired on the document
+	Stats map[CheckID]CheckStats
+	// Size is the number of bytes read from the document, and Duration
+	// the time it took to validate them
+	Size     int64
+	Duration time.Duration
+	// Tokens counts the tokens of each kind read from the document
+	Tokens map[TokenKind]int
+	// Elements and Attributes count the start elements and their
+	// attributes, namespace declarations included; MaxDepth is the
+	// deepest nesting of elements, and MaxAttributes the most attributes
+	// on a single element. Like Tokens, they only cover the part of the
+	// document read before validation stopped.
+	Elements, Attributes    int
+	MaxDepth, MaxAttributes int
+}
+
+// count records the shape of a token at the given depth, that of the
+// elements it is in, or the one it opens
+func (report *ValidationReport) count(token xml.Token, depth int) {
+	report.Tokens[tokenKindOf(token)]++
+	start, ok := token.(xml.StartElement)
+	if !ok {
+		return
+	}
+	report.Elements++
+	report.Attributes += len(start.Attr)
+	if depth > report.MaxDepth {
+		report.MaxDepth = depth
+	}
+	if len(start.Attr) > report.MaxAttributes {
+		report.MaxAttributes = len(start.Attr)
+	}
+}
+
+// Summary describes the report in a single line suitable for log fields,
+// counting findings per check category, e.g.
+// "3 findings: 1 roundtrip, 2 structure; 84KB; 12ms"
+func (report *ValidationReport) Summary() string {
+	summary := &strings.Builder{}
+	if len(report.Errors) == 1 {
+		summary.WriteString("1 finding")
+	} else {
+		fmt.Fprintf(summary, "%d findings", len(report.Errors))
+	}
+	categories := map[Category]int{}
+	for _, err := range report.Errors {
+		categories[FindingOf(err).Check.Category()]++
+	}
+	names := make([]string, 0, len(categories))
+	for category := range categories {
+		names = append(names, string(category))
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i == 0 {
+			summary.WriteString(": ")
+		} else {
+			summary.WriteString(", ")
+		}
+		fmt.Fprintf(summary, "%d %s", categories[Category(name)], name)
+	}
+	fmt.Fprintf(summary, "; %s; %s", formatSize(report.Size), formatDuration(report.Duration))
+	return summary.String()
+}
+
+// formatSize formats a number of bytes with a binary unit
+func formatSize(size int64) string {
+	switch {
+	case size < 1<<10:
+		return fmt.Sprintf("%dB", size)
+	case size < 1<<20:
+		return fmt.Sprintf("%dKB", size>>10)
+	}
+	return fmt.Sprintf("%.1fMB", float64(size)/(1<<20))
+}
+
+// formatDuration rounds a duration to a precision fit for logs
+func formatDuration(duration time.Duration) string {
+	if duration < time.Millisecond {
+		return duration.Round(time.Microsecond).String()
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+// Report validates the entire document and describes the outcome along
+// with the shape of the document; an error is only returned if the
+// document couldn't be read
+func (v *Validator) Report(xmlReader io.Reader) (*ValidationReport, error) {
+	return v.reportContext(context.Background(), xmlReader)
+}
+
+// reportContext is like Report, but stops validating and returns the
+// context's error once the context is done
+func (v *Validator) reportContext(ctx context.Context, xmlReader io.Reader) (*ValidationReport, error) {
+	start := time.Now()
+	report := &ValidationReport{Errors: []error{}, Tokens: map[TokenKind]int{}}
+	d := v.newDocument(xmlReader)
+	d.ctx = ctx
+	d.report = report
+	defer d.release()
+	err := d.runAll(func(err error) bool {
+		report.Errors = append(report.Errors, err)
+		return true
+	})
+	if err != nil {
+		syntaxError := &xml.SyntaxError{}
+		if !errors.As(err, &syntaxError) && !errors.Is(err, ErrTooManyErrors) {
+			return nil, err
+		}
+		report.Errors = append(report.Errors, err)
+		report.Failed = true
+	}
+	for _, stats := range d.stats {
+		if stats.Rejections > 0 {
+			report.Failed = true
+		}
+	}
+	report.Stats = d.stats
+	report.Size = d.decoder.InputOffset()
+	report.Duration = time.Since(start)
+	return report, nil
+}
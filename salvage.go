@@ -0,0 +1,64 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Salvage validates the whole document like ValidateAll, returning every
+// finding, along with the tokens left once the elements holding findings
+// that fail validation are removed: a failing start or end element is
+// dropped with its whole subtree, any other failing token with the element
+// enclosing it, and failing tokens outside the root element on their own.
+// Tokens are still checked while a subtree is skipped, so every problem is
+// reported. It is meant for ingesting large dirty feeds, not for security
+// decisions: the salvaged tokens are a different document than the one
+// sent, which a signature over it doesn't cover. Tokenization can't resume
+// after a syntax or read error, so salvaging stops there, returning the
+// error last and the tokens read so far, possibly with elements left open.
+func (v *Validator) Salvage(xmlReader io.Reader) ([]ValidatedToken, []error) {
+	tokens := []ValidatedToken{}
+	errs := []error{}
+	// starts holds the index in tokens of the start element of every open
+	// element
+	var starts []int
+	// skipping is the depth of the element being skipped, or 0
+	skipping := 0
+	d := v.newDocument(xmlReader)
+	defer d.release()
+	for {
+		start := d.offset
+		token, findings, err := d.next()
+		if errors.Is(err, io.EOF) {
+			return tokens, errs
+		} else if err != nil {
+			return tokens, append(errs, err)
+		}
+		failed := false
+		for _, finding := range findings {
+			d.record(finding.Check, finding.Severity, finding)
+			errs = append(errs, finding)
+			failed = failed || v.Fails(finding)
+		}
+		// the path holds start and end elements, so depth is that of the
+		// element enclosing any other token
+		depth := len(d.path)
+		if _, ok := token.(xml.StartElement); ok {
+			starts = append(starts, len(tokens))
+		}
+		if failed && skipping == 0 && depth > 0 {
+			tokens = tokens[:starts[depth-1]]
+			skipping = depth
+		}
+		if !failed && skipping == 0 {
+			tokens = append(tokens, d.validatedToken(token, start))
+		}
+		if _, ok := token.(xml.EndElement); ok && depth > 0 {
+			starts = starts[:depth-1]
+			if skipping == depth {
+				skipping = 0
+			}
+		}
+	}
+}
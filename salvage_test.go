@@ -0,0 +1,53 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSalvage(t *testing.T) {
+	registerTestCheck(t, "test-bad", "test", SeverityError, func(d *document, token xml.Token) error {
+		switch tok := token.(type) {
+		case xml.StartElement:
+			if tok.Name.Local == "Bad" {
+				return errors.New("bad element")
+			}
+		case xml.CharData:
+			if string(tok) == "bad" {
+				return errors.New("bad text")
+			}
+		case xml.Comment:
+			if string(tok) == "bad" {
+				return errors.New("bad comment")
+			}
+		}
+		return nil
+	})
+	render := func(tokens []ValidatedToken) string {
+		out := &bytes.Buffer{}
+		encoder := xml.NewEncoder(out)
+		for _, token := range tokens {
+			require.NoError(t, encoder.EncodeToken(token.Token))
+		}
+		require.NoError(t, encoder.Flush())
+		return out.String()
+	}
+
+	doc := `<!--bad--><Root><Keep>ok</Keep><Bad><Bad/>bad</Bad><Parent><Keep/>bad<Keep/></Parent><Last/></Root>`
+	tokens, errs := New().Salvage(strings.NewReader(doc))
+	require.Equal(t, `<Root><Keep>ok</Keep><Last></Last></Root>`, render(tokens),
+		"Should drop failing tokens outside the root, failing elements and elements enclosing failing tokens")
+	require.Len(t, errs, 5, "Should report findings in skipped subtrees too")
+	require.EqualValues(t, 16, tokens[1].Start, "Salvaged tokens should keep their position")
+
+	tokens, errs = New().Salvage(strings.NewReader(`<Root><Keep/><Open>]]>`))
+	require.Equal(t, `<Root><Keep></Keep><Open>`, render(tokens), "Should return the tokens read before syntax errors")
+	require.NotEmpty(t, errs)
+	syntaxError := &xml.SyntaxError{}
+	require.True(t, errors.As(errs[len(errs)-1], &syntaxError), "Should return syntax errors last")
+}
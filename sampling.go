@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"bytes"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+)
+
+// SamplingConfig configures WithSampling
+type SamplingConfig struct {
+	// Rate is the fraction of documents that are fully validated, between
+	// 0 and 1
+	Rate float64
+	// ByContentHash selects documents by a hash of their content rather
+	// than randomly, so a given document is either always or never fully
+	// validated; this requires reading the whole document into memory
+	// before validating it
+	ByContentHash bool
+}
+
+// WithSampling makes the Validator fully validate only a fraction of the
+// documents, for pipelines that can't afford the latency of validating
+// every one of them. Documents that aren't selected are still parsed and
+// subjected to every check but the expensive ones, i.e. the roundtrip
+// check, so syntax errors and cheap checks are enforced on all documents.
+//
+// Sampling trades security for throughput: an attacker able to submit the
+// same malicious document repeatedly will eventually get it through with
+// random sampling, and every time with ByContentHash if it isn't selected.
+func WithSampling(cfg SamplingConfig) Option {
+	return func(v *Validator) {
+		v.sampling = &cfg
+	}
+}
+
+// sample decides whether a document gets fully validated, returning a
+// reader to validate in place of the one given
+func (cfg *SamplingConfig) sample(xmlReader io.Reader) (io.Reader, bool) {
+	if cfg.Rate >= 1 {
+		return xmlReader, true
+	}
+	if !cfg.ByContentHash {
+		return xmlReader, rand.Float64() < cfg.Rate
+	}
+	content, err := ioutil.ReadAll(xmlReader)
+	if err != nil {
+		// let validation fail on the read error
+		return io.MultiReader(bytes.NewReader(content), &errorReader{err}), true
+	}
+	return bytes.NewReader(content), cfg.sampleHash(content)
+}
+
+// sampleBytes is like sample, for in-memory documents
+func (cfg *SamplingConfig) sampleBytes(xmlBytes []byte) bool {
+	if cfg.Rate >= 1 {
+		return true
+	}
+	if !cfg.ByContentHash {
+		return rand.Float64() < cfg.Rate
+	}
+	return cfg.sampleHash(xmlBytes)
+}
+
+// sampleHash decides whether to sample a document by its content hash
+func (cfg *SamplingConfig) sampleHash(content []byte) bool {
+	hash := fnv.New64a()
+	hash.Write(content)
+	return float64(hash.Sum64()) < cfg.Rate*math.MaxUint64
+}
+
+// cheapChecks returns the checks run on documents left out by sampling
+func cheapChecks(checks []activeCheck) []activeCheck {
+	cheap := checks[:0]
+	for _, c := range checks {
+		if !c.expensive {
+			cheap = append(cheap, c)
+		}
+	}
+	return cheap
+}
+
+// errorReader fails every read with err
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,49 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampling(t *testing.T) {
+	registerTestCheck(t, "test-expensive", CategoryRoundtrip, SeverityError, onStartElements)
+	builtinChecks[len(builtinChecks)-1].expensive = true
+	unstable := `<Root/>`
+
+	v := New(WithSampling(SamplingConfig{Rate: 0}))
+	require.NoError(t, v.Validate(strings.NewReader(unstable)), "Shouldn't run expensive checks on unsampled documents")
+	require.Error(t, v.Validate(strings.NewReader(`<Root>]]></Root>`)), "Should still error on syntax errors")
+	errs := v.ValidateAll(strings.NewReader(`<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd"></Root>`))
+	require.Len(t, errs, 1, "Should still run cheap checks")
+	require.Equal(t, CheckKnownAttacks, errs[0].(XMLValidationError).Check, "Should still run cheap checks")
+
+	v = New(WithSampling(SamplingConfig{Rate: 1}))
+	require.Error(t, v.Validate(strings.NewReader(unstable)), "Should fully validate sampled documents")
+
+	for _, rate := range []float64{0, 1} {
+		v = New(WithSampling(SamplingConfig{Rate: rate, ByContentHash: true}))
+		err := v.Validate(strings.NewReader(unstable))
+		require.Equal(t, rate == 1, err != nil, "Should select documents by content hash")
+	}
+
+	v = New(WithSampling(SamplingConfig{Rate: 0.5, ByContentHash: true}))
+	first := v.Validate(strings.NewReader(unstable))
+	for i := 0; i < 10; i++ {
+		require.Equal(t, first, v.Validate(strings.NewReader(unstable)), "Should consistently select the same documents")
+	}
+	selected := 0
+	for i := 0; i < 1000; i++ {
+		doc := strings.Repeat(" ", i) + unstable
+		if v.Validate(strings.NewReader(doc)) != nil {
+			selected++
+		}
+	}
+	require.InDelta(t, 500, selected, 100, "Should select about the configured fraction of documents")
+
+	readErr := errors.New("connection reset")
+	err := v.Validate(&failingReader{readErr})
+	require.True(t, errors.Is(err, readErr), "Should return read errors")
+}
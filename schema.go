@@ -0,0 +1,96 @@
+package validator
+
+// JSONSchema is the JSON Schema of the JSON this module emits, so
+// integrators can code against a stable contract. Its definitions are:
+//
+//   - finding: a finding, as marshaled by Finding and written
+//     by the xrv command; only check, severity and message are always set
+//   - result: a verdict with the findings it is based on, as answered by
+//     xrv's JSON-RPC and streaming modes
+//   - report: a validation report recorded by package xrvstore
+//   - event: a finding as posted by xrvhttp.WebhookSink
+//
+// Fields are only ever added to the definitions, as optional fields.
+const JSONSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/mattermost/xml-roundtrip-validator/schema.json",
+  "title": "xml-roundtrip-validator output",
+  "anyOf": [
+    {"$ref": "#/$defs/finding"},
+    {"$ref": "#/$defs/result"},
+    {"$ref": "#/$defs/report"},
+    {"$ref": "#/$defs/event"}
+  ],
+  "$defs": {
+    "finding": {
+      "type": "object",
+      "required": ["check", "severity", "message"],
+      "additionalProperties": false,
+      "properties": {
+        "check": {"type": "string", "description": "ID of the check reporting the finding"},
+        "code": {"type": "string", "description": "stable error code of the finding"},
+        "severity": {"enum": ["warning", "error"]},
+        "message": {"type": "string"},
+        "start": {"type": "integer", "description": "byte offset of the offending token"},
+        "end": {"type": "integer", "description": "byte offset just past the offending token"},
+        "line": {"type": "integer", "description": "1-based line of start"},
+        "column": {"type": "integer", "description": "1-based column of start"},
+        "end_line": {"type": "integer", "description": "1-based line of end"},
+        "end_column": {"type": "integer", "description": "1-based column of end"},
+        "path": {"type": "string", "description": "XPath of the element the offending token belongs to"},
+        "namespaces": {
+          "type": "object",
+          "additionalProperties": {"type": "string"},
+          "description": "prefix to URI bindings in scope at round trip findings, the empty prefix holding the default namespace"
+        },
+        "snippet": {"type": "string", "description": "offending token with its surrounding text"},
+        "roundtrip": {"$ref": "#/$defs/roundtrip"},
+        "suggestion": {"type": "string", "description": "how to fix the document, for findings with a known fix"}
+      }
+    },
+    "roundtrip": {
+      "type": "object",
+      "required": ["code", "message", "expected", "observed"],
+      "additionalProperties": false,
+      "properties": {
+        "code": {"type": "string"},
+        "message": {"type": "string"},
+        "expected": {"type": "string", "description": "token as tokenized from the document"},
+        "observed": {"type": "string", "description": "token as tokenized again after encoding it"},
+        "overflow": {"type": "string"}
+      }
+    },
+    "result": {
+      "type": "object",
+      "required": ["valid"],
+      "additionalProperties": false,
+      "properties": {
+        "valid": {"type": "boolean"},
+        "findings": {"type": "array", "items": {"$ref": "#/$defs/finding"}}
+      }
+    },
+    "report": {
+      "type": "object",
+      "required": ["digest", "time", "rejected"],
+      "additionalProperties": false,
+      "properties": {
+        "digest": {"type": "string", "description": "sha256: followed by the hex digest of the document"},
+        "time": {"type": "string", "format": "date-time"},
+        "source": {"type": "string"},
+        "rejected": {"type": "boolean"},
+        "findings": {"type": "array", "items": {"$ref": "#/$defs/finding"}}
+      }
+    },
+    "event": {
+      "type": "object",
+      "required": ["time", "rejected", "finding"],
+      "additionalProperties": false,
+      "properties": {
+        "time": {"type": "string", "format": "date-time"},
+        "rejected": {"type": "boolean"},
+        "finding": {"$ref": "#/$defs/finding"}
+      }
+    }
+  }
+}
+`
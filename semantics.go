@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Semantics identifies a generation of encoding/xml tokenization, which
+// decides how names with an empty namespace prefix or local name, or with
+// several colons, are read
+type Semantics int
+
+const (
+	// SemanticsGo116 splits such names into a prefix and local name the
+	// encoder writes back differently, failing the round trip check, as
+	// Go 1.16 and older do
+	SemanticsGo116 Semantics = iota + 1
+	// SemanticsGo117 tokenizes such names as local names, colons
+	// included, which survive round trips, as Go 1.17 to 1.19 do
+	SemanticsGo117
+	// SemanticsGo120 rejects names with several colons as syntax errors,
+	// but still tokenizes names with empty parts as local names, as Go
+	// 1.20 and newer do
+	SemanticsGo120
+)
+
+func (s Semantics) String() string {
+	switch s {
+	case SemanticsGo116:
+		return "go1.16"
+	case SemanticsGo117:
+		return "go1.17"
+	case SemanticsGo120:
+		return "go1.20"
+	}
+	return "unknown"
+}
+
+// stdlibSemantics is probed once, since it can't change at runtime
+var stdlibSemantics = probeSemantics()
+
+// StdlibSemantics returns the tokenization semantics of the encoding/xml
+// the program is built with, as probed at init by tokenizing a name with
+// several colons, so libraries can log or assert which guarantees
+// validation provides; see StrictLegacy for making them independent of it
+func StdlibSemantics() Semantics {
+	return stdlibSemantics
+}
+
+// probeSemantics tells the generations apart by how they tokenize <x::Root/>
+func probeSemantics() Semantics {
+	decoder := xml.NewDecoder(strings.NewReader(`<x::Root/>`))
+	decoder.Strict = false
+	token, err := decoder.RawToken()
+	if err != nil {
+		return SemanticsGo120
+	}
+	if start, ok := token.(xml.StartElement); ok && start.Name.Local == "x::Root" {
+		return SemanticsGo117
+	}
+	return SemanticsGo116
+}
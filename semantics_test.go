@@ -0,0 +1,14 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdlibSemantics(t *testing.T) {
+	require.Equal(t, expectedSemantics, StdlibSemantics(), "Should probe the semantics of the Go version tests are built with")
+	require.Equal(t, StdlibSemantics(), New().Capabilities().Semantics, "Capabilities should report the semantics")
+	require.Equal(t, "go1.17", SemanticsGo117.String())
+	require.Equal(t, "unknown", Semantics(0).String())
+}
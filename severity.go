@@ -0,0 +1,50 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Severity describes how serious a validation finding is
+type Severity int
+
+const (
+	// SeverityWarning marks findings that are suspicious, but not known to be exploitable
+	SeverityWarning Severity = iota + 1
+	// SeverityError marks findings that make the document unsafe to process
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses the textual representation of a severity, as
+// returned by Severity.String
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "warning":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
+
+// SeverityOf returns the severity of an error returned by this package;
+// errors that don't carry a severity, such as syntax errors, are always
+// considered to be of SeverityError
+func SeverityOf(err error) Severity {
+	validationError := XMLValidationError{}
+	if errors.As(err, &validationError) && validationError.Severity != 0 {
+		return validationError.Severity
+	}
+	return SeverityError
+}
@@ -0,0 +1,56 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []Severity{SeverityWarning, SeverityError} {
+		parsed, err := ParseSeverity(s.String())
+		require.NoError(t, err, "Should parse the string representation of a severity")
+		require.Equal(t, s, parsed, "Parsed severity should match the original")
+	}
+
+	_, err := ParseSeverity("fatal")
+	require.Error(t, err, "Should error on unknown severities")
+}
+
+func TestSeverityOf(t *testing.T) {
+	require.Equal(t, SeverityWarning, SeverityOf(XMLValidationError{Severity: SeverityWarning, err: io.ErrUnexpectedEOF}),
+		"Validation errors should report their own severity")
+	require.Equal(t, SeverityError, SeverityOf(XMLValidationError{err: io.ErrUnexpectedEOF}),
+		"Validation errors without a severity should default to errors")
+	require.Equal(t, SeverityError, SeverityOf(&xml.SyntaxError{Msg: "bad", Line: 1}),
+		"Syntax errors should always be errors")
+}
+
+func TestFailOn(t *testing.T) {
+	warning := XMLValidationError{Severity: SeverityWarning, err: io.ErrUnexpectedEOF}
+	hard := XMLValidationError{Severity: SeverityError, err: io.ErrUnexpectedEOF}
+
+	v := New()
+	require.False(t, v.Fails(warning), "Warnings shouldn't fail validation by default")
+	require.True(t, v.Fails(hard), "Errors should fail validation by default")
+	require.False(t, v.Fails(nil), "A nil error should never fail validation")
+
+	v = New(WithFailOn(SeverityWarning))
+	require.True(t, v.Fails(warning), "Warnings should fail validation when failing on warnings")
+	require.True(t, v.Fails(hard), "Errors should fail validation when failing on warnings")
+
+	require.NoError(t, v.Validate(bytes.NewBufferString(`<Root></Root>`)), "Should pass on valid XML documents")
+	require.Error(t, v.Validate(bytes.NewBufferString(`<Root>]]></Root>`)), "Should error on unparseable XML documents")
+	require.Len(t, v.ValidateAll(bytes.NewBufferString(`<Root>]]></Root>`)), 1, "Should return exactly one error")
+}
+
+func TestValidateWithOptions(t *testing.T) {
+	doc := `<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd"></Root>`
+	require.NoError(t, ValidateWithOptions(bytes.NewBufferString(doc)), "Warnings shouldn't fail validation by default")
+	require.Error(t, ValidateWithOptions(bytes.NewBufferString(doc), WithFailOn(SeverityWarning)), "Should apply the options")
+	require.Len(t, ValidateAllWithOptions(bytes.NewBufferString(doc)), 1, "Should report warnings")
+	require.Empty(t, ValidateAllWithOptions(bytes.NewBufferString(doc), WithCheck(CheckKnownAttacks, CheckConfig{Disabled: true})), "Should apply the options")
+}
@@ -0,0 +1,85 @@
+package validator
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// FindingEvent describes a single finding published to a FindingSink
+type FindingEvent struct {
+	// Time is when the finding was reported
+	Time time.Time
+	// Check and Severity identify the check reporting the finding and the
+	// severity it was reported with
+	Check    CheckID
+	Severity Severity
+	// Rejected is set if the finding was severe enough to fail validation
+	Rejected bool
+	// Err is the finding itself, either an XMLValidationError or the
+	// *xml.SyntaxError that stopped validation
+	Err error
+	// Finding describes Err, see FindingOf
+	Finding Finding
+}
+
+// FindingSink receives the findings reported by a Validator, e.g. to ship
+// them to a SIEM. Publish is called from the validating goroutine for
+// every finding, so it must not block, and implementations must be safe
+// for concurrent use; sinks that need to do I/O should queue events and
+// handle them in the background.
+type FindingSink interface {
+	Publish(event FindingEvent)
+}
+
+// WithFindingSink makes the Validator publish every finding it reports to s;
+// it may be passed several times to publish to several sinks
+func WithFindingSink(s FindingSink) Option {
+	return func(v *Validator) {
+		v.sinks = append(v.sinks, s)
+	}
+}
+
+// publish hands a finding to every configured sink
+func (d *document) publish(id CheckID, severity Severity, err error) {
+	if len(d.v.sinks) == 0 {
+		return
+	}
+	event := FindingEvent{
+		Time:     time.Now(),
+		Check:    id,
+		Severity: severity,
+		Rejected: severity >= d.v.failOn,
+		Err:      err,
+		Finding:  FindingOf(err),
+	}
+	event.Finding.Severity = severity
+	for _, sink := range d.v.sinks {
+		sink.Publish(event)
+	}
+}
+
+// ChannelSink is a FindingSink sending findings to a channel; findings are
+// dropped instead of blocking validation while the channel is full
+type ChannelSink struct {
+	ch      chan<- FindingEvent
+	dropped int64
+}
+
+// NewChannelSink returns a ChannelSink sending findings to ch
+func NewChannelSink(ch chan<- FindingEvent) *ChannelSink {
+	return &ChannelSink{ch: ch}
+}
+
+// Publish implements FindingSink
+func (s *ChannelSink) Publish(event FindingEvent) {
+	select {
+	case s.ch <- event:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Dropped returns the number of findings dropped because the channel was full
+func (s *ChannelSink) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
@@ -0,0 +1,41 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelSink(t *testing.T) {
+	ch := make(chan FindingEvent, 10)
+	sink := NewChannelSink(ch)
+	v := New(WithFindingSink(sink))
+
+	errs := v.ValidateAll(strings.NewReader(`<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd"><x::Element/></Root>`))
+	require.Len(t, errs, 2, "Should report the warning and the syntax error")
+	close(ch)
+	var events []FindingEvent
+	for event := range ch {
+		events = append(events, event)
+	}
+	require.Len(t, events, 2, "Should publish every finding")
+	require.Equal(t, CheckKnownAttacks, events[0].Check, "Should publish findings in order")
+	require.Equal(t, SeverityWarning, events[0].Severity, "Should publish the severity of findings")
+	require.False(t, events[0].Rejected, "Warnings shouldn't be marked as rejecting the document")
+	require.Equal(t, errs[0], events[0].Err, "Should publish the finding itself")
+	require.Equal(t, CheckSyntax, events[1].Check, "Should publish syntax errors")
+	require.True(t, events[1].Rejected, "Errors should be marked as rejecting the document")
+	require.IsType(t, &xml.SyntaxError{}, events[1].Err, "Should publish syntax errors")
+	require.False(t, events[1].Time.IsZero(), "Should timestamp findings")
+	require.Zero(t, sink.Dropped(), "Shouldn't drop findings")
+
+	ch = make(chan FindingEvent, 1)
+	sink = NewChannelSink(ch)
+	v = New(WithFindingSink(sink))
+	v.ValidateAll(strings.NewReader(`<Root><x::Element/></Root>`))
+	v.ValidateAll(strings.NewReader(`<Root><x::Element/></Root>`))
+	require.Len(t, ch, 1, "Should fill the channel")
+	require.Equal(t, int64(1), sink.Dropped(), "Should drop findings instead of blocking")
+}
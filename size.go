@@ -0,0 +1,132 @@
+package validator
+
+import (
+	"fmt"
+	"io"
+)
+
+// SizeLimitError is returned when a document, or a single token in it, is
+// larger than the Validator's limit; validation stops there, without
+// reading the rest of the document
+type SizeLimitError struct {
+	// Token is set if a single token exceeded the limit set with
+	// WithMaxTokenBytes, rather than the document exceeding the limit set
+	// with WithMaxBytes
+	Token bool
+	// Limit is the limit exceeded, in bytes
+	Limit int64
+}
+
+func (err *SizeLimitError) Error() string {
+	if err.Token {
+		return fmt.Sprintf("validator: token exceeds the size limit of %d bytes", err.Limit)
+	}
+	return fmt.Sprintf("validator: document exceeds the size limit of %d bytes", err.Limit)
+}
+
+// WithMaxBytes limits the size of documents, in bytes, so hostile streams
+// are rejected without being read to completion; a limit of zero or less,
+// the default, disables the limit
+func WithMaxBytes(n int64) Option {
+	return func(v *Validator) {
+		v.maxBytes = n
+	}
+}
+
+// WithMaxTokenBytes limits the size of single tokens, in bytes, such as a
+// start element with its attributes or a run of character data, bounding
+// the memory a document can make encoding/xml allocate at once; a limit of
+// zero or less, the default, disables the limit
+func WithMaxTokenBytes(n int64) Option {
+	return func(v *Validator) {
+		v.maxTokenBytes = n
+	}
+}
+
+// limitBytes makes r fail with a SizeLimitError once reading it exceeds
+// the limit set with WithMaxBytes, reading at most one byte past it. It
+// wraps readers before anything buffers them, such as emulation, sampling
+// or readAll, so hostile streams are never read to completion.
+func (v *Validator) limitBytes(r io.Reader) io.Reader {
+	if v.maxBytes <= 0 {
+		return r
+	}
+	return &byteLimitedReader{r: r, limit: v.maxBytes}
+}
+
+// LimitReader returns a reader of r failing with a SizeLimitError once
+// reading it exceeds the limit set with WithMaxBytes, so callers buffering a
+// document before validating it can bound the memory it takes; r is
+// returned as is if no limit is set
+func (v *Validator) LimitReader(r io.Reader) io.Reader {
+	return v.limitBytes(r)
+}
+
+// byteLimitedReader reads at most one byte past its limit, so encoding/xml
+// can still tell a document of exactly the limit apart from a longer one
+type byteLimitedReader struct {
+	r     io.Reader
+	limit int64
+	// read is the number of bytes read so far
+	read int64
+}
+
+func (r *byteLimitedReader) Read(p []byte) (int, error) {
+	if r.read > r.limit {
+		return 0, &SizeLimitError{Limit: r.limit}
+	}
+	if int64(len(p)) > r.limit+1-r.read {
+		p = p[:r.limit+1-r.read]
+	}
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// limitTokens makes r fail with a SizeLimitError as soon as reading it
+// exceeds the limit set with WithMaxTokenBytes, once the document reading
+// it is set
+func (v *Validator) limitTokens(r io.Reader) io.Reader {
+	if v.maxTokenBytes <= 0 {
+		return r
+	}
+	return &tokenLimitedReader{r: r}
+}
+
+// tokenLimitedReader reads at most one byte past the end of a token of the
+// size limit, so encoding/xml can still tell a token of exactly the limit
+// apart from a longer one
+type tokenLimitedReader struct {
+	r io.Reader
+	d *document
+	// read is the number of bytes read so far
+	read int64
+}
+
+func (r *tokenLimitedReader) Read(p []byte) (int, error) {
+	max := r.d.v.maxTokenBytes
+	allowed := r.d.offset + max + 1 - r.read
+	if allowed <= 0 {
+		return 0, &SizeLimitError{Token: true, Limit: max}
+	}
+	if int64(len(p)) > allowed {
+		p = p[:allowed]
+	}
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// checkSize returns a SizeLimitError if the token ending at end exceeds
+// one of the Validator's size limits; limits on in-memory documents are
+// only enforced this way, and limits on streams by reading no further
+func (d *document) checkSize(end int64) error {
+	v := d.v
+	if v.maxBytes > 0 && end > v.maxBytes {
+		return &SizeLimitError{Limit: v.maxBytes}
+	}
+	if v.maxTokenBytes > 0 && end-d.offset > v.maxTokenBytes {
+		return &SizeLimitError{Token: true, Limit: v.maxTokenBytes}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// endlessReader serves the start of a document followed by endless text
+type endlessReader struct {
+	read int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	if r.read == 0 && len(p) >= len("<Root>") {
+		r.read += copy(p, "<Root>")
+		return len("<Root>"), nil
+	}
+	for i := range p {
+		p[i] = 'a'
+	}
+	r.read += len(p)
+	return len(p), nil
+}
+
+func TestSizeLimits(t *testing.T) {
+	doc := `<Root><Child a="1">text</Child></Root>`
+
+	v := New(WithMaxBytes(int64(len(doc))))
+	require.NoError(t, v.Validate(strings.NewReader(doc)), "Should allow documents up to the limit")
+	require.NoError(t, v.ValidateBytes([]byte(doc)), "Should allow documents up to the limit")
+	v = New(WithMaxBytes(int64(len(doc) - 1)))
+	for _, err := range []error{v.Validate(strings.NewReader(doc)), v.ValidateBytes([]byte(doc))} {
+		sizeError := &SizeLimitError{}
+		require.True(t, errors.As(err, &sizeError), "Should reject documents over the limit")
+		require.Equal(t, SizeLimitError{Limit: int64(len(doc) - 1)}, *sizeError)
+		require.False(t, errors.As(err, &IOError{}), "Shouldn't mistake the limit for an I/O error")
+	}
+
+	endless := &endlessReader{}
+	err := New(WithMaxBytes(1 << 20)).Validate(endless)
+	require.True(t, errors.As(err, new(*SizeLimitError)), "Should reject endless documents")
+	require.LessOrEqual(t, endless.read, 1<<20+1, "Should stop reading past the limit")
+
+	v = New(WithMaxTokenBytes(int64(len(`<Child a="1">`))))
+	require.NoError(t, v.Validate(strings.NewReader(doc)), "Should allow tokens up to the limit")
+	require.NoError(t, v.ValidateBytes([]byte(doc)), "Should allow tokens up to the limit")
+	v = New(WithMaxTokenBytes(int64(len(`<Child a="1">`) - 1)))
+	for _, err := range []error{v.Validate(strings.NewReader(doc)), v.ValidateBytes([]byte(doc))} {
+		sizeError := &SizeLimitError{}
+		require.True(t, errors.As(err, &sizeError), "Should reject tokens over the limit")
+		require.Equal(t, SizeLimitError{Token: true, Limit: int64(len(`<Child a="1">`) - 1)}, *sizeError)
+	}
+
+	endless = &endlessReader{}
+	err = New(WithMaxTokenBytes(4096)).Validate(endless)
+	require.True(t, errors.As(err, new(*SizeLimitError)), "Should reject endless tokens")
+	require.LessOrEqual(t, endless.read, 4096+len("<Root>")+1, "Should stop reading past the limit")
+}
+
+func TestLimitReader(t *testing.T) {
+	endless := &endlessReader{}
+	_, err := ioutil.ReadAll(New(WithMaxBytes(1 << 16)).LimitReader(endless))
+	sizeError := &SizeLimitError{}
+	require.True(t, errors.As(err, &sizeError), "Should fail past the limit")
+	require.Equal(t, SizeLimitError{Limit: 1 << 16}, *sizeError)
+	require.LessOrEqual(t, endless.read, 1<<16+1, "Should stop reading past the limit")
+
+	r := strings.NewReader("<Root/>")
+	require.Equal(t, io.Reader(r), New().LimitReader(r), "Should return readers as is without a limit")
+}
+
+func TestSizeLimitsBeforeBuffering(t *testing.T) {
+	const limit = 4096
+	paths := map[string]func(v *Validator, r io.Reader) error{
+		"Validate": func(v *Validator, r io.Reader) error {
+			return v.Validate(r)
+		},
+		"QuickValidate": func(v *Validator, r io.Reader) error {
+			return v.QuickValidate(r)
+		},
+		"ValidateTiered": func(v *Validator, r io.Reader) error {
+			_, err := v.ValidateTiered(r)
+			return err
+		},
+		"ValidateDual": func(v *Validator, r io.Reader) error {
+			return v.ValidateDual(r).Current
+		},
+		"Decode": func(v *Validator, r io.Reader) error {
+			var out struct{}
+			return v.Decode(r, &out)
+		},
+	}
+	for name, validate := range paths {
+		for _, opts := range [][]Option{
+			{WithMaxBytes(limit)},
+			{WithMaxBytes(limit), EmulateGo117()},
+			{WithMaxBytes(limit), WithSampling(SamplingConfig{Rate: 0.5, ByContentHash: true})},
+		} {
+			endless := &endlessReader{}
+			err := validate(New(opts...), endless)
+			require.True(t, errors.As(err, new(*SizeLimitError)), "%s should reject endless documents", name)
+			require.LessOrEqual(t, endless.read, limit+1, "%s should stop reading past the limit", name)
+		}
+	}
+}
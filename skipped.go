@@ -0,0 +1,154 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// maxGapContext is the number of skipped bytes quoted in findings
+const maxGapContext = 32
+
+// SkippedBytesConfig configures CheckSkippedBytes
+type SkippedBytesConfig struct {
+	CheckConfig
+}
+
+// WithSkippedBytesCheck enables and configures CheckSkippedBytes
+func WithSkippedBytesCheck(cfg SkippedBytesConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckSkippedBytes] = cfg.CheckConfig
+	}
+}
+
+// newSkippedBytesCheck creates the per-document state of CheckSkippedBytes
+func newSkippedBytesCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		raw := d.raw()
+		gap := unexplainedBytes(token, raw)
+		if gap < 0 {
+			return nil
+		}
+		skipped := raw[gap:]
+		if len(skipped) > maxGapContext {
+			skipped = skipped[:maxGapContext]
+		}
+		return fmt.Errorf("tokenizer skipped bytes at offset %d: %q", d.offset+int64(gap), d.redact(string(skipped)))
+	}
+}
+
+// unexplainedBytes reconciles the raw bytes of a token with the token
+// itself, returning the offset of the first byte the token doesn't account
+// for, or -1 if it accounts for all of them; whitespace and markup
+// delimiters are accounted for wherever the syntax allows them, and the
+// content of character data is trusted to be decoded faithfully
+func unexplainedBytes(token xml.Token, raw []byte) int {
+	s := &rawScanner{raw: raw, failed: -1}
+	switch t := token.(type) {
+	case xml.StartElement:
+		s.expect("<")
+		s.expect(qualifiedName(t.Name))
+		for _, attr := range t.Attr {
+			s.skipSpace()
+			s.expect(qualifiedName(attr.Name))
+			s.skipSpace()
+			if s.accept("=") {
+				s.skipSpace()
+				s.skipValue()
+			}
+		}
+		s.skipSpace()
+		s.accept("/")
+		s.expect(">")
+	case xml.EndElement:
+		// the end element of an empty element tag has no bytes of its own
+		if len(raw) == 0 {
+			return -1
+		}
+		s.expect("</")
+		s.expect(qualifiedName(t.Name))
+		s.skipSpace()
+		s.expect(">")
+	case xml.ProcInst:
+		s.expect("<?")
+		s.expect(t.Target)
+		s.skipSpace()
+		s.expect(string(t.Inst))
+		s.expect("?>")
+	case xml.Comment:
+		s.expect("<!--")
+		s.expect(string(t))
+		s.expect("-->")
+	case xml.Directive:
+		s.expect("<!")
+		s.expect(string(t))
+		s.expect(">")
+	default:
+		return -1
+	}
+	if s.failed < 0 && s.pos < len(raw) {
+		return s.pos
+	}
+	return s.failed
+}
+
+// rawScanner matches the raw bytes of a token against its expected syntax,
+// remembering the position of the first mismatch
+type rawScanner struct {
+	raw    []byte
+	pos    int
+	failed int
+}
+
+// expect consumes the given bytes, or records a mismatch at the first byte
+// that differs from them
+func (s *rawScanner) expect(expected string) {
+	if s.failed >= 0 || s.accept(expected) {
+		return
+	}
+	rest := s.raw[s.pos:]
+	i := 0
+	for i < len(rest) && i < len(expected) && rest[i] == expected[i] {
+		i++
+	}
+	s.failed = s.pos + i
+}
+
+// accept consumes the given bytes if they come next, reporting whether they did
+func (s *rawScanner) accept(expected string) bool {
+	if s.failed >= 0 || !bytes.HasPrefix(s.raw[s.pos:], []byte(expected)) {
+		return false
+	}
+	s.pos += len(expected)
+	return true
+}
+
+func (s *rawScanner) skipSpace() {
+	for s.failed < 0 && s.pos < len(s.raw) && isSpace(s.raw[s.pos]) {
+		s.pos++
+	}
+}
+
+// skipValue consumes an attribute value, which may be unquoted in
+// non-strict mode
+func (s *rawScanner) skipValue() {
+	if s.failed >= 0 || s.pos >= len(s.raw) {
+		return
+	}
+	if quote := s.raw[s.pos]; quote == '"' || quote == '\'' {
+		end := bytes.IndexByte(s.raw[s.pos+1:], quote)
+		if end < 0 {
+			s.failed = s.pos
+			return
+		}
+		s.pos += end + 2
+		return
+	}
+	for s.pos < len(s.raw) && !isSpace(s.raw[s.pos]) && s.raw[s.pos] != '>' && s.raw[s.pos] != '/' {
+		s.pos++
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
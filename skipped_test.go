@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkippedBytes(t *testing.T) {
+	v := New(WithSkippedBytesCheck(SkippedBytesConfig{}))
+
+	clean := []string{
+		`<Root/>`,
+		"\xef\xbb\xbf<?xml version=\"1.0\"  ?>\n<Root/>",
+		`<?target   inst  ?><Root a = 'x' b="y"	></Root >`,
+		`<Root><!-- comment --><![CDATA[data]]>a &amp; b</Root>`,
+		`<!DOCTYPE Root [ <!ENTITY a "b"> ]><Root/>`,
+		`<x:Root xmlns:x="urn:x" x:a="1"><x:Child/></x:Root>`,
+	}
+	for _, doc := range clean {
+		require.Empty(t, v.ValidateAll(strings.NewReader(doc)), "Should pass when the tokens account for every byte of %s", doc)
+	}
+
+	errs := v.ValidateAll(strings.NewReader(`<Root><!DOCTYPE x [ <!-- hidden --> <!ENTITY a "b"> ]></Root>`))
+	require.Len(t, errs, 1, "Should report comments discarded from directives")
+	require.Equal(t, CheckSkippedBytes, errs[0].(XMLValidationError).Check, "Finding should be reported by the skipped bytes check")
+	require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Skipped bytes should be warnings by default")
+	require.Contains(t, errs[0].Error(), `tokenizer skipped bytes at offset 20: "<!-- hidden -->`, "Should locate the skipped bytes")
+
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Root><!DOCTYPE x [ <!-- c --> ]></Root>`)), "Should be disabled by default")
+}
+
+func TestUnexplainedBytes(t *testing.T) {
+	start := xml.StartElement{Name: xml.Name{Local: "Root"}, Attr: []xml.Attr{{Name: xml.Name{Local: "a"}, Value: "1"}}}
+	require.Equal(t, -1, unexplainedBytes(start, []byte(`<Root a="1">`)), "Should account for start elements")
+	require.Equal(t, -1, unexplainedBytes(start, []byte(`<Root a=1/>`)), "Should account for unquoted attribute values")
+	require.Equal(t, 12, unexplainedBytes(start, []byte(`<Root a="1" b="2">`)), "Should report attributes missing from the token")
+	require.Equal(t, 5, unexplainedBytes(start, []byte(`<Rootx a="1">`)), "Should report mismatched names")
+	require.Equal(t, 5, unexplainedBytes(xml.Comment(" a "), []byte(`<!-- b -->`)), "Should report mismatched comments")
+	require.Equal(t, 4, unexplainedBytes(xml.EndElement{Name: xml.Name{Local: "a"}}, []byte(`</a>x`)), "Should report trailing bytes")
+	require.Equal(t, -1, unexplainedBytes(xml.EndElement{Name: xml.Name{Local: "a"}}, nil), "Should account for the end of empty element tags")
+}
@@ -0,0 +1,55 @@
+//go:build go1.21
+// +build go1.21
+
+package validator
+
+import (
+	"log/slog"
+)
+
+// LogValue describes the finding as a group of attributes for structured
+// logging, with its position and round trip mismatch as separate fields
+func (f Finding) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("check", string(f.Check)),
+		slog.String("code", string(f.Code)),
+		slog.String("severity", f.Severity.String()),
+		slog.Int64("line", f.Line),
+		slog.Int64("column", f.Column),
+		slog.Int64("start", f.Start),
+		slog.Int64("end", f.End),
+	}
+	if f.Path != "" {
+		attrs = append(attrs, slog.String("path", f.Path))
+	}
+	if f.Message != "" {
+		attrs = append(attrs, slog.String("message", f.Message))
+	}
+	if f.Expected != nil {
+		attrs = append(attrs, slog.Any("roundtrip", XMLRoundtripError{Expected: f.Expected, Observed: f.Observed, Overflow: f.Overflow}))
+	}
+	if f.Suggestion != "" {
+		attrs = append(attrs, slog.String("suggestion", f.Suggestion))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogValue describes the finding the way Finding does
+func (err XMLValidationError) LogValue() slog.Value {
+	return err.Finding().LogValue()
+}
+
+// LogValue describes the mismatch as a group of attributes for structured
+// logging, with the kind of the mismatching token
+func (err XMLRoundtripError) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("code", string(err.Code())),
+		slog.String("token", tokenKindOf(err.Expected).String()),
+		slog.String("expected", formatToken(err.Expected)),
+		slog.String("observed", formatToken(err.Observed)),
+	}
+	if len(err.Overflow) > 0 {
+		attrs = append(attrs, slog.String("overflow", string(err.Overflow)))
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogValue(t *testing.T) {
+	roundtripError := XMLRoundtripError{
+		Expected: xml.Directive("DOCTYPE x"),
+		Observed: xml.Directive("DOCTYPE y"),
+	}
+	err := XMLValidationError{Start: 6, End: 19, Line: 1, Column: 7, Severity: SeverityError, Check: CheckRoundtrip, err: roundtripError}
+
+	out := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+	logger.Info("rejected", "finding", err)
+	require.Equal(t, `level=INFO msg=rejected finding.check=roundtrip finding.code=directive-mutation finding.severity=error `+
+		`finding.line=1 finding.column=7 finding.start=6 finding.end=19 `+
+		`finding.message="`+roundtripError.Error()+`" `+
+		`finding.roundtrip.code=directive-mutation finding.roundtrip.token=directive `+
+		`finding.roundtrip.expected="<!DOCTYPE x>" finding.roundtrip.observed="<!DOCTYPE y>"`+"\n", out.String(),
+		"Should log findings as attributes")
+}
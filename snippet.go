@@ -0,0 +1,39 @@
+package validator
+
+// WithSnippets makes findings carry a Snippet of the document around the
+// offending token, with up to n bytes of context on either side; tokens
+// longer than 2n bytes are shortened to their first and last n bytes.
+// Context following the token is only included as far as the document
+// was already read, which is all of it for in-memory documents.
+func WithSnippets(n int) Option {
+	return func(v *Validator) {
+		v.snippetContext = n
+	}
+}
+
+// snippetEllipsis replaces the middle of tokens too long for snippets
+const snippetEllipsis = "..."
+
+// snippet returns the bytes from start to end along with their context,
+// or an empty string if snippets are disabled or redaction enabled
+func (d *document) snippet(start, end int64) string {
+	n := int64(d.v.snippetContext)
+	if n <= 0 || d.v.redaction {
+		return ""
+	}
+	data := d.input.consumed()
+	if in, ok := d.input.(*sliceReader); ok {
+		data = in.data
+	}
+	from, to := start-n, end+n
+	if from < 0 {
+		from = 0
+	}
+	if to > int64(len(data)) {
+		to = int64(len(data))
+	}
+	if end-start <= 2*n {
+		return string(data[from:to])
+	}
+	return string(data[from:start+n]) + snippetEllipsis + string(data[end-n:to])
+}
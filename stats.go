@@ -0,0 +1,90 @@
+package validator
+
+import (
+	"sync"
+)
+
+// Metrics receives check activity from a Validator, e.g. to export it to
+// a monitoring system; implementations must be safe for concurrent use
+type Metrics interface {
+	// CheckFired is called every time a check reports a finding
+	CheckFired(id CheckID)
+	// DocumentRejected is called once per document for every check that
+	// reported a finding severe enough to fail validation
+	DocumentRejected(id CheckID)
+}
+
+// CheckStats counts the activity of a single check
+type CheckStats struct {
+	// Hits is the number of findings the check reported
+	Hits int64
+	// Rejections is the number of documents that failed validation
+	// because of a finding reported by the check
+	Rejections int64
+}
+
+// WithMetrics makes the Validator report check activity to m, in addition
+// to the counters returned by Validator.Stats
+func WithMetrics(m Metrics) Option {
+	return func(v *Validator) {
+		v.metrics = m
+	}
+}
+
+// statsCounter is the Metrics implementation backing Validator.Stats
+type statsCounter struct {
+	mu    sync.Mutex
+	stats map[CheckID]CheckStats
+}
+
+func (c *statsCounter) CheckFired(id CheckID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats[id]
+	stats.Hits++
+	c.stats[id] = stats
+}
+
+func (c *statsCounter) DocumentRejected(id CheckID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats[id]
+	stats.Rejections++
+	c.stats[id] = stats
+}
+
+func (c *statsCounter) snapshot() map[CheckID]CheckStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot := make(map[CheckID]CheckStats, len(c.stats))
+	for id, stats := range c.stats {
+		snapshot[id] = stats
+	}
+	return snapshot
+}
+
+// Stats returns how many times each check fired and how many documents
+// it rejected since the Validator was created; checks that never fired
+// are omitted
+func (v *Validator) Stats() map[CheckID]CheckStats {
+	return v.stats.snapshot()
+}
+
+// record accounts for a single finding reported by a check
+func (d *document) record(id CheckID, severity Severity, err error) {
+	d.v.stats.CheckFired(id)
+	if d.v.metrics != nil {
+		d.v.metrics.CheckFired(id)
+	}
+	d.publish(id, severity, err)
+	stats := d.stats[id]
+	stats.Hits++
+	if severity >= d.v.failOn && stats.Rejections == 0 {
+		stats.Rejections = 1
+		d.v.stats.DocumentRejected(id)
+		if d.v.metrics != nil {
+			d.v.metrics.DocumentRejected(id)
+		}
+	}
+	d.stats[id] = stats
+}
@@ -0,0 +1,106 @@
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	fired    []CheckID
+	rejected []CheckID
+}
+
+func (m *recordingMetrics) CheckFired(id CheckID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fired = append(m.fired, id)
+}
+
+func (m *recordingMetrics) DocumentRejected(id CheckID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rejected = append(m.rejected, id)
+}
+
+func TestStats(t *testing.T) {
+	metrics := &recordingMetrics{}
+	v := New(WithMetrics(metrics))
+
+	require.Empty(t, v.Stats(), "A new Validator shouldn't have any statistics")
+
+	require.NoError(t, v.Validate(bytes.NewBufferString(`<Root></Root>`)), "Should pass on valid XML documents")
+	require.Error(t, v.Validate(bytes.NewBufferString(`<Root>]]></Root>`)), "Should error on unparseable XML documents")
+	require.Error(t, v.Validate(bytes.NewBufferString(`<Root><!--`)), "Should error on unparseable XML documents")
+
+	require.Equal(t, map[CheckID]CheckStats{
+		CheckSyntax: {Hits: 2, Rejections: 2},
+	}, v.Stats(), "Syntax errors should be counted")
+	require.Equal(t, []CheckID{CheckSyntax, CheckSyntax}, metrics.fired, "Metrics should be told about every finding")
+	require.Equal(t, []CheckID{CheckSyntax, CheckSyntax}, metrics.rejected, "Metrics should be told about every rejection")
+}
+
+func TestReport(t *testing.T) {
+	v := New()
+
+	report, err := v.Report(bytes.NewBufferString(`<Root></Root>`))
+	require.NoError(t, err, "Should report on valid XML documents")
+	require.False(t, report.Failed, "Valid XML documents shouldn't fail")
+	require.Empty(t, report.Errors, "Valid XML documents shouldn't have findings")
+
+	report, err = v.Report(bytes.NewBufferString(`<Root>]]></Root>`))
+	require.NoError(t, err, "Syntax errors should be reported as findings")
+	require.True(t, report.Failed, "Unparseable XML documents should fail")
+	require.Len(t, report.Errors, 1, "Should return exactly one error")
+	require.Equal(t, CheckStats{Hits: 1, Rejections: 1}, report.Stats[CheckSyntax], "Report should count the syntax error")
+
+	readErr := errors.New("connection reset")
+	_, err = v.Report(&failingReader{readErr})
+	require.True(t, errors.Is(err, readErr), "Read errors should be returned")
+}
+
+func TestReportSummary(t *testing.T) {
+	registerTestCheck(t, "test-a", "a", SeverityError, onStartElements)
+	registerTestCheck(t, "test-b", "b", SeverityWarning, onStartElements)
+
+	report, err := New().Report(strings.NewReader(`<Root><Child/></Root>]]>`))
+	require.NoError(t, err, "Should report on documents with findings")
+	require.Equal(t, int64(24), report.Size, "Should count the bytes read")
+	require.True(t, strings.HasPrefix(report.Summary(), "5 findings: 2 a, 2 b, 1 syntax; 24B; "), "Should count findings per category in %q", report.Summary())
+
+	report = &ValidationReport{Errors: []error{XMLValidationError{Check: CheckRoundtrip, err: errors.New("mismatch")}}, Size: 86000, Duration: 12345 * time.Microsecond}
+	require.Equal(t, "1 finding: 1 roundtrip; 83KB; 12ms", report.Summary(), "Should summarize reports in a single line")
+	report = &ValidationReport{Size: 3 << 20, Duration: 850 * time.Microsecond}
+	require.Equal(t, "0 findings; 3.0MB; 850µs", report.Summary(), "Should summarize reports without findings")
+}
+
+func TestReportShape(t *testing.T) {
+	doc := `<?xml version="1.0"?><Root xmlns:a="urn:a" a:x="1"><!-- c --><Child a="1" b="2" c="3"><Leaf/>text</Child><Child/></Root>`
+	report, err := New().Report(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Equal(t, map[TokenKind]int{
+		TokenProcInst:     1,
+		TokenStartElement: 4,
+		TokenEndElement:   4,
+		TokenComment:      1,
+		TokenCharData:     1,
+	}, report.Tokens, "Should count tokens of every kind")
+	require.Equal(t, 4, report.Elements)
+	require.Equal(t, 5, report.Attributes, "Namespace declarations should count as attributes")
+	require.Equal(t, 3, report.MaxDepth)
+	require.Equal(t, 3, report.MaxAttributes)
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,454 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// newXMLDeclarationCheck creates the per-document state of CheckXMLDeclaration
+func newXMLDeclarationCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		procInst, ok := token.(xml.ProcInst)
+		if !ok || !strings.EqualFold(procInst.Target, "xml") {
+			return nil
+		}
+		if procInst.Target != "xml" {
+			return fmt.Errorf("processing instruction target %q is reserved", procInst.Target)
+		}
+		if d.offset != 0 && !(d.offset == int64(len(utf8BOM)) && bytes.HasPrefix(d.input.consumed(), utf8BOM)) {
+			return errors.New("XML declaration is only allowed at the start of the document")
+		}
+		return nil
+	}
+}
+
+// TrailingContentConfig configures CheckTrailingContent
+type TrailingContentConfig struct {
+	CheckConfig
+}
+
+// WithTrailingContentCheck enables and configures CheckTrailingContent
+func WithTrailingContentCheck(cfg TrailingContentConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckTrailingContent] = cfg.CheckConfig
+	}
+}
+
+// newTrailingContentCheck creates the per-document state of
+// CheckTrailingContent
+func newTrailingContentCheck(v *Validator) tokenCheck {
+	closed := false
+	return func(d *document, token xml.Token) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			if closed && len(d.path) == 1 {
+				return fmt.Errorf("element %s follows the root element", qualifiedName(t.Name))
+			}
+		case xml.EndElement:
+			closed = closed || (d.closing && len(d.path) == 1)
+		case xml.CharData:
+			if closed && len(d.path) == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return errors.New("character data follows the root element")
+			}
+		}
+		return nil
+	}
+}
+
+// AttributeOrderConfig configures CheckAttributeOrder
+type AttributeOrderConfig struct {
+	CheckConfig
+}
+
+// WithAttributeOrderCheck enables and configures CheckAttributeOrder
+func WithAttributeOrderCheck(cfg AttributeOrderConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckAttributeOrder] = cfg.CheckConfig
+	}
+}
+
+// newAttributeOrderCheck creates the per-document state of CheckAttributeOrder
+func newAttributeOrderCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			return nil
+		}
+		for i := 1; i < len(start.Attr); i++ {
+			if d.canonicalLess(start.Attr[i], start.Attr[i-1]) {
+				return fmt.Errorf("attribute %s would be moved before %s by canonicalization",
+					qualifiedName(start.Attr[i].Name), qualifiedName(start.Attr[i-1].Name))
+			}
+		}
+		return nil
+	}
+}
+
+// DuplicateAttributesConfig configures CheckDuplicateAttributes
+type DuplicateAttributesConfig struct {
+	CheckConfig
+}
+
+// WithDuplicateAttributesCheck enables and configures
+// CheckDuplicateAttributes
+func WithDuplicateAttributesCheck(cfg DuplicateAttributesConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckDuplicateAttributes] = cfg.CheckConfig
+	}
+}
+
+// newDuplicateAttributesCheck creates the per-document state of
+// CheckDuplicateAttributes
+func newDuplicateAttributesCheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok || len(start.Attr) < 2 {
+			return nil
+		}
+		seen := make(map[xml.Name]xml.Name, len(start.Attr))
+		for _, attr := range start.Attr {
+			if _, ok := declaredPrefix(attr); ok {
+				// reported by CheckDuplicateDeclarations
+				continue
+			}
+			name := d.resolveName(attr.Name, false)
+			first, ok := seen[name]
+			switch {
+			case ok && first == attr.Name:
+				return fmt.Errorf("attribute %s appears more than once", qualifiedName(attr.Name))
+			case ok:
+				return fmt.Errorf("attributes %s and %s both resolve to {%s}%s",
+					qualifiedName(first), qualifiedName(attr.Name), name.Space, name.Local)
+			}
+			seen[name] = attr.Name
+		}
+		return nil
+	}
+}
+
+// canonicalLess reports whether Canonical XML puts attribute a before b:
+// namespace declarations come first, sorted by prefix, followed by the
+// other attributes sorted by namespace URI, then local name
+func (d *document) canonicalLess(a, b xml.Attr) bool {
+	aPrefix, aDeclaration := declaredPrefix(a)
+	bPrefix, bDeclaration := declaredPrefix(b)
+	if aDeclaration || bDeclaration {
+		return aDeclaration && (!bDeclaration || aPrefix < bPrefix)
+	}
+	aName, bName := d.resolveName(a.Name, false), d.resolveName(b.Name, false)
+	if aName.Space != bName.Space {
+		return aName.Space < bName.Space
+	}
+	return aName.Local < bName.Local
+}
+
+var cdataStart = []byte("<![CDATA[")
+
+// CDATAConfig configures CheckCDATA
+type CDATAConfig struct {
+	CheckConfig
+	// RequireLossless only reports CDATA sections whose content, as written
+	// in the document, doesn't survive being converted to escaped character
+	// data and parsed again, for projects normalizing CDATA sections away;
+	// by default every CDATA section whose content needs escaping is
+	// reported, since converting it changes the length of the document and
+	// breaks byte-range signatures
+	RequireLossless bool
+	// Disallow reports every CDATA section as an XMLPolicyError with
+	// ErrCodeCDATASection, for documents never expected to hold any, since
+	// canonicalizers disagree on whether CDATA sections are the same as
+	// the escaped character data they stand for
+	Disallow bool
+}
+
+// WithCDATACheck enables and configures CheckCDATA
+func WithCDATACheck(cfg CDATAConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckCDATA] = cfg.CheckConfig
+		v.cdata = cfg
+	}
+}
+
+// newCDATACheck creates the per-document state of CheckCDATA
+func newCDATACheck(v *Validator) tokenCheck {
+	return func(d *document, token xml.Token) error {
+		data, ok := token.(xml.CharData)
+		raw := d.raw()
+		if !ok || !bytes.HasPrefix(raw, cdataStart) {
+			return nil
+		}
+		if v.cdata.Disallow {
+			return XMLPolicyError{ErrCodeCDATASection, d.location()}
+		}
+		escaped, err := escapeCharData(data)
+		if err != nil {
+			return err
+		}
+		if !v.cdata.RequireLossless {
+			if !bytes.Equal(escaped, data) {
+				return errors.New("CDATA section content would change when converted to escaped character data")
+			}
+			return nil
+		}
+		content := bytes.TrimSuffix(raw[len(cdataStart):], []byte("]]>"))
+		if !bytes.Equal(unescapeText(escaped), content) {
+			return errors.New("CDATA section content can't be converted to escaped character data without loss")
+		}
+		return nil
+	}
+}
+
+// escapeCharData escapes character data the way xml.Encoder does
+func escapeCharData(data xml.CharData) ([]byte, error) {
+	escaped := &bytes.Buffer{}
+	encoder := xml.NewEncoder(escaped)
+	if err := encoder.EncodeToken(data); err != nil {
+		return nil, err
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return escaped.Bytes(), nil
+}
+
+// unescapeText parses escaped character data back into its content
+func unescapeText(escaped []byte) []byte {
+	decoder := xml.NewDecoder(io.MultiReader(
+		strings.NewReader("<x>"), bytes.NewReader(escaped), strings.NewReader("</x>")))
+	content := []byte{}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return content
+		}
+		if data, ok := token.(xml.CharData); ok {
+			content = append(content, data...)
+		}
+	}
+}
+
+// ExpectCharset returns a copy of the Validator that reports documents whose
+// XML declaration doesn't match the given charset, as found in the charset
+// parameter of a Content-Type header; documents without an encoding
+// declaration are expected to be UTF-8. The copy shares the statistics,
+// metrics and finding sinks of the original.
+func (v *Validator) ExpectCharset(charset string) *Validator {
+	expecting := *v
+	expecting.charset = charset
+	return &expecting
+}
+
+// newCharsetCheck creates the per-document state of CheckCharset
+func newCharsetCheck(v *Validator) tokenCheck {
+	decided := v.charset == ""
+	return func(d *document, token xml.Token) error {
+		if decided {
+			return nil
+		}
+		if data, ok := token.(xml.CharData); ok && bytes.Equal(data, utf8BOM) {
+			// the declaration may follow a byte order mark
+			return nil
+		}
+		decided = true
+		declared := "UTF-8"
+		if procInst, ok := token.(xml.ProcInst); ok && procInst.Target == "xml" {
+			if encoding, ok := declarationAttribute(procInst.Inst, "encoding"); ok {
+				declared = encoding
+			}
+		}
+		if normalizeCharset(declared) != normalizeCharset(v.charset) {
+			return fmt.Errorf("document declares encoding %q, but was delivered as %q", declared, v.charset)
+		}
+		return nil
+	}
+}
+
+// declarationAttribute returns the value of a pseudo-attribute of an XML
+// declaration
+func declarationAttribute(inst []byte, name string) (string, bool) {
+	for _, field := range strings.Fields(string(inst)) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) == 2 && parts[0] == name {
+			return strings.Trim(parts[1], `"'`), true
+		}
+	}
+	return "", false
+}
+
+// normalizeCharset makes spellings of the same charset name compare equal
+func normalizeCharset(charset string) string {
+	charset = strings.ToLower(charset)
+	return strings.NewReplacer("-", "", "_", "").Replace(charset)
+}
+
+// TokenKind is a set of kinds of tokens, combined with |
+type TokenKind uint
+
+// Kinds of tokens, matching the token types of encoding/xml
+const (
+	TokenStartElement TokenKind = 1 << iota
+	TokenEndElement
+	TokenCharData
+	TokenComment
+	TokenProcInst
+	TokenDirective
+)
+
+var tokenKindNames = []string{"start element", "end element", "character data", "comment", "processing instruction", "directive"}
+
+func (k TokenKind) String() string {
+	var names []string
+	for i, name := range tokenKindNames {
+		if k&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// tokenKindOf returns the kind of a token
+func tokenKindOf(token xml.Token) TokenKind {
+	switch token.(type) {
+	case xml.StartElement:
+		return TokenStartElement
+	case xml.EndElement:
+		return TokenEndElement
+	case xml.CharData:
+		return TokenCharData
+	case xml.Comment:
+		return TokenComment
+	case xml.ProcInst:
+		return TokenProcInst
+	case xml.Directive:
+		return TokenDirective
+	}
+	return 0
+}
+
+// TokenKindsConfig configures CheckTokenKinds
+type TokenKindsConfig struct {
+	CheckConfig
+	// Allowed holds the kinds of tokens allowed in documents; zero allows
+	// elements and character data only. The XML declaration is allowed
+	// regardless, since CheckXMLDeclaration already covers it.
+	Allowed TokenKind
+}
+
+// WithTokenKindsCheck enables and configures CheckTokenKinds
+func WithTokenKindsCheck(cfg TokenKindsConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckTokenKinds] = cfg.CheckConfig
+		v.tokenKinds = cfg
+	}
+}
+
+// newTokenKindsCheck creates the per-document state of CheckTokenKinds
+func newTokenKindsCheck(v *Validator) tokenCheck {
+	allowed := v.tokenKinds.Allowed
+	if allowed == 0 {
+		allowed = TokenStartElement | TokenEndElement | TokenCharData
+	}
+	return func(d *document, token xml.Token) error {
+		if procInst, ok := token.(xml.ProcInst); ok && procInst.Target == "xml" {
+			return nil
+		}
+		if kind := tokenKindOf(token); kind&allowed == 0 {
+			return fmt.Errorf("%s not allowed, expected %s", kind, allowed)
+		}
+		return nil
+	}
+}
+
+// WithAllowedRoots enables CheckRoot, rejecting documents whose root element
+// isn't one of the given names as soon as it is read, without validating
+// the rest of the document. Names are matched on their namespace URI and
+// local name; like with encoding/xml's XMLName fields, a name without a
+// namespace matches root elements in any namespace.
+func WithAllowedRoots(names ...xml.Name) Option {
+	return func(v *Validator) {
+		v.checks[CheckRoot] = CheckConfig{}
+		v.allowedRoots = names
+	}
+}
+
+// newRootCheck creates the per-document state of CheckRoot
+func newRootCheck(v *Validator) tokenCheck {
+	seen := false
+	return func(d *document, token xml.Token) error {
+		start, ok := token.(xml.StartElement)
+		if !ok || seen {
+			return nil
+		}
+		seen = true
+		name := d.resolveName(start.Name, true)
+		for _, allowed := range v.allowedRoots {
+			if allowed.Local == name.Local && (allowed.Space == "" || allowed.Space == name.Space) {
+				return nil
+			}
+		}
+		d.stop = true
+		if name.Space == "" {
+			return fmt.Errorf("root element %s not allowed", name.Local)
+		}
+		return fmt.Errorf("root element {%s}%s not allowed", name.Space, name.Local)
+	}
+}
+
+// NameCaseConfig configures CheckNameCase
+type NameCaseConfig struct {
+	CheckConfig
+}
+
+// WithNameCaseCheck enables and configures CheckNameCase
+func WithNameCaseCheck(cfg NameCaseConfig) Option {
+	return func(v *Validator) {
+		v.checks[CheckNameCase] = cfg.CheckConfig
+	}
+}
+
+// newNameCaseCheck creates the per-document state of CheckNameCase
+func newNameCaseCheck(v *Validator) tokenCheck {
+	// siblings holds the names of the children of each open element seen
+	// so far, after those of the root elements, keyed by their lower case
+	siblings := []map[string]string{nil}
+	return func(d *document, token xml.Token) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := qualifiedName(t.Name)
+			seen := siblings[len(siblings)-1]
+			if seen == nil {
+				seen = map[string]string{}
+				siblings[len(siblings)-1] = seen
+			}
+			siblings = append(siblings, nil)
+			key := strings.ToLower(name)
+			sibling, ok := seen[key]
+			if !ok {
+				seen[key] = name
+			} else if sibling != name {
+				return fmt.Errorf("element %s has a sibling %s differing only by case", name, sibling)
+			}
+		case xml.EndElement:
+			if len(siblings) > 1 {
+				siblings = siblings[:len(siblings)-1]
+			}
+			if !d.closing {
+				return nil
+			}
+			name, open := qualifiedName(t.Name), qualifiedName(d.path[len(d.path)-1])
+			if name != open && strings.EqualFold(name, open) {
+				return fmt.Errorf("end tag %s closes %s, differing only by case", name, open)
+			}
+		}
+		return nil
+	}
+}
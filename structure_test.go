@@ -0,0 +1,241 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestXMLDeclaration(t *testing.T) {
+	valid := []string{
+		`<?xml version="1.0" encoding="UTF-8"?><Root/>`,
+		"\xef\xbb\xbf<?xml version=\"1.0\"?><Root/>",
+		`<Root><?xml-stylesheet href="style.xsl"?></Root>`,
+	}
+	for _, doc := range valid {
+		require.Empty(t, New().ValidateAll(strings.NewReader(doc)), "Should pass on documents with a single leading XML declaration")
+	}
+
+	invalid := []string{
+		` <?xml version="1.0"?><Root/>`,
+		`<?xml version="1.0"?><Root><?xml version="1.0"?></Root>`,
+		`<Root/><?xml version="1.0"?>`,
+		`<?XML version="1.0"?><Root/>`,
+		`<Root><?Xml version="1.0"?></Root>`,
+	}
+	for _, doc := range invalid {
+		errs := New().ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report misplaced or misspelled XML declarations in %s", doc)
+		require.Equal(t, CheckXMLDeclaration, errs[0].(XMLValidationError).Check, "Finding should be reported by the XML declaration check")
+		require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Misplaced XML declarations should be warnings by default")
+	}
+
+	require.NoError(t, New().Validate(strings.NewReader(invalid[1])), "Warnings shouldn't fail validation")
+	require.Error(t, New(WithFailOn(SeverityWarning)).Validate(strings.NewReader(invalid[1])), "Warnings should fail validation when failing on warnings")
+}
+
+func TestAttributeOrder(t *testing.T) {
+	v := New(WithAttributeOrderCheck(AttributeOrderConfig{}))
+
+	canonical := []string{
+		`<Root/>`,
+		`<Root a="1" b="2"/>`,
+		`<Root xmlns="urn:d" xmlns:a="urn:b" xmlns:b="urn:a" id="1" b:x="2" a:x="3"/>`,
+		`<Root xmlns:z="urn:a" id="1" xml:lang="en" z:b="2"/>`,
+	}
+	for _, doc := range canonical {
+		require.Empty(t, v.ValidateAll(strings.NewReader(doc)), "Should pass on canonically ordered attributes in %s", doc)
+	}
+
+	reordered := map[string]string{
+		`<Root b="1" a="2"/>`:                                     "attribute a would be moved before b by canonicalization",
+		`<Root id="1" xmlns:a="urn:a"/>`:                          "attribute xmlns:a would be moved before id by canonicalization",
+		`<Root xmlns:a="urn:a" xmlns="urn:d"/>`:                   "attribute xmlns would be moved before xmlns:a by canonicalization",
+		`<Root xmlns:a="urn:b" xmlns:b="urn:a" a:x="1" b:x="2"/>`: "attribute b:x would be moved before a:x by canonicalization",
+		`<Root xmlns:a="urn:a" a:x="1" id="2"/>`:                  "attribute id would be moved before a:x by canonicalization",
+	}
+	for doc, message := range reordered {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report attributes out of canonical order in %s", doc)
+		require.Equal(t, CheckAttributeOrder, errs[0].(XMLValidationError).Check, "Finding should be reported by the attribute order check")
+		require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Attribute order findings should be warnings by default")
+		require.Contains(t, errs[0].Error(), message, "Should describe the reordering")
+	}
+
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Root b="1" a="2"/>`)), "Should be disabled by default")
+}
+
+func TestDuplicateAttributes(t *testing.T) {
+	require.NoError(t, New().Validate(strings.NewReader(`<Root a="1" a="2"/>`)), "Should be disabled unless configured")
+
+	v := New(WithDuplicateAttributesCheck(DuplicateAttributesConfig{}))
+	require.Empty(t, v.ValidateAll(strings.NewReader(`<Root xmlns:x="urn:x" xmlns:y="urn:y" a="1" x:a="2" y:a="3" xml:lang="en" lang="fr"><A a="1"/></Root>`)),
+		"Should pass on attributes with distinct resolved names")
+	for doc, message := range map[string]string{
+		`<Root a="1" b="2" a="3"/>`:                                         "attribute a appears more than once",
+		`<Root xmlns:x="urn:a" xmlns:y="urn:a" x:a="1" y:a="2"/>`:           "attributes x:a and y:a both resolve to {urn:a}a",
+		`<Root xmlns:x="urn:a"><A xmlns:y="urn:a" x:a="1" y:a="2"/></Root>`: "attributes x:a and y:a both resolve to {urn:a}a",
+	} {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report duplicate attributes in %s", doc)
+		require.Equal(t, CheckDuplicateAttributes, errs[0].(XMLValidationError).Check)
+		require.Contains(t, errs[0].Error(), message)
+	}
+}
+
+func TestCDATA(t *testing.T) {
+	v := New(WithCDATACheck(CDATAConfig{}))
+	require.Empty(t, v.ValidateAll(strings.NewReader("<Root><![CDATA[plain\ntext]]>a &lt; b</Root>")), "Should pass on CDATA sections that don't need escaping")
+	for _, doc := range []string{
+		`<Root><![CDATA[a < b]]></Root>`,
+		`<Root><![CDATA[a & b]]></Root>`,
+		`<Root><![CDATA["quoted"]]></Root>`,
+		"<Root><![CDATA[a\tb]]></Root>",
+	} {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report CDATA sections needing escaping in %s", doc)
+		require.Equal(t, CheckCDATA, errs[0].(XMLValidationError).Check, "Finding should be reported by the CDATA check")
+		require.Equal(t, int64(6), errs[0].(XMLValidationError).Start, "Should report the CDATA section")
+	}
+
+	v = New(WithCDATACheck(CDATAConfig{RequireLossless: true}))
+	require.Empty(t, v.ValidateAll(strings.NewReader(`<Root><![CDATA[a < b & "c"]]></Root>`)), "Should pass on CDATA sections that convert losslessly")
+	errs := v.ValidateAll(strings.NewReader("<Root><![CDATA[a\r\nb]]></Root>"))
+	require.Len(t, errs, 1, "Should report CDATA sections whose content changes when converted")
+	require.Contains(t, errs[0].Error(), "without loss", "Should describe the problem")
+
+	v = New(WithCDATACheck(CDATAConfig{Disallow: true}))
+	errs = v.ValidateAll(strings.NewReader(`<Root><![CDATA[plain]]>text<A><![CDATA[a < b]]></A></Root>`))
+	require.Len(t, errs, 2, "Should report every CDATA section")
+	require.True(t, errors.Is(errs[0], ErrCodeCDATASection), "Should report a policy error")
+	require.Equal(t, ErrCodeCDATASection, FindingOf(errs[1]).Code)
+	require.Contains(t, errs[1].Error(), "cdata-section not allowed: in element A", "Should tell the element holding the section")
+
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Root><![CDATA[a < b]]></Root>`)), "Should be disabled by default")
+}
+
+func TestCharset(t *testing.T) {
+	for input, charset := range map[string]string{
+		`<Root/>`: "utf-8",
+		`<?xml version="1.0" encoding="UTF-8"?><Root/>`:             "UTF8",
+		"\xef\xbb\xbf<?xml version='1.0' encoding='utf-8'?><Root/>": "utf-8",
+		`<?xml version="1.0" encoding="ISO-8859-1"?><Root/>`:        "iso_8859-1",
+	} {
+		require.NoError(t, New().ExpectCharset(charset).Validate(strings.NewReader(input)), "Should pass %q delivered as %s", input, charset)
+	}
+	for input, charset := range map[string]string{
+		`<Root/>`: "iso-8859-1",
+		`<?xml version="1.0" encoding="ISO-8859-1"?><Root/>`: "utf-8",
+		`<?xml version="1.0"?><Root/>`:                       "us-ascii",
+	} {
+		errs := New().ExpectCharset(charset).ValidateAll(strings.NewReader(input))
+		require.Len(t, errs, 1, "Should report %q delivered as %s", input, charset)
+		require.Equal(t, CheckCharset, errs[0].(XMLValidationError).Check, "Finding should be reported by the charset check")
+		require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Charset mismatches should be warnings")
+	}
+	require.NoError(t, New().Validate(strings.NewReader(`<?xml version="1.0" encoding="ISO-8859-1"?><Root/>`)), "Shouldn't check without an expected charset")
+}
+
+func TestTokenKinds(t *testing.T) {
+	v := New(WithTokenKindsCheck(TokenKindsConfig{}))
+	require.NoError(t, v.Validate(strings.NewReader(`<?xml version="1.0"?><Root a="1">text<![CDATA[data]]><Child/></Root>`)), "Should allow elements and character data by default")
+
+	for doc, message := range map[string]string{
+		`<Root><!-- comment --></Root>`:                  "comment not allowed, expected start element|end element|character data",
+		`<Root><?target data?></Root>`:                   "processing instruction not allowed",
+		`<!DOCTYPE Root><Root/>`:                         "directive not allowed",
+		`<?xml version="1.0"?><?xml-stylesheet?><Root/>`: "processing instruction not allowed",
+	} {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report tokens of kinds not allowed in %s", doc)
+		require.Equal(t, CheckTokenKinds, errs[0].(XMLValidationError).Check, "Finding should be reported by the token kinds check")
+		require.Contains(t, errs[0].Error(), message, "Should describe the token kind")
+	}
+
+	v = New(WithTokenKindsCheck(TokenKindsConfig{Allowed: TokenStartElement | TokenEndElement | TokenComment}))
+	require.NoError(t, v.Validate(strings.NewReader(`<Root><!-- comment --><Child/></Root>`)), "Should allow configured kinds")
+	require.Error(t, v.Validate(strings.NewReader(`<Root>text</Root>`)), "Should only allow configured kinds")
+
+	require.NoError(t, New().Validate(strings.NewReader(`<Root><!-- comment --></Root>`)), "Should be disabled unless configured")
+	require.Equal(t, "none", TokenKind(0).String(), "Should name empty sets of kinds")
+}
+
+func TestAllowedRoots(t *testing.T) {
+	v := New(WithAllowedRoots(xml.Name{Space: "urn:oasis:names:tc:SAML:2.0:protocol", Local: "Response"}, xml.Name{Local: "Envelope"}))
+	for _, doc := range []string{
+		`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol"/>`,
+		`<Response xmlns="urn:oasis:names:tc:SAML:2.0:protocol"><Other/></Response>`,
+		`<?xml version="1.0"?><!-- comment --><Envelope/>`,
+		`<s:Envelope xmlns:s="urn:any"/>`,
+	} {
+		require.NoError(t, v.Validate(strings.NewReader(doc)), "Should pass allowed roots in %s", doc)
+	}
+
+	for doc, message := range map[string]string{
+		`<Response/>`: "root element Response not allowed",
+		`<samlp:Response xmlns:samlp="urn:other"/>`: "root element {urn:other}Response not allowed",
+		`<samlp:Response/>`:                         "root element {samlp}Response not allowed",
+	} {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should reject roots not allowed in %s", doc)
+		require.Equal(t, CheckRoot, errs[0].(XMLValidationError).Check, "Finding should be reported by the root check")
+		require.Contains(t, errs[0].Error(), message, "Should describe the root element")
+	}
+
+	errs := v.ValidateAll(strings.NewReader(`<Other><:Element/>]]></Other><`))
+	require.Len(t, errs, 1, "Should stop validating documents with roots not allowed")
+	require.NoError(t, New().Validate(strings.NewReader(`<Other/>`)), "Should be disabled unless configured")
+}
+
+func TestNameCase(t *testing.T) {
+	v := New(WithNameCaseCheck(NameCaseConfig{}))
+	require.Empty(t, v.ValidateAll(strings.NewReader(`<Root><A/><B><A/></B><A/><a:B xmlns:a="urn:a"/></Root>`)), "Should pass names differing by more than case")
+
+	for doc, message := range map[string]string{
+		`<Root><Assertion></assertion></Root>`:            "end tag assertion closes Assertion, differing only by case",
+		`<Root><Assertion/><Other/><assertion/></Root>`:   "element assertion has a sibling Assertion differing only by case",
+		`<Root><saml:Assertion/><SAML:Assertion/></Root>`: "element SAML:Assertion has a sibling saml:Assertion differing only by case",
+		`<Root><A><B/></A><A><b/></A></Root>`:             "",
+		`<Root/><root/>`:                                  "element root has a sibling Root differing only by case",
+	} {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		if message == "" {
+			require.Empty(t, errs, "Should only compare siblings in %s", doc)
+			continue
+		}
+		require.Len(t, errs, 1, "Should report names differing only by case in %s", doc)
+		require.Equal(t, CheckNameCase, errs[0].(XMLValidationError).Check, "Finding should be reported by the name case check")
+		require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Names differing by case should be warnings by default")
+		require.Contains(t, errs[0].Error(), message, "Should describe the names")
+	}
+
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Root><Assertion></assertion></Root>`)), "Should be disabled unless configured")
+}
+
+func TestTrailingContent(t *testing.T) {
+	v := New(WithTrailingContentCheck(TrailingContentConfig{}))
+	require.Empty(t, v.ValidateAll(strings.NewReader("<?xml version=\"1.0\"?>\n<Root><A/>text</Root>\n<!-- c --><?pi?>\n")),
+		"Should pass whitespace, comments and processing instructions after the root element")
+
+	for doc, message := range map[string]string{
+		`<Root></Root><Root/>`:     "element Root follows the root element",
+		`<Root/>trailing`:          "character data follows the root element",
+		`<Root/> <x:Other/>`:       "element x:Other follows the root element",
+		`<Root/><![CDATA[x]]>`:     "character data follows the root element",
+		"<Root/>\n<Other></Other>": "element Other follows the root element",
+	} {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Len(t, errs, 1, "Should report content after the root element in %s", doc)
+		require.Equal(t, CheckTrailingContent, errs[0].(XMLValidationError).Check)
+		require.Equal(t, SeverityWarning, SeverityOf(errs[0]), "Trailing content should be a warning by default")
+		require.NoError(t, v.Validate(strings.NewReader(doc)), "Warnings shouldn't fail validation")
+		require.Contains(t, errs[0].Error(), message, "Should describe the trailing content")
+	}
+
+	v = New(WithTrailingContentCheck(TrailingContentConfig{CheckConfig: CheckConfig{Severity: SeverityError}}))
+	require.Error(t, v.Validate(strings.NewReader(`<Root/><Root/>`)), "Severity should be configurable")
+	require.Empty(t, New().ValidateAll(strings.NewReader(`<Root/><Root/>`)), "Should be disabled unless configured")
+}
@@ -0,0 +1,137 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Subtree is the exact original byte range of an element and its content
+type Subtree struct {
+	// Path holds the names of the element and its ancestors, starting with
+	// the top-level element, resolved to their namespace URI
+	Path []xml.Name
+	// Start and End are the byte offsets of the start of the element's
+	// start tag and the end of its end tag in the document
+	Start, End int64
+	// Line and Column are the 1-based position of the element's start tag
+	Line, Column int64
+	// Bytes holds the document's bytes from Start to End
+	Bytes []byte
+}
+
+// ExtractSubtrees validates the whole document and returns the subtrees of
+// every element whose path matches the pattern, in document order, e.g.
+// "/samlp:Response/saml:Assertion" for a signed SAML assertion; see
+// CheckConfig.Paths for the pattern syntax. Nothing is returned if the
+// document fails validation, so signature verification can operate on
+// original bytes selected by a validated structural pass. Elements left
+// open by the end of the document extend to its end.
+//
+// The prefixes of the pattern are resolved with namespaces, mapping them
+// to namespace URIs, and elements are matched on their namespace URI and
+// local name, so the prefixes the document's author chooses can't make an
+// element escape extraction, or another one be extracted in its place.
+// Unprefixed names are resolved with the "" entry of namespaces, if any;
+// otherwise, like with WithAllowedRoots, they match elements in any
+// namespace.
+func (v *Validator) ExtractSubtrees(xmlReader io.Reader, pattern string, namespaces map[string]string) ([]Subtree, error) {
+	steps, err := resolvePattern(pattern, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	subtrees := []Subtree{}
+	// open holds the indexes of the subtrees whose end tag hasn't been read
+	var open []int
+	d := v.newDocument(xmlReader)
+	for {
+		start := d.offset
+		token, findings, err := d.next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			d.record(finding.Check, finding.Severity, finding)
+			if v.Fails(finding) {
+				return nil, finding
+			}
+		}
+		switch token.(type) {
+		case xml.StartElement:
+			if matchResolvedSteps(steps, d.names) {
+				line, column := d.position(start)
+				subtrees = append(subtrees, Subtree{
+					Path:   append([]xml.Name(nil), d.names...),
+					Start:  start,
+					Line:   line,
+					Column: column,
+				})
+				open = append(open, len(subtrees)-1)
+			}
+		case xml.EndElement:
+			if len(open) > 0 && len(subtrees[open[len(open)-1]].Path) == len(d.names) {
+				subtrees[open[len(open)-1]].End = d.offset
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	xmlBytes := d.input.consumed()
+	for _, i := range open {
+		subtrees[i].End = int64(len(xmlBytes))
+	}
+	for i := range subtrees {
+		subtrees[i].Bytes = xmlBytes[subtrees[i].Start:subtrees[i].End]
+	}
+	return subtrees, nil
+}
+
+// resolvePattern splits a path pattern into steps whose names are resolved
+// to namespace URIs; descendant steps have an empty local name
+func resolvePattern(pattern string, namespaces map[string]string) ([]xml.Name, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		pattern = "//" + pattern
+	}
+	steps := []xml.Name{}
+	for _, step := range strings.Split(pattern[1:], "/") {
+		name := xml.Name{Local: step}
+		if i := strings.IndexByte(step, ':'); i >= 0 {
+			uri, ok := namespaces[step[:i]]
+			if !ok {
+				return nil, fmt.Errorf("validator: namespace prefix %q of pattern %q isn't bound", step[:i], pattern)
+			}
+			name = xml.Name{Space: uri, Local: step[i+1:]}
+		} else if step != "" {
+			name.Space = namespaces[""]
+		}
+		steps = append(steps, name)
+	}
+	return steps, nil
+}
+
+// matchResolvedSteps is like matchSteps, for resolved names; a step
+// without a namespace matches elements in any namespace
+func matchResolvedSteps(steps []xml.Name, path []xml.Name) bool {
+	if len(steps) == 0 {
+		return len(path) == 0
+	}
+	if steps[0].Local == "" {
+		// descendant step; try skipping any number of elements
+		for i := 0; i <= len(path); i++ {
+			if matchResolvedSteps(steps[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	if steps[0].Local != "*" && (steps[0].Local != path[0].Local || (steps[0].Space != "" && steps[0].Space != path[0].Space)) {
+		return false
+	}
+	return matchResolvedSteps(steps[1:], path[1:])
+}
@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractSubtrees(t *testing.T) {
+	assertion := `<saml:Assertion ID="_1">
+		<saml:Issuer>issuer</saml:Issuer>
+		<saml:Assertion ID="_2"/>
+	</saml:Assertion>`
+	doc := `<samlp:Response xmlns:samlp="urn:p" xmlns:saml="urn:a">
+	` + assertion + `
+</samlp:Response>`
+
+	namespaces := map[string]string{"samlp": "urn:p", "saml": "urn:a"}
+	subtrees, err := New().ExtractSubtrees(strings.NewReader(doc), "/samlp:Response/saml:Assertion", namespaces)
+	require.NoError(t, err, "Should pass on valid documents")
+	require.Len(t, subtrees, 1, "Should only extract elements matching the pattern")
+	require.Equal(t, assertion, string(subtrees[0].Bytes), "Should extract the exact original bytes")
+	require.Equal(t, doc[subtrees[0].Start:subtrees[0].End], string(subtrees[0].Bytes), "Should extract the bytes between Start and End")
+	require.Equal(t, int64(2), subtrees[0].Line, "Should locate the subtree")
+	require.Equal(t, int64(2), subtrees[0].Column, "Should locate the subtree")
+	require.Equal(t, []xml.Name{{Space: "urn:p", Local: "Response"}, {Space: "urn:a", Local: "Assertion"}}, subtrees[0].Path,
+		"Should return the resolved path of the subtree")
+
+	subtrees, err = New().ExtractSubtrees(strings.NewReader(doc), "//saml:Assertion", namespaces)
+	require.NoError(t, err, "Should pass on valid documents")
+	require.Len(t, subtrees, 2, "Should extract nested matches")
+	require.Equal(t, assertion, string(subtrees[0].Bytes), "Should extract the enclosing element first")
+	require.Equal(t, `<saml:Assertion ID="_2"/>`, string(subtrees[1].Bytes), "Should extract empty element tags")
+
+	subtrees, err = New().ExtractSubtrees(strings.NewReader(`<Root><Child>unclosed`), "Child", nil)
+	require.NoError(t, err, "Should pass on valid tokens")
+	require.Equal(t, "<Child>unclosed", string(subtrees[0].Bytes), "Unclosed elements should extend to the end of the document")
+
+	subtrees, err = New().ExtractSubtrees(strings.NewReader(`<Root><Child/>]]></Root>`), "Child", nil)
+	require.Error(t, err, "Should error on invalid documents")
+	require.Nil(t, subtrees, "Shouldn't extract anything from invalid documents")
+
+	_, err = New().ExtractSubtrees(strings.NewReader(doc), "//saml:Assertion", nil)
+	require.Error(t, err, "Should error on patterns with unbound prefixes")
+}
+
+func TestExtractSubtreesReboundPrefixes(t *testing.T) {
+	namespaces := map[string]string{"samlp": "urn:p", "saml": "urn:a"}
+	doc := `<samlp:Response xmlns:samlp="urn:p" xmlns:saml="urn:attacker" xmlns:evil="urn:a">` +
+		`<saml:Assertion ID="_forged"/>` +
+		`<evil:Assertion ID="_signed"/>` +
+		`</samlp:Response>`
+	subtrees, err := New().ExtractSubtrees(strings.NewReader(doc), "/samlp:Response/saml:Assertion", namespaces)
+	require.NoError(t, err, "Should pass on valid documents")
+	require.Len(t, subtrees, 1, "Should match elements on their namespace URI rather than their prefix")
+	require.Equal(t, `<evil:Assertion ID="_signed"/>`, string(subtrees[0].Bytes), "Should extract the element in the namespace of the pattern")
+
+	subtrees, err = New().ExtractSubtrees(strings.NewReader(`<Response xmlns="urn:p"><Assertion xmlns="urn:a"/><Assertion/></Response>`),
+		"/samlp:Response/saml:Assertion", namespaces)
+	require.NoError(t, err, "Should pass on valid documents")
+	require.Len(t, subtrees, 1, "Should match elements in default namespaces")
+	require.Equal(t, `<Assertion xmlns="urn:a"/>`, string(subtrees[0].Bytes))
+
+	subtrees, err = New().ExtractSubtrees(strings.NewReader(`<samlp:Response><saml:Assertion/></samlp:Response>`),
+		"//saml:Assertion", namespaces)
+	require.NoError(t, err, "Should pass on valid documents")
+	require.Empty(t, subtrees, "Shouldn't match elements with undeclared prefixes")
+}
+
+func TestExtractSubtreesSingleLine(t *testing.T) {
+	doc := "<Root>" + strings.Repeat(`<e a="1">text</e>`, 20000) + "</Root>"
+	subtrees, err := New().ExtractSubtrees(strings.NewReader(doc), "/Root/e", nil)
+	require.NoError(t, err)
+	require.Len(t, subtrees, 20000)
+	last := subtrees[len(subtrees)-1]
+	require.Equal(t, []int64{1, last.Start + 1}, []int64{last.Line, last.Column}, "Should locate subtrees on a single line")
+}
@@ -0,0 +1,460 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// StrictTokenizer is a TokenParser reading tokens by the grammar of XML 1.0
+// (Fifth Edition) and Namespaces in XML 1.0, independently of encoding/xml,
+// so its verdicts don't depend on the Go version. CheckDifferential uses it
+// as the reference encoding/xml is compared with unless configured with
+// another parser. It is strict where encoding/xml is lenient: names must
+// be made of NameStartChar and NameChar characters and have at most one
+// colon, separating a non-empty prefix and local name; attribute values
+// must be quoted, unique per element and free of "<"; references must be
+// to character references or predefined entities, since DTDs aren't
+// processed; and only characters allowed by the Char production may
+// appear. Like the XML processors the specification describes, it
+// normalizes line endings, and whitespace in attribute values to spaces.
+type StrictTokenizer struct{}
+
+// strictScanner reads the markup of a single token
+type strictScanner struct {
+	markup []byte
+	pos    int
+}
+
+// ParseToken parses the token at the start of markup
+func (StrictTokenizer) ParseToken(markup []byte) (xml.Token, int, error) {
+	s := &strictScanner{markup: markup}
+	var token xml.Token
+	var err error
+	switch {
+	case len(markup) == 0:
+		return nil, 0, s.errorf("no token")
+	case markup[0] != '<':
+		token, err = s.charData()
+	case bytes.HasPrefix(markup, []byte("<!--")):
+		token, err = s.comment()
+	case bytes.HasPrefix(markup, cdataStart):
+		token, err = s.cdata()
+	case bytes.HasPrefix(markup, []byte("<!")):
+		token, err = s.directive()
+	case bytes.HasPrefix(markup, []byte("<?")):
+		token, err = s.procInst()
+	case bytes.HasPrefix(markup, []byte("</")):
+		token, err = s.endElement()
+	default:
+		token, err = s.startElement()
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return token, s.pos, nil
+}
+
+func (s *strictScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("offset %d: %s", s.pos, fmt.Sprintf(format, args...))
+}
+
+// peek returns the character at the current position without reading it,
+// or -1 at the end of the markup
+func (s *strictScanner) peek() (rune, int, error) {
+	if s.pos >= len(s.markup) {
+		return -1, 0, nil
+	}
+	r, size := utf8.DecodeRune(s.markup[s.pos:])
+	if r == utf8.RuneError && size == 1 {
+		return 0, 0, s.errorf("invalid UTF-8")
+	}
+	if !isXMLChar(r) {
+		return 0, 0, s.errorf("character %U not allowed", r)
+	}
+	return r, size, nil
+}
+
+// next reads the character at the current position, failing at the end of
+// the markup
+func (s *strictScanner) next() (rune, error) {
+	r, size, err := s.peek()
+	if err != nil {
+		return 0, err
+	}
+	if r < 0 {
+		return 0, s.errorf("unexpected end of token")
+	}
+	s.pos += size
+	return r, nil
+}
+
+// expect reads the given literal
+func (s *strictScanner) expect(literal string) error {
+	if !bytes.HasPrefix(s.markup[s.pos:], []byte(literal)) {
+		return s.errorf("expected %q", literal)
+	}
+	s.pos += len(literal)
+	return nil
+}
+
+// space skips whitespace, reporting whether there was any
+func (s *strictScanner) space() bool {
+	start := s.pos
+	for s.pos < len(s.markup) && isXMLSpace(s.markup[s.pos]) {
+		s.pos++
+	}
+	return s.pos > start
+}
+
+// until reads characters up to the given delimiter, which is skipped, and
+// returns them with line endings normalized
+func (s *strictScanner) until(delimiter string) ([]byte, error) {
+	start := s.pos
+	for !bytes.HasPrefix(s.markup[s.pos:], []byte(delimiter)) {
+		if _, err := s.next(); err != nil {
+			return nil, err
+		}
+	}
+	content := normalizeNewlines(s.markup[start:s.pos])
+	s.pos += len(delimiter)
+	return content, nil
+}
+
+// name reads a Name
+func (s *strictScanner) name() (string, error) {
+	start := s.pos
+	r, size, err := s.peek()
+	if err != nil {
+		return "", err
+	}
+	if r < 0 || !isNameStartChar(r) {
+		return "", s.errorf("expected a name")
+	}
+	for r >= 0 && isNameChar(r) {
+		s.pos += size
+		if r, size, err = s.peek(); err != nil {
+			return "", err
+		}
+	}
+	return string(s.markup[start:s.pos]), nil
+}
+
+// qualifiedName reads a QName, split into prefix and local name the way
+// RawToken splits names
+func (s *strictScanner) qualifiedName() (xml.Name, error) {
+	start := s.pos
+	name, err := s.name()
+	if err != nil {
+		return xml.Name{}, err
+	}
+	colon := bytes.IndexByte([]byte(name), ':')
+	if colon < 0 {
+		return xml.Name{Local: name}, nil
+	}
+	if colon == 0 || colon == len(name)-1 || bytes.Count([]byte(name), []byte(":")) > 1 {
+		s.pos = start
+		return xml.Name{}, s.errorf("name %q isn't a qualified name", name)
+	}
+	return xml.Name{Space: name[:colon], Local: name[colon+1:]}, nil
+}
+
+func (s *strictScanner) startElement() (xml.Token, error) {
+	s.pos++
+	name, err := s.qualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	start := xml.StartElement{Name: name, Attr: []xml.Attr{}}
+	seen := map[xml.Name]bool{}
+	for {
+		spaced := s.space()
+		if bytes.HasPrefix(s.markup[s.pos:], []byte("/>")) {
+			s.pos += 2
+			return start, nil
+		}
+		if bytes.HasPrefix(s.markup[s.pos:], []byte(">")) {
+			s.pos++
+			return start, nil
+		}
+		if !spaced {
+			return nil, s.errorf("expected whitespace, \">\" or \"/>\"")
+		}
+		attrName, err := s.qualifiedName()
+		if err != nil {
+			return nil, err
+		}
+		if seen[attrName] {
+			return nil, s.errorf("duplicate attribute %s", qualifiedName(attrName))
+		}
+		seen[attrName] = true
+		s.space()
+		if err := s.expect("="); err != nil {
+			return nil, err
+		}
+		s.space()
+		value, err := s.attrValue()
+		if err != nil {
+			return nil, err
+		}
+		start.Attr = append(start.Attr, xml.Attr{Name: attrName, Value: value})
+	}
+}
+
+// attrValue reads a quoted attribute value, normalizing whitespace
+func (s *strictScanner) attrValue() (string, error) {
+	quote, err := s.next()
+	if err != nil {
+		return "", err
+	}
+	if quote != '"' && quote != '\'' {
+		return "", s.errorf("attribute values must be quoted")
+	}
+	value := &bytes.Buffer{}
+	for {
+		r, err := s.next()
+		switch {
+		case err != nil:
+			return "", err
+		case r == quote:
+			return value.String(), nil
+		case r == '<':
+			return "", s.errorf("\"<\" not allowed in attribute values")
+		case r == '&':
+			if err := s.reference(value); err != nil {
+				return "", err
+			}
+		case r == '\r':
+			if s.pos < len(s.markup) && s.markup[s.pos] == '\n' {
+				s.pos++
+			}
+			value.WriteByte(' ')
+		case r == '\n' || r == '\t':
+			value.WriteByte(' ')
+		default:
+			value.WriteRune(r)
+		}
+	}
+}
+
+// reference reads the rest of a character or entity reference, after its
+// "&", and writes its replacement text
+func (s *strictScanner) reference(out *bytes.Buffer) error {
+	end := bytes.IndexByte(s.markup[s.pos:], ';')
+	if end < 0 {
+		return s.errorf("unterminated reference")
+	}
+	ref := string(s.markup[s.pos : s.pos+end])
+	if len(ref) > 1 && ref[0] == '#' {
+		var code uint64
+		var err error
+		if ref[1] == 'x' {
+			code, err = strconv.ParseUint(ref[2:], 16, 32)
+		} else {
+			code, err = strconv.ParseUint(ref[1:], 10, 32)
+		}
+		if err != nil || !isXMLChar(rune(code)) {
+			return s.errorf("invalid character reference &%s;", ref)
+		}
+		out.WriteRune(rune(code))
+		s.pos += end + 1
+		return nil
+	}
+	replacement, ok := predefinedEntities[ref]
+	if !ok {
+		return s.errorf("undeclared entity &%s;", ref)
+	}
+	out.WriteByte(replacement)
+	s.pos += end + 1
+	return nil
+}
+
+// predefinedEntities maps the entities every XML processor recognizes to
+// their replacement text
+var predefinedEntities = map[string]byte{
+	"lt":   '<',
+	"gt":   '>',
+	"amp":  '&',
+	"apos": '\'',
+	"quot": '"',
+}
+
+func (s *strictScanner) endElement() (xml.Token, error) {
+	s.pos += 2
+	name, err := s.qualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	s.space()
+	if err := s.expect(">"); err != nil {
+		return nil, err
+	}
+	return xml.EndElement{Name: name}, nil
+}
+
+func (s *strictScanner) charData() (xml.Token, error) {
+	data := &bytes.Buffer{}
+	for {
+		r, size, err := s.peek()
+		switch {
+		case err != nil:
+			return nil, err
+		case r < 0 || r == '<':
+			return xml.CharData(data.Bytes()), nil
+		case r == '&':
+			s.pos++
+			if err := s.reference(data); err != nil {
+				return nil, err
+			}
+			continue
+		case r == '>' && bytes.HasSuffix(s.markup[:s.pos], []byte("]]")):
+			return nil, s.errorf("\"]]>\" not allowed in character data")
+		case r == '\r':
+			if s.pos+1 < len(s.markup) && s.markup[s.pos+1] == '\n' {
+				s.pos++
+			}
+			data.WriteByte('\n')
+		default:
+			data.WriteRune(r)
+		}
+		s.pos += size
+	}
+}
+
+func (s *strictScanner) comment() (xml.Token, error) {
+	s.pos += len("<!--")
+	start := s.pos
+	content, err := s.until("--")
+	if err != nil {
+		return nil, err
+	}
+	if err := s.expect(">"); err != nil {
+		s.pos = start
+		return nil, s.errorf("\"--\" not allowed in comments")
+	}
+	if bytes.HasSuffix(content, []byte("-")) {
+		s.pos = start
+		return nil, s.errorf("comments can't end with \"-\"")
+	}
+	return xml.Comment(content), nil
+}
+
+func (s *strictScanner) cdata() (xml.Token, error) {
+	s.pos += len(cdataStart)
+	content, err := s.until("]]>")
+	if err != nil {
+		return nil, err
+	}
+	return xml.CharData(content), nil
+}
+
+func (s *strictScanner) procInst() (xml.Token, error) {
+	s.pos += 2
+	target, err := s.name()
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte([]byte(target), ':') >= 0 {
+		return nil, s.errorf("processing instruction target %q has a colon", target)
+	}
+	if target != "xml" && len(target) == 3 && bytes.EqualFold([]byte(target), []byte("xml")) {
+		return nil, s.errorf("processing instruction target %q is reserved", target)
+	}
+	if !s.space() {
+		if err := s.expect("?>"); err != nil {
+			return nil, err
+		}
+		return xml.ProcInst{Target: target, Inst: []byte{}}, nil
+	}
+	inst, err := s.until("?>")
+	if err != nil {
+		return nil, err
+	}
+	return xml.ProcInst{Target: target, Inst: inst}, nil
+}
+
+// directive reads a document type declaration, the only directive XML
+// allows; its internal subset is delimited, but not otherwise parsed
+func (s *strictScanner) directive() (xml.Token, error) {
+	s.pos += 2
+	if err := s.expect("DOCTYPE"); err != nil {
+		return nil, err
+	}
+	if !s.space() {
+		return nil, s.errorf("expected whitespace")
+	}
+	if _, err := s.qualifiedName(); err != nil {
+		return nil, err
+	}
+	subset := false
+	for {
+		r, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case r == '"' || r == '\'':
+			if _, err := s.until(string(r)); err != nil {
+				return nil, err
+			}
+		case subset && bytes.HasPrefix(s.markup[s.pos-1:], []byte("<!--")):
+			s.pos--
+			if _, err := s.comment(); err != nil {
+				return nil, err
+			}
+		case subset && bytes.HasPrefix(s.markup[s.pos-1:], []byte("<?")):
+			s.pos--
+			if _, err := s.procInst(); err != nil {
+				return nil, err
+			}
+		case r == '[' && !subset:
+			subset = true
+		case r == ']' && subset:
+			subset = false
+		case r == '>' && !subset:
+			return xml.Directive(normalizeNewlines(s.markup[2 : s.pos-1])), nil
+		}
+	}
+}
+
+// normalizeNewlines translates "\r\n" and "\r" into "\n"
+func normalizeNewlines(b []byte) []byte {
+	if bytes.IndexByte(b, '\r') < 0 {
+		return append([]byte{}, b...)
+	}
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(b, []byte("\r"), []byte("\n"))
+}
+
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// isXMLChar reports whether r matches the Char production
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// isNameStartChar reports whether r matches the NameStartChar production
+func isNameStartChar(r rune) bool {
+	return r == ':' || r == '_' ||
+		r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' ||
+		r >= 0xC0 && r <= 0xD6 || r >= 0xD8 && r <= 0xF6 ||
+		r >= 0xF8 && r <= 0x2FF || r >= 0x370 && r <= 0x37D ||
+		r >= 0x37F && r <= 0x1FFF || r >= 0x200C && r <= 0x200D ||
+		r >= 0x2070 && r <= 0x218F || r >= 0x2C00 && r <= 0x2FEF ||
+		r >= 0x3001 && r <= 0xD7FF || r >= 0xF900 && r <= 0xFDCF ||
+		r >= 0xFDF0 && r <= 0xFFFD || r >= 0x10000 && r <= 0xEFFFF
+}
+
+// isNameChar reports whether r matches the NameChar production
+func isNameChar(r rune) bool {
+	return isNameStartChar(r) || r == '-' || r == '.' ||
+		r >= '0' && r <= '9' || r == 0xB7 ||
+		r >= 0x300 && r <= 0x36F || r >= 0x203F && r <= 0x2040
+}
@@ -0,0 +1,91 @@
+package validator
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictTokenizer(t *testing.T) {
+	for _, tc := range []struct {
+		markup string
+		token  xml.Token
+	}{
+		{`<x:Root xmlns:x="urn:x" a = 'b "c"' d="&lt;&#65;&#x42;">`, xml.StartElement{
+			Name: xml.Name{Space: "x", Local: "Root"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Space: "xmlns", Local: "x"}, Value: "urn:x"},
+				{Name: xml.Name{Local: "a"}, Value: `b "c"`},
+				{Name: xml.Name{Local: "d"}, Value: "<AB"},
+			},
+		}},
+		{"<Root a=\"1\r\n2\t3\"/>", xml.StartElement{Name: xml.Name{Local: "Root"}, Attr: []xml.Attr{{Name: xml.Name{Local: "a"}, Value: "1 2 3"}}}},
+		{`<Ünïcödé·1/>`, xml.StartElement{Name: xml.Name{Local: "Ünïcödé·1"}, Attr: []xml.Attr{}}},
+		{`</x:Root >`, xml.EndElement{Name: xml.Name{Space: "x", Local: "Root"}}},
+		{"text\r\n&amp; ]]", xml.CharData("text\n& ]]")},
+		{`<![CDATA[<&]]>`, xml.CharData("<&")},
+		{`<!-- - comment -->`, xml.Comment(" - comment ")},
+		{`<?xml version="1.0"?>`, xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0"`)}},
+		{`<?pi?>`, xml.ProcInst{Target: "pi"}},
+		{`<!DOCTYPE x [ <!ENTITY a "<>"> <!-- ]> --> ]>`, xml.Directive(`DOCTYPE x [ <!ENTITY a "<>"> <!-- ]> --> ]`)},
+	} {
+		token, n, err := StrictTokenizer{}.ParseToken([]byte(tc.markup))
+		require.NoError(t, err, "Should parse %s", tc.markup)
+		require.Equal(t, len(tc.markup), n, "Should parse all of %s", tc.markup)
+		require.True(t, sameToken(tc.token, token), "Should parse %s as %#v, not %#v", tc.markup, tc.token, token)
+	}
+
+	for _, markup := range []string{
+		``,
+		`<1Root>`,
+		`<x::Root>`,
+		`<:Root>`,
+		`<x:>`,
+		`<Root a>`,
+		`<Root a=b>`,
+		`<Root a="1"b="2">`,
+		`<Root a="1" a="2">`,
+		`<Root a="<">`,
+		`<Root a="&custom;">`,
+		`<Root a="&#0;">`,
+		`<Root`,
+		"<Root\x01/>",
+		"text\xff",
+		`text ]]> more`,
+		`text &#xD800;`,
+		`<!-- a -- b -->`,
+		`<!-- a --->`,
+		`<?XML version="1.0"?>`,
+		`<?x:pi?>`,
+		`<?pi?x?>`,
+		`<!ELEMENT x ANY>`,
+		`<!DOCTYPE x [ "unterminated ]>`,
+		`</Root a="1">`,
+	} {
+		_, _, err := StrictTokenizer{}.ParseToken([]byte(markup))
+		require.Error(t, err, "Should reject %q", markup)
+	}
+
+	_, n, err := StrictTokenizer{}.ParseToken([]byte(`<Root>text`))
+	require.NoError(t, err)
+	require.Equal(t, 6, n, "Should only parse the first token")
+}
+
+func TestStrictTokenizerDifferential(t *testing.T) {
+	v := New(WithDifferentialCheck(DifferentialConfig{}))
+	valid := "\ufeff<?xml version=\"1.0\"?>\n<!DOCTYPE Root>\n<x:Root xmlns:x=\"urn:x\" x:a=\"&amp;\"><!--c--><?pi x?>text\r\nmore<![CDATA[<>]]><Empty/></x:Root>\n"
+	require.Empty(t, v.ValidateAll(strings.NewReader(valid)), "Valid documents should read the same with both parsers")
+
+	for _, doc := range []string{
+		`<Root a="1` + "\n" + `2"/>`,
+		`<Root>&custom;</Root>`,
+		`<Root a=1/>`,
+		`<Root><!DOCTYPE x [<!-- y -->]></Root>`,
+	} {
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.NotEmpty(t, errs, "Should report parser differentials in %s", doc)
+		require.Equal(t, CheckDifferential, errs[0].(XMLValidationError).Check)
+	}
+}
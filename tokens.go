@@ -0,0 +1,95 @@
+package validator
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// ValidatedToken is a token that passed validation, along with its position
+// in the document
+type ValidatedToken struct {
+	// Token is the token as returned by xml.Decoder.RawToken
+	Token xml.Token
+	// Resolved is Token with element and attribute names translated into
+	// namespace URIs, as xml.Decoder.Token would return it
+	Resolved xml.Token
+	// Start and End are the byte offsets of the token in the document
+	Start, End int64
+	// Line and Column are the 1-based position of the start of the token
+	Line, Column int64
+	// Base is the effective base URI established by xml:base attributes
+	// in scope of the token, or an empty string if there are none
+	Base string
+}
+
+// Tokens validates the document and returns its full token sequence,
+// returning the first finding that fails validation instead. Feeding the
+// returned tokens to signing or canonicalization code guarantees it
+// operates on exactly the stream that was validated.
+func (v *Validator) Tokens(xmlReader io.Reader) ([]ValidatedToken, error) {
+	tokens := []ValidatedToken{}
+	d := v.newDocument(xmlReader)
+	defer d.release()
+	for {
+		start := d.offset
+		token, findings, err := d.next()
+		if errors.Is(err, io.EOF) {
+			return tokens, nil
+		} else if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			d.record(finding.Check, finding.Severity, finding)
+			if v.Fails(finding) {
+				return nil, finding
+			}
+		}
+		tokens = append(tokens, d.validatedToken(token, start))
+	}
+}
+
+// validatedToken copies the token just read, which started at the given
+// offset, along with its position and context
+func (d *document) validatedToken(token xml.Token, start int64) ValidatedToken {
+	line, column := d.position(start)
+	return ValidatedToken{
+		Token:    xml.CopyToken(token),
+		Resolved: d.resolveToken(token),
+		Start:    start,
+		End:      d.offset,
+		Line:     line,
+		Column:   column,
+		Base:     d.base(),
+	}
+}
+
+// NewValidatingTokenReader returns a token reader passing through the
+// tokens read from r after checking that each of them survives a round trip
+// through encoding/xml, as CheckToken does; tokens may be raw or have their
+// namespaces resolved. The first token failing the check isn't passed
+// through, and its error is returned by every later call. Unlike validating
+// a document, this only applies the roundtrip check, since the other checks
+// need the bytes the tokens were read from.
+func NewValidatingTokenReader(r xml.TokenReader) xml.TokenReader {
+	return &validatingTokenReader{r: r}
+}
+
+type validatingTokenReader struct {
+	r   xml.TokenReader
+	err error
+}
+
+func (tr *validatingTokenReader) Token() (xml.Token, error) {
+	if tr.err != nil {
+		return nil, tr.err
+	}
+	token, err := tr.r.Token()
+	if token != nil {
+		if cerr := CheckToken(token); cerr != nil {
+			tr.err = cerr
+			return nil, cerr
+		}
+	}
+	return token, err
+}
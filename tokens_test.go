@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	xmlString := "<x:Root xmlns:x=\"urn:x\" xmlns=\"urn:default\">\n<Child x:attr=\"1\" attr=\"2\" xml:lang=\"en\">text</Child><y:Other/></x:Root>"
+	tokens, err := New().Tokens(strings.NewReader(xmlString))
+	require.NoError(t, err, "Should tokenize valid XML documents")
+	require.Len(t, tokens, 8, "Should return every token")
+
+	root := tokens[0]
+	require.Equal(t, xml.Name{Space: "x", Local: "Root"}, root.Token.(xml.StartElement).Name, "Raw names should be kept")
+	require.Equal(t, xml.Name{Space: "urn:x", Local: "Root"}, root.Resolved.(xml.StartElement).Name, "Prefixed names should be resolved")
+
+	child := tokens[2]
+	require.Equal(t, int64(2), child.Line, "Token line should be recorded")
+	require.Equal(t, int64(1), child.Column, "Token column should be recorded")
+	require.Equal(t, `<Child x:attr="1" attr="2" xml:lang="en">`, xmlString[child.Start:child.End], "Token offsets should span the token")
+	resolved := child.Resolved.(xml.StartElement)
+	require.Equal(t, xml.Name{Space: "urn:default", Local: "Child"}, resolved.Name, "Unprefixed names should be in the default namespace")
+	require.Equal(t, []xml.Attr{
+		{Name: xml.Name{Space: "urn:x", Local: "attr"}, Value: "1"},
+		{Name: xml.Name{Local: "attr"}, Value: "2"},
+		{Name: xml.Name{Space: xmlURL, Local: "lang"}, Value: "en"},
+	}, resolved.Attr, "Attribute names should be resolved")
+
+	require.Equal(t, xml.CharData("text"), tokens[3].Token, "Character data should be copied")
+	require.Equal(t, xml.Name{Space: "urn:default", Local: "Child"}, tokens[4].Resolved.(xml.EndElement).Name, "End elements should be resolved")
+	require.Equal(t, xml.Name{Space: "y", Local: "Other"}, tokens[5].Resolved.(xml.StartElement).Name, "Unbound prefixes should be kept")
+	require.Equal(t, xml.Name{Space: "urn:x", Local: "Root"}, tokens[7].Resolved.(xml.EndElement).Name, "Scopes should be restored after closing elements")
+
+	_, err = New().Tokens(strings.NewReader(`<Root>]]></Root>`))
+	require.Error(t, err, "Should error on unparseable XML documents")
+}
+
+// sliceTokenReader returns the tokens of a slice
+type sliceTokenReader []xml.Token
+
+func (r *sliceTokenReader) Token() (xml.Token, error) {
+	if len(*r) == 0 {
+		return nil, io.EOF
+	}
+	token := (*r)[0]
+	*r = (*r)[1:]
+	return token, nil
+}
+
+func TestValidatingTokenReader(t *testing.T) {
+	tr := NewValidatingTokenReader(xml.NewDecoder(strings.NewReader(`<Root xmlns="urn:x"><x:Child xmlns:x="urn:y" x:attr="1">text</x:Child></Root>`)))
+	var root struct {
+		Child struct {
+			Attr string `xml:"attr,attr"`
+		} `xml:"Child"`
+	}
+	require.NoError(t, xml.NewTokenDecoder(tr).Decode(&root), "Should pass stable resolved tokens through")
+	require.Equal(t, "1", root.Child.Attr)
+
+	unstable := xml.Directive(`DOCTYPE x [<!-- -->]`)
+	tr = NewValidatingTokenReader(&sliceTokenReader{xml.StartElement{Name: xml.Name{Local: "Root"}}, unstable, xml.EndElement{Name: xml.Name{Local: "Root"}}})
+	token, err := tr.Token()
+	require.NoError(t, err, "Should pass stable raw tokens through")
+	require.Equal(t, xml.StartElement{Name: xml.Name{Local: "Root"}}, token)
+	token, err = tr.Token()
+	require.Error(t, err, "Should fail on unstable tokens")
+	require.Nil(t, token, "Shouldn't pass unstable tokens through")
+	_, err = tr.Token()
+	require.Equal(t, CheckToken(unstable), err, "Should keep returning the error")
+}
+
+func TestTokensSingleLine(t *testing.T) {
+	doc := "<Root>" + strings.Repeat(`<e a="1">text</e>`, 20000) + "</Root>"
+	tokens, err := New().Tokens(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Len(t, tokens, 60002)
+	last := tokens[len(tokens)-1]
+	require.Equal(t, []int64{1, int64(len(doc) - len("</Root>") + 1)}, []int64{last.Line, last.Column}, "Should locate tokens on a single line")
+}
@@ -2,10 +2,12 @@ package validator
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 )
 
 // XMLRoundtripError is returned when a round-trip token doesn't match the original
@@ -21,10 +23,35 @@ func (err XMLRoundtripError) Error() string {
 	return fmt.Sprintf("roundtrip error: unexpected overflow after token: %s", err.Overflow)
 }
 
-// XMLValidationError is returned when validating an XML document fails
+// XMLValidationError is returned when validating an XML document fails,
+// by Validate as well as ValidateAll
 type XMLValidationError struct {
+	// Start and End are the byte offsets of the offending token in the
+	// document, so document[Start:End] holds its raw bytes; Line and
+	// Column are the 1-based position of Start, and EndLine and EndColumn
+	// that of End, just past the token
 	Start, End, Line, Column int64
-	err                      error
+	EndLine, EndColumn       int64
+	// Path locates the element containing the offending token, or closed or
+	// opened by it, as an XPath expression with the position of every
+	// element among its siblings of the same name, e.g.
+	// "/samlp:Response[1]/saml:Assertion[1]/ds:Signature[1]"; names are
+	// written with their namespace prefix, as in the document
+	Path string
+	// Namespaces holds the prefix to URI bindings in scope at the offending
+	// token of round trip mismatches, including those declared by the token
+	// itself, with the default namespace bound to the empty prefix; it
+	// tells which namespaces rebound prefixes and colons in names resolve to
+	Namespaces map[string]string
+	Severity   Severity
+	Check      CheckID
+	// Snippet holds the offending token with its surrounding text if
+	// enabled with WithSnippets
+	Snippet string
+	// elements holds the names of the elements in Path, resolved to their
+	// namespace URI
+	elements []xml.Name
+	err      error
 }
 
 func (err XMLValidationError) Error() string {
@@ -35,94 +62,164 @@ func (err XMLValidationError) Unwrap() error {
 	return err.err
 }
 
-// Validate makes sure the given XML bytes survive round trips through encoding/xml without mutations
-func Validate(xmlReader io.Reader) error {
-	xmlBuffer := &bytes.Buffer{}
-	xmlReader = &byteReader{io.TeeReader(xmlReader, xmlBuffer)}
-	decoder := xml.NewDecoder(xmlReader)
-	decoder.Strict = false
-	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
-	offset := int64(0)
-	for {
-		token, err := decoder.RawToken()
-		if errors.Is(err, io.EOF) {
-			return nil
-		} else if err != nil {
-			return err
-		}
-		if err := CheckToken(token); err != nil {
-			xmlBytes := xmlBuffer.Bytes()
-			line := bytes.Count(xmlBytes[0:offset], []byte{'\n'}) + 1
-			lineStart := int64(bytes.LastIndexByte(xmlBytes[0:offset], '\n')) + 1
-			column := offset - lineStart + 1
-			return XMLValidationError{
-				Start:  offset,
-				End:    decoder.InputOffset(),
-				Line:   int64(line),
-				Column: column,
-				err:    err,
-			}
-		}
-		offset = decoder.InputOffset()
+// IOError is returned when reading a document fails, such as on network
+// resets or corrupt compressed input, as opposed to the document being
+// invalid; retrying may succeed
+type IOError struct {
+	Err error
+}
+
+func (err IOError) Error() string {
+	return fmt.Sprintf("validator: reading document: %s", err.Err.Error())
+}
+
+func (err IOError) Unwrap() error {
+	return err.Err
+}
+
+// readError wraps errors returned while reading a document in an IOError,
+// unless they are syntax errors, size limit errors or io.EOF
+func readError(err error) error {
+	syntaxError := &xml.SyntaxError{}
+	ioError := IOError{}
+	sizeError := &SizeLimitError{}
+	if err == nil || errors.Is(err, io.EOF) || errors.As(err, &syntaxError) || errors.As(err, &ioError) || errors.As(err, &sizeError) {
+		return err
 	}
+	return IOError{err}
+}
+
+// readAll reads a whole document, returning an IOError if that fails
+func readAll(xmlReader io.Reader) ([]byte, error) {
+	xmlBytes, err := ioutil.ReadAll(xmlReader)
+	return xmlBytes, readError(err)
+}
+
+// Validate makes sure the given XML bytes survive round trips through
+// encoding/xml without mutations. It validates the document with Default,
+// returning the first finding failing validation.
+func Validate(xmlReader io.Reader) error {
+	return Default().Validate(xmlReader)
+}
+
+// ValidateBytes is like Validate, but validates an in-memory document
+// without copying it
+func ValidateBytes(xmlBytes []byte) error {
+	return Default().ValidateBytes(xmlBytes)
 }
 
 // ValidateAll is like Validate, but instead of returning after the first error,
-// it accumulates errors and validates the entire document
+// it accumulates errors and validates the entire document; findings that
+// don't fail validation with Default are left out
 func ValidateAll(xmlReader io.Reader) []error {
-	xmlBuffer := &bytes.Buffer{}
-	xmlReader = io.TeeReader(xmlReader, xmlBuffer)
-	errs := []error{}
-	offset := int64(0)
-	line := int64(1)
-	column := int64(1)
-	for {
-		err := Validate(xmlReader)
-		if err == nil {
-			// reached the end with no additional errors
-			break
+	return failing(Default(), Default().ValidateAll(xmlReader))
+}
+
+// ValidateAllBytes is like ValidateAll, but validates an in-memory document
+// without copying it
+func ValidateAllBytes(xmlBytes []byte) []error {
+	return failing(Default(), Default().ValidateAllBytes(xmlBytes))
+}
+
+// ValidateAllDetailed is like Validator.ValidateAllDetailed, leaving out
+// findings that don't fail validation with Default
+func ValidateAllDetailed(xmlReader io.Reader) ([]XMLValidationError, error) {
+	v := Default()
+	findings, err := v.ValidateAllDetailed(xmlReader)
+	errs := []XMLValidationError{}
+	for _, finding := range findings {
+		if v.Fails(finding) {
+			errs = append(errs, finding)
 		}
-		validationError := XMLValidationError{}
-		if errors.As(err, &validationError) {
-			// validation errors contain line numbers and offsets, but
-			// these offsets are based on the offset where Validate
-			// was called, so they need to be adjusted to accordingly
-			validationError.Start += offset
-			validationError.End += offset
-			if validationError.Line == 1 {
-				validationError.Column += column - 1
-			}
-			validationError.Line += line - 1
-			errs = append(errs, validationError)
-			xmlBytes := xmlBuffer.Bytes()
-			offset += int64(len(xmlBytes))
-			newLines := int64(bytes.Count(xmlBytes, []byte("\n")))
-			line += newLines
-			if newLines > 0 {
-				column = int64(len(xmlBytes) - bytes.LastIndex(xmlBytes, []byte("\n")))
-			} else {
-				column += int64(len(xmlBytes))
-			}
-			xmlBuffer.Reset()
-		} else {
-			// this was not a validation error, but likely
-			// completely unparseable XML instead; no point
-			// in trying to continue
+	}
+	return errs, err
+}
+
+// ValidateAllFunc is like ValidateAll, but passes every error to fn as soon
+// as it is found, and stops validating once fn returns false
+func ValidateAllFunc(xmlReader io.Reader, fn func(error) bool) {
+	v := Default()
+	v.ValidateAllFunc(xmlReader, func(err error) bool {
+		return !v.Fails(err) || fn(err)
+	})
+}
+
+// ValidateContext is like Validate, but stops validating and returns the
+// context's error once the context is done
+func ValidateContext(ctx context.Context, xmlReader io.Reader) error {
+	return Default().ValidateContext(ctx, xmlReader)
+}
+
+// ValidateAllContext is like ValidateAll, but stops validating once the
+// context is done, returning the errors so far followed by the context's error
+func ValidateAllContext(ctx context.Context, xmlReader io.Reader) []error {
+	return failing(Default(), Default().ValidateAllContext(ctx, xmlReader))
+}
+
+// failing returns the findings that fail validation with v
+func failing(v *Validator, findings []error) []error {
+	errs := []error{}
+	for _, err := range findings {
+		if v.Fails(err) {
 			errs = append(errs, err)
-			break
 		}
 	}
 	return errs
 }
 
+// position computes the 1-based line and column of a byte offset
+func position(xmlBytes []byte, offset int64) (line, column int64) {
+	line = int64(bytes.Count(xmlBytes[0:offset], []byte{'\n'})) + 1
+	lineStart := int64(bytes.LastIndexByte(xmlBytes[0:offset], '\n')) + 1
+	return line, offset - lineStart + 1
+}
+
+// lineTracker locates offsets in a document read front to back; it is
+// advanced to the start of every token, so locating offsets from there on
+// only scans the bytes of the token rather than the whole document
+type lineTracker struct {
+	// offset is the offset the tracker was advanced to, lines the number
+	// of line feeds before it, and lineStart the offset of the line it is on
+	offset, lines, lineStart int64
+}
+
+// advance moves the tracker forward to offset
+func (t *lineTracker) advance(xmlBytes []byte, offset int64) {
+	if offset <= t.offset {
+		return
+	}
+	chunk := xmlBytes[t.offset:offset]
+	if n := bytes.Count(chunk, []byte{'\n'}); n > 0 {
+		t.lines += int64(n)
+		t.lineStart = t.offset + int64(bytes.LastIndexByte(chunk, '\n')) + 1
+	}
+	t.offset = offset
+}
+
+// position is like the package function, scanning the whole document only
+// for offsets before the one the tracker was advanced to
+func (t *lineTracker) position(xmlBytes []byte, offset int64) (line, column int64) {
+	if offset < t.offset {
+		return position(xmlBytes, offset)
+	}
+	chunk := xmlBytes[t.offset:offset]
+	line = t.lines + int64(bytes.Count(chunk, []byte{'\n'})) + 1
+	lineStart := t.lineStart
+	if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+		lineStart = t.offset + int64(i) + 1
+	}
+	return line, offset - lineStart + 1
+}
+
 // bufio implements a ByteReader but we explicitly don't want any buffering
 type byteReader struct {
 	r io.Reader
+	// scratch is read into, since a local array would escape to the heap
+	scratch [1]byte
 }
 
 func (r *byteReader) ReadByte() (byte, error) {
-	var p [1]byte
-	n, err := r.r.Read(p[:])
+	n, err := r.r.Read(r.scratch[:])
 
 	// The doc for the io.ByteReader interface states:
 	//   If ReadByte returns an error, no input byte was consumed, and the returned byte value is undefined.
@@ -131,7 +228,7 @@ func (r *byteReader) ReadByte() (byte, error) {
 		// this byteReader is only used in the context of the Validate() function,
 		// we deliberately choose to completely ignore the error in this case.
 		// return the byte extracted from the reader
-		return p[0], nil
+		return r.scratch[0], nil
 	}
 
 	return 0, err
@@ -141,27 +238,113 @@ func (r *byteReader) Read(p []byte) (int, error) {
 	return r.r.Read(p)
 }
 
-// CheckToken computes a round trip for a given xml.Token and returns an
-// error if the newly calculated token differs from the original
-func CheckToken(before xml.Token) error {
-	buffer := &bytes.Buffer{}
-	encoder := xml.NewEncoder(buffer)
+// documentInput is the input of a validation run, which records the bytes
+// consumed by the decoder so findings can be located in them
+type documentInput interface {
+	io.ByteReader
+	io.Reader
+	consumed() []byte
+}
 
-	switch t := before.(type) { // nolint:gocritic
-	case xml.EndElement:
-		// xml.Encoder expects matching StartElements for all EndElements
-		if err := encoder.EncodeToken(xml.StartElement{Name: t.Name}); err != nil {
-			return err
+// bufferedInput copies every byte read from a reader into a pooled buffer
+type bufferedInput struct {
+	byteReader
+	buffer *bytes.Buffer
+}
+
+func newBufferedInput(r io.Reader) *bufferedInput {
+	in := &bufferedInput{buffer: getBuffer()}
+	in.r = io.TeeReader(r, in.buffer)
+	return in
+}
+
+func (in *bufferedInput) consumed() []byte {
+	return in.buffer.Bytes()
+}
+
+// release returns the buffer to the pool once nothing refers to the
+// consumed bytes anymore
+func (in *bufferedInput) release() {
+	putBuffer(in.buffer)
+	in.buffer = nil
+}
+
+// sliceReader reads an in-memory document, which already holds every byte
+// consumed, without copying it
+type sliceReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *sliceReader) ReadByte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.EOF
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+func (r *sliceReader) Read(p []byte) (int, error) {
+	if r.pos >= len(r.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.data[r.pos:])
+	r.pos += n
+	return n, nil
+}
+
+func (r *sliceReader) consumed() []byte {
+	return r.data[:r.pos]
+}
+
+// SerializeToken renders a token exactly as CheckToken encodes it for the
+// roundtrip comparison, to reproduce the validator's view of a mismatching
+// token; errors are those CheckToken returns for tokens xml.Encoder
+// refuses to encode
+func SerializeToken(t xml.Token) ([]byte, error) {
+	encoded, start, err := encodeToken(t, newTokenEncoder())
+	if err != nil {
+		return nil, err
+	}
+	return encoded[start:], nil
+}
+
+// encodeToken encodes a token with an unused encoder, returning the
+// encoded bytes and the offset of the token in them: end elements are
+// preceded by a matching start element, which xml.Encoder expects
+func encodeToken(t xml.Token, encoder *tokenEncoder) ([]byte, int, error) {
+	if end, ok := t.(xml.EndElement); ok {
+		if err := encoder.EncodeToken(xml.StartElement{Name: end.Name}); err != nil {
+			return nil, 0, err
+		}
+		if err := encoder.Flush(); err != nil {
+			return nil, 0, err
 		}
 	}
+	start := encoder.buffer.Len()
 
-	if err := encoder.EncodeToken(before); err != nil {
-		return err
+	if err := encoder.EncodeToken(t); err != nil {
+		return nil, 0, err
 	}
 	if err := encoder.Flush(); err != nil {
+		return nil, 0, err
+	}
+	return encoder.buffer.Bytes(), start, nil
+}
+
+// CheckToken computes a round trip for a given xml.Token and returns an
+// error if the newly calculated token differs from the original
+func CheckToken(before xml.Token) error {
+	encoder := getEncoder()
+	var open *xml.StartElement
+	if start, ok := before.(xml.StartElement); ok {
+		open = &start
+	}
+	defer putEncoder(encoder, open)
+	encoded, _, err := encodeToken(before, encoder)
+	if err != nil {
 		return err
 	}
-	encoded := buffer.Bytes()
 	decoder := xml.NewDecoder(bytes.NewReader(encoded))
 	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
 
@@ -183,7 +366,7 @@ func CheckToken(before xml.Token) error {
 	offset := decoder.InputOffset()
 	if offset != int64(len(encoded)) {
 		// this is likely unreachable, but just in case
-		return XMLRoundtripError{before, after, encoded[offset:]}
+		return XMLRoundtripError{before, after, append([]byte(nil), encoded[offset:]...)}
 	}
 	return nil
 }
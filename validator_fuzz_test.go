@@ -0,0 +1,96 @@
+//go:build go1.18
+// +build go1.18
+
+package validator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"testing"
+)
+
+// FuzzValidate checks the invariant the validator exists to guarantee: if a
+// document passes validation, then re-encoding its tokens with encoding/xml
+// and parsing the result yields an equivalent token stream
+func FuzzValidate(f *testing.F) {
+	seeds := []string{
+		`<Root></Root>`,
+		`<x:Root xmlns:x="http://example.com/"></x:Root>`,
+		`<Root xmlns="http://example.com/1" x:attr="y"/>`,
+		`<x:Root xmlns="http://example.com/1" x:attr="y" x:attr2="z"/>`,
+		`<?xml version="1.0" encoding="EUC-JP"?><Root></Root>`,
+		`<Root>text &quot;hello&quot;</Root>`,
+		`<Root><![CDATA[text "hello"]]></Root>`,
+		`<!-- comment --><Root/>`,
+		`<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`,
+		`<Root><! <<!-- -->!-- x --> y></Root>`,
+		`<Root :="value"/>`,
+		`<x:>`,
+		`<Root><x::Element></::Element></Root>`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if err := Validate(bytes.NewReader(input)); err != nil {
+			return
+		}
+		before, err := rawTokens(input)
+		if err != nil {
+			t.Fatalf("validated document failed to parse: %v", err)
+		}
+
+		encoded := &bytes.Buffer{}
+		encoder := xml.NewEncoder(encoded)
+		for _, token := range before {
+			if err := encoder.EncodeToken(token); err != nil {
+				// encoding/xml refuses to encode some tokens, such as
+				// mismatched end elements; nothing can mutate then
+				return
+			}
+		}
+		if err := encoder.Flush(); err != nil {
+			return
+		}
+
+		after, err := rawTokens(encoded.Bytes())
+		if err != nil {
+			t.Fatalf("re-encoded document %q failed to parse: %v", encoded.Bytes(), err)
+		}
+		if len(before) != len(after) {
+			t.Fatalf("re-encoding %q changed the number of tokens from %d to %d: %q", input, len(before), len(after), encoded.Bytes())
+		}
+		for i := range before {
+			if !tokenEquals(before[i], after[i]) {
+				t.Fatalf("re-encoding %q mutated token %d from %#v to %#v", input, i, before[i], after[i])
+			}
+		}
+	})
+}
+
+func rawTokens(input []byte) ([]xml.Token, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(input))
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	tokens := []xml.Token{}
+	for {
+		token, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			return tokens, nil
+		} else if err != nil {
+			return nil, err
+		}
+		// adjacent runs of character data, e.g. text followed by a CDATA
+		// section, are merged when re-encoded
+		if data, ok := token.(xml.CharData); ok && len(tokens) > 0 {
+			if previous, ok := tokens[len(tokens)-1].(xml.CharData); ok {
+				tokens[len(tokens)-1] = append(previous, data...)
+				continue
+			}
+		}
+		tokens = append(tokens, xml.CopyToken(token))
+	}
+}
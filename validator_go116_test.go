@@ -13,6 +13,13 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// rejectsColons is set since names with several colons fail validation
+// with roundtrip errors
+const rejectsColons = true
+
+// expectedSemantics is the tokenization semantics StdlibSemantics should probe
+const expectedSemantics = SemanticsGo116
+
 func TestColonsInLocalNames(t *testing.T) {
 	var err error
 
@@ -11,6 +11,13 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// rejectsColons is unset since names with several colons are tokenized
+// as local names and pass validation
+const rejectsColons = false
+
+// expectedSemantics is the tokenization semantics StdlibSemantics should probe
+const expectedSemantics = SemanticsGo117
+
 func TestColonsInLocalNames(t *testing.T) {
 	var err error
 
@@ -11,6 +11,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// rejectsColons is set since names with several colons are syntax errors
+const rejectsColons = true
+
+// expectedSemantics is the tokenization semantics StdlibSemantics should probe
+const expectedSemantics = SemanticsGo120
+
 func TestEmptyNames(t *testing.T) {
 	var err error
 
@@ -3,8 +3,10 @@ package validator
 import (
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"testing"
 
@@ -30,9 +32,100 @@ func TestValidXML(t *testing.T) {
 
 	for _, doc := range docs {
 		require.NoError(t, Validate(bytes.NewBufferString(doc)), "Should pass on valid XML documents")
+		require.NoError(t, ValidateBytes([]byte(doc)), "Should pass on valid in-memory XML documents")
 	}
 }
 
+func TestValidateBytes(t *testing.T) {
+	docs := []string{
+		`<Root></Root>`,
+		`<Root>]]></Root>`,
+		`<Root xmlns:x="http://example.com/"><!DOCTYPE x [<!ENTITY y "z">]>` + "\n" + `<x:Element/></Root>`,
+		"<Root>\n\t<:Element/>\n\t<Element :attr=\"z\"/>\n</Root>",
+	}
+	v := New()
+	for _, doc := range docs {
+		require.Equal(t, Validate(strings.NewReader(doc)), ValidateBytes([]byte(doc)), "Should validate %q like Validate", doc)
+		require.Equal(t, ValidateAll(strings.NewReader(doc)), ValidateAllBytes([]byte(doc)), "Should validate %q like ValidateAll", doc)
+		require.Equal(t, v.Validate(strings.NewReader(doc)), v.ValidateBytes([]byte(doc)), "Should validate %q like Validator.Validate", doc)
+		require.Equal(t, v.ValidateAll(strings.NewReader(doc)), v.ValidateAllBytes([]byte(doc)), "Should validate %q like Validator.ValidateAll", doc)
+	}
+}
+
+func TestValidateAllFunc(t *testing.T) {
+	registerTestCheck(t, "test-a", "a", SeverityError, onStartElements)
+	registerTestCheck(t, "test-b", "b", SeverityWarning, onStartElements)
+	doc := `<Root><Child/><Child/></Root>`
+	var errs []error
+	ValidateAllFunc(strings.NewReader(doc), func(err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	require.Equal(t, ValidateAll(strings.NewReader(doc)), errs, "Should pass the errors ValidateAll returns")
+
+	errs = nil
+	New().ValidateAllFunc(strings.NewReader(doc), func(err error) bool {
+		errs = append(errs, err)
+		return len(errs) < 2
+	})
+	require.Len(t, errs, 2, "Should stop once the callback returns false")
+
+	errs = nil
+	New().ValidateAllFunc(strings.NewReader(doc+"<"), func(err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	require.Len(t, errs, 7, "Should pass every finding")
+	syntaxError := &xml.SyntaxError{}
+	require.True(t, errors.As(errs[6], &syntaxError), "Should pass syntax errors last")
+}
+
+func TestValidateAllDetailed(t *testing.T) {
+	registerTestCheck(t, "test-a", "a", SeverityError, onStartElements)
+	registerTestCheck(t, "test-b", "b", SeverityWarning, onStartElements)
+	doc := "<Root>\n  <Child/>\n  <Child><"
+
+	findings, err := New().ValidateAllDetailed(strings.NewReader(doc))
+	require.NoError(t, err, "Syntax errors should be returned as findings")
+	errs := New().ValidateAll(strings.NewReader(doc))
+	require.Len(t, findings, len(errs), "Should return as many findings as ValidateAll")
+	for i, finding := range findings[:len(findings)-1] {
+		require.Equal(t, errs[i], finding, "Should return the findings ValidateAll returns")
+	}
+	syntax := findings[len(findings)-1]
+	require.Equal(t, CheckSyntax, syntax.Check, "Should return syntax errors as findings")
+	require.Equal(t, int64(27), syntax.Start, "Should locate syntax errors at the token they were found in")
+	require.Equal(t, [2]int64{3, 10}, [2]int64{syntax.Line, syntax.Column}, "Should locate syntax errors at the token they were found in")
+	syntaxError := &xml.SyntaxError{}
+	require.True(t, errors.As(syntax, &syntaxError), "Should wrap syntax errors")
+
+	findings, err = ValidateAllDetailed(strings.NewReader(doc))
+	require.NoError(t, err, "Syntax errors should be returned as findings")
+	require.Len(t, findings, 4, "Should only return findings failing validation with Default")
+}
+
+func TestValidateOffsets(t *testing.T) {
+	v := New(WithUndeclaredPrefixCheck(UndeclaredPrefixConfig{}), WithCDATACheck(CDATAConfig{CheckConfig: CheckConfig{Severity: SeverityError}}))
+	for doc, raw := range map[string]string{
+		"<Root>\n  <x:Child a=\"1\"/></Root>":               `<x:Child a="1"/>`,
+		"<Root><?xml version=\"1.0\"?><![CDATA[<]]></Root>": `<![CDATA[<]]>`,
+		`<Root><Child/><Child y:a="1"></Child></Root>`:      `<Child y:a="1">`,
+	} {
+		err := v.Validate(strings.NewReader(doc))
+		require.Error(t, err, "Should fail on %s", doc)
+		validationError := err.(XMLValidationError)
+		require.Equal(t, raw, doc[validationError.Start:validationError.End], "Validate should report the byte range of the offending token in %s", doc)
+		errs := v.ValidateAll(strings.NewReader(doc))
+		require.Contains(t, errs, err, "Validate should report the same range as ValidateAll in %s", doc)
+	}
+
+	doc := "<Root>\n  <![CDATA[a\n<b]]>\n</Root>"
+	err := v.Validate(strings.NewReader(doc))
+	require.Error(t, err, "Should fail on CDATA sections spanning lines")
+	validationError := err.(XMLValidationError)
+	require.Equal(t, [4]int64{2, 3, 3, 6}, [4]int64{validationError.Line, validationError.Column, validationError.EndLine, validationError.EndColumn}, "Should locate the start and end of findings")
+}
+
 func TestUnparseableXML(t *testing.T) {
 	var err error
 
@@ -62,6 +155,28 @@ func TestUnparseableXML(t *testing.T) {
 	}
 }
 
+func TestDefault(t *testing.T) {
+	previous := Default()
+	defer SetDefault(previous)
+
+	warning := `<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd"></Root>`
+	require.NoError(t, Validate(strings.NewReader(warning)), "Warnings shouldn't fail validation by default")
+	require.Empty(t, ValidateAll(strings.NewReader(warning)), "Warnings shouldn't be reported by default")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			Validate(strings.NewReader(warning))
+		}
+	}()
+	SetDefault(New(WithFailOn(SeverityWarning)))
+	<-done
+	require.Error(t, Validate(strings.NewReader(warning)), "Should validate with the configured default")
+	require.Len(t, ValidateAll(strings.NewReader(warning)), 1, "Should report findings failing the configured default")
+	require.Error(t, ValidateBytes([]byte(warning)), "Should validate in-memory documents with the configured default")
+}
+
 func TestTokenEquals(t *testing.T) {
 	tokens := []xml.Token{
 		tokenize(t, `token`),
@@ -86,9 +201,27 @@ func TestTokenEquals(t *testing.T) {
 	require.False(t, tokenEquals(nonToken, nonToken), "Non-token types should never equal")
 }
 
+func TestSerializeToken(t *testing.T) {
+	for token, expected := range map[string]string{
+		// xml.Encoder treats raw prefixes as namespace URIs
+		`<x:Root xmlns:x="urn:x" attr="&quot;">`: `<Root xmlns="x" xmlns:_xmlns="xmlns" _xmlns:x="urn:x" attr="&#34;">`,
+		`</x:Root>`:                              `</Root>`,
+		`text "hello"`:                           `text &#34;hello&#34;`,
+		`<!--comment-->`:                         `<!--comment-->`,
+		`<?target inst?>`:                        `<?target inst?>`,
+		`<!DOCTYPE x>`:                           `<!DOCTYPE x>`,
+	} {
+		serialized, err := SerializeToken(tokenize(t, token))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(serialized), "Should serialize %q like the roundtrip check", token)
+	}
+	_, err := SerializeToken(xml.ProcInst{Target: "x", Inst: []byte("?>")})
+	require.Equal(t, CheckToken(xml.ProcInst{Target: "x", Inst: []byte("?>")}), err, "Should fail like the roundtrip check")
+}
+
 func TestErrorMessages(t *testing.T) {
 	require.Equal(t, "validator: in token starting at 2:16: unexpected EOF",
-		XMLValidationError{34, 54, 2, 16, io.ErrUnexpectedEOF}.Error(),
+		XMLValidationError{Start: 34, End: 54, Line: 2, Column: 16, err: io.ErrUnexpectedEOF}.Error(),
 		"Validation error message should match expectation")
 
 	require.Equal(t, "roundtrip error: expected {{ Foo} []}, observed {{ Bar} []}",
@@ -102,16 +235,107 @@ func TestErrorMessages(t *testing.T) {
 
 var errSink []error
 
+const responseXML = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:dsig="http://www.w3.org/2000/09/xmldsig#" xmlns:enc="http://www.w3.org/2001/04/xmlenc#" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:x500="urn:oasis:names:tc:SAML:2.0:profiles:attribute:X500" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" Destination="http://127.0.0.1:5556/callback" ID="id-IWlPTptSB-PlR80dwt8ZhVeG70mrz7nPvTVrhduK" InResponseTo="_e66b3a98-831c-4c96-5706-b63fe0549624" IssueInstant="2016-12-12T16:54:35Z" Version="2.0"><saml:Issuer Format="urn:oasis:names:tc:SAML:2.0:nameid-format:entity">https://deaoam-dev02.jpl.nasa.gov:14101/oam/fed</saml:Issuer><samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status><saml:Assertion ID="id-rT9rTqxdQC9j34YhVeNayUWC9EbIBgym6gp-MZt-" IssueInstant="2016-12-12T16:54:35Z" Version="2.0"><saml:Issuer Format="urn:oasis:names:tc:SAML:2.0:nameid-format:entity">https://deaoam-dev02.jpl.nasa.gov:14101/oam/fed</saml:Issuer><dsig:Signature><dsig:SignedInfo><dsig:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/><dsig:SignatureMethod Algorithm="http://www.w3.org/2000/09/xmldsig#rsa-sha1"/><dsig:Reference URI="#id-rT9rTqxdQC9j34YhVeNayUWC9EbIBgym6gp-MZt-"><dsig:Transforms><dsig:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/><dsig:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/></dsig:Transforms><dsig:DigestMethod Algorithm="http://www.w3.org/2000/09/xmldsig#sha1"/><dsig:DigestValue>z1HD/59hv6UOd5+jeG+ihaFWLgI=</dsig:DigestValue></dsig:Reference></dsig:SignedInfo><dsig:SignatureValue>I99oG5kiOfIgbXYa21z/TOmzftTkFnXe9ObhBNSKit9kAhT93apYROqqXv4Ax96P144Ld7ERX1hgJsytK8LC2874Pk7QrSNm4zvW3x0D4GR4lM06CvJK/EhIur3TrCUJDPigvyP7TJitheCyBejwt0x0lqNP/OzR3tMbAIMRoho=</dsig:SignatureValue></dsig:Signature><saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:2.0:nameid-format:persistent" NameQualifier="https://deaoam-dev02.jpl.nasa.gov:14101/oam/fed" SPNameQualifier="JSAuth">pkieu</saml:NameID><saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer"><saml:SubjectConfirmationData InResponseTo="_e66b3a98-831c-4c96-5706-b63fe0549624" NotOnOrAfter="2016-12-12T16:59:35Z" Recipient="http://127.0.0.1:5556/callback"/></saml:SubjectConfirmation></saml:Subject><saml:Conditions NotBefore="2016-12-12T16:54:35Z" NotOnOrAfter="2016-12-12T16:59:35Z"><saml:AudienceRestriction><saml:Audience>JSAuth</saml:Audience></saml:AudienceRestriction></saml:Conditions><saml:AuthnStatement AuthnInstant="2016-12-12T16:54:10Z" SessionIndex="id-l3NCbxKoBfUZcuKhlotMuIF3ydgYJgGGG6BGTTU6" SessionNotOnOrAfter="2016-12-12T17:54:35Z"><saml:AuthnContext><saml:AuthnContextClassRef>urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport</saml:AuthnContextClassRef></saml:AuthnContext></saml:AuthnStatement></saml:Assertion></samlp:Response>`
+
 func BenchmarkSAMLResponse(b *testing.B) {
-	responseXML := `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:dsig="http://www.w3.org/2000/09/xmldsig#" xmlns:enc="http://www.w3.org/2001/04/xmlenc#" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:x500="urn:oasis:names:tc:SAML:2.0:profiles:attribute:X500" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" Destination="http://127.0.0.1:5556/callback" ID="id-IWlPTptSB-PlR80dwt8ZhVeG70mrz7nPvTVrhduK" InResponseTo="_e66b3a98-831c-4c96-5706-b63fe0549624" IssueInstant="2016-12-12T16:54:35Z" Version="2.0"><saml:Issuer Format="urn:oasis:names:tc:SAML:2.0:nameid-format:entity">https://deaoam-dev02.jpl.nasa.gov:14101/oam/fed</saml:Issuer><samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status><saml:Assertion ID="id-rT9rTqxdQC9j34YhVeNayUWC9EbIBgym6gp-MZt-" IssueInstant="2016-12-12T16:54:35Z" Version="2.0"><saml:Issuer Format="urn:oasis:names:tc:SAML:2.0:nameid-format:entity">https://deaoam-dev02.jpl.nasa.gov:14101/oam/fed</saml:Issuer><dsig:Signature><dsig:SignedInfo><dsig:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/><dsig:SignatureMethod Algorithm="http://www.w3.org/2000/09/xmldsig#rsa-sha1"/><dsig:Reference URI="#id-rT9rTqxdQC9j34YhVeNayUWC9EbIBgym6gp-MZt-"><dsig:Transforms><dsig:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/><dsig:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/></dsig:Transforms><dsig:DigestMethod Algorithm="http://www.w3.org/2000/09/xmldsig#sha1"/><dsig:DigestValue>z1HD/59hv6UOd5+jeG+ihaFWLgI=</dsig:DigestValue></dsig:Reference></dsig:SignedInfo><dsig:SignatureValue>I99oG5kiOfIgbXYa21z/TOmzftTkFnXe9ObhBNSKit9kAhT93apYROqqXv4Ax96P144Ld7ERX1hgJsytK8LC2874Pk7QrSNm4zvW3x0D4GR4lM06CvJK/EhIur3TrCUJDPigvyP7TJitheCyBejwt0x0lqNP/OzR3tMbAIMRoho=</dsig:SignatureValue></dsig:Signature><saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:2.0:nameid-format:persistent" NameQualifier="https://deaoam-dev02.jpl.nasa.gov:14101/oam/fed" SPNameQualifier="JSAuth">pkieu</saml:NameID><saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer"><saml:SubjectConfirmationData InResponseTo="_e66b3a98-831c-4c96-5706-b63fe0549624" NotOnOrAfter="2016-12-12T16:59:35Z" Recipient="http://127.0.0.1:5556/callback"/></saml:SubjectConfirmation></saml:Subject><saml:Conditions NotBefore="2016-12-12T16:54:35Z" NotOnOrAfter="2016-12-12T16:59:35Z"><saml:AudienceRestriction><saml:Audience>JSAuth</saml:Audience></saml:AudienceRestriction></saml:Conditions><saml:AuthnStatement AuthnInstant="2016-12-12T16:54:10Z" SessionIndex="id-l3NCbxKoBfUZcuKhlotMuIF3ydgYJgGGG6BGTTU6" SessionNotOnOrAfter="2016-12-12T17:54:35Z"><saml:AuthnContext><saml:AuthnContextClassRef>urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport</saml:AuthnContextClassRef></saml:AuthnContext></saml:AuthnStatement></saml:Assertion></samlp:Response>`
 	for i := 0; i < b.N; i++ {
 		errSink = ValidateAll(bytes.NewBufferString(responseXML))
 	}
 }
 
+func BenchmarkSAMLResponseBytes(b *testing.B) {
+	xmlBytes := []byte(responseXML)
+	for i := 0; i < b.N; i++ {
+		errSink = ValidateAllBytes(xmlBytes)
+	}
+}
+
+// singleLineXML is a large document on a single line, with a finding in
+// every element
+var singleLineXML = "<Root>" + strings.Repeat(`<e :b="z"/>`, 40000) + "</Root>"
+
+func BenchmarkSingleLineFindings(b *testing.B) {
+	xmlBytes := []byte(singleLineXML)
+	for i := 0; i < b.N; i++ {
+		errSink = New().ValidateAllBytes(xmlBytes)
+	}
+}
+
+func TestSingleLinePositions(t *testing.T) {
+	v := New()
+	require.NoError(t, v.Validate(strings.NewReader(singleLineXML)), "Warnings shouldn't fail validation")
+	errs := v.ValidateAll(strings.NewReader(singleLineXML))
+	require.Len(t, errs, 40000, "Should report every element")
+	last := errs[len(errs)-1].(XMLValidationError)
+	require.Equal(t, []int64{1, last.Start + 1, 1, last.End + 1}, []int64{last.Line, last.Column, last.EndLine, last.EndColumn},
+		"Should locate findings on a single line")
+
+	errs = v.ValidateAll(strings.NewReader("<Root>\n<e :b=\"z\"/>\n\n  <e\n :b=\"z\"/></Root>"))
+	require.Len(t, errs, 2)
+	require.Equal(t, []int64{2, 1, 2, 12}, []int64{errs[0].(XMLValidationError).Line, errs[0].(XMLValidationError).Column,
+		errs[0].(XMLValidationError).EndLine, errs[0].(XMLValidationError).EndColumn}, "Should locate findings after line feeds")
+	require.Equal(t, []int64{4, 3, 5, 10}, []int64{errs[1].(XMLValidationError).Line, errs[1].(XMLValidationError).Column,
+		errs[1].(XMLValidationError).EndLine, errs[1].(XMLValidationError).EndColumn}, "Should locate findings spanning line feeds")
+}
+
 func tokenize(t *testing.T, s string) xml.Token {
 	decoder := xml.NewDecoder(strings.NewReader(s))
 	token, err := decoder.RawToken()
 	require.NoError(t, err, "Tokenization should succeed")
 	return token
 }
+
+func TestIOError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	failing := func() io.Reader {
+		return io.MultiReader(strings.NewReader(`<Root><Child/>`), &failingReader{readErr})
+	}
+	isIOError := func(err error) bool {
+		ioError := IOError{}
+		return errors.As(err, &ioError) && errors.Is(err, readErr)
+	}
+
+	require.True(t, isIOError(Validate(failing())), "Validate should return read errors as IOError")
+	errs := ValidateAll(failing())
+	require.True(t, isIOError(errs[len(errs)-1]), "ValidateAll should return read errors as IOError")
+	_, err := ValidateAllDetailed(failing())
+	require.True(t, isIOError(err), "ValidateAllDetailed should return read errors as IOError")
+	_, err = New().Tokens(failing())
+	require.True(t, isIOError(err), "Tokens should return read errors as IOError")
+	require.True(t, isIOError(New().QuickValidate(failing())), "QuickValidate should return read errors as IOError")
+	_, err = ioutil.ReadAll(NewValidatingReader(failing()))
+	require.True(t, isIOError(err), "Validating readers should return read errors as IOError")
+	decoder := NewValidatingDecoder(failing())
+	for err = nil; err == nil; _, err = decoder.Token() {
+	}
+	require.True(t, isIOError(err), "Validating decoders should return read errors as IOError")
+
+	err = New().ValidateCompressed(strings.NewReader(`<Root/>`), "gzip")
+	require.True(t, errors.As(err, &IOError{}), "Decompression errors should be returned as IOError")
+
+	err = Validate(strings.NewReader(`<Root>]]></Root>`))
+	require.False(t, errors.As(err, &IOError{}), "Syntax errors shouldn't be returned as IOError")
+	require.Nil(t, Validate(strings.NewReader(`<Root/>`)), "EOF shouldn't be returned as IOError")
+}
+
+func TestSnippets(t *testing.T) {
+	doc := "<Root>" + strings.Repeat(" ", 100) + "<x:Child/>]]></Root>"
+	v := New(WithSnippets(5), WithUndeclaredPrefixCheck(UndeclaredPrefixConfig{}))
+
+	errs := v.ValidateAllBytes([]byte(doc))
+	require.Len(t, errs, 2, "Should report the undeclared prefix and the syntax error")
+	require.Equal(t, "     <x:Child/>]]></", errs[0].(XMLValidationError).Snippet, "Should surround the token with context")
+	errs = v.ValidateAll(strings.NewReader(doc))
+	require.Equal(t, "     <x:Child/>", errs[0].(XMLValidationError).Snippet, "Should only include context already read")
+
+	findings, err := v.ValidateAllDetailed(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Equal(t, "ild/>]]>", findings[len(findings)-1].Snippet, "Should include snippets of syntax errors")
+
+	v = New(WithSnippets(2), WithCheck(CheckCDATA, CheckConfig{Severity: SeverityError}))
+	err = v.ValidateBytes([]byte(`<Root><![CDATA[<<<<<<]]></Root>`))
+	require.Error(t, err, "Should report the CDATA section")
+	require.Equal(t, "t><!...]></", err.(XMLValidationError).Snippet, "Should shorten long tokens")
+
+	require.Empty(t, New(WithUndeclaredPrefixCheck(UndeclaredPrefixConfig{})).ValidateAll(strings.NewReader(doc))[0].(XMLValidationError).Snippet, "Should be disabled by default")
+}
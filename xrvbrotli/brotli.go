@@ -0,0 +1,26 @@
+// Package xrvbrotli adds the br content coding to Validator.ValidateCompressed.
+// It is a separate module so the validator itself stays free of
+// dependencies; import it for its side effect:
+//
+//	import _ "github.com/mattermost/xml-roundtrip-validator/xrvbrotli"
+package xrvbrotli
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/andybalholm/brotli"
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// Encoding is the name of the content coding, as used in HTTP
+const Encoding = "br"
+
+func init() {
+	validator.RegisterDecompressor(Encoding, Decompress)
+}
+
+// Decompress returns a reader of the decompressed content of r
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(brotli.NewReader(r)), nil
+}
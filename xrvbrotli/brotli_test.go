@@ -0,0 +1,27 @@
+package xrvbrotli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, input string) *bytes.Buffer {
+	t.Helper()
+	var compressed bytes.Buffer
+	w := brotli.NewWriter(&compressed)
+	_, err := w.Write([]byte(input))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return &compressed
+}
+
+func TestValidateCompressed(t *testing.T) {
+	v := validator.New()
+	require.NoError(t, v.ValidateCompressed(compress(t, `<foo></foo>`), Encoding), "Should pass on valid documents")
+	require.Error(t, v.ValidateCompressed(compress(t, `<Root>]]></Root>`), Encoding), "Should error on invalid documents")
+	require.Error(t, v.ValidateCompressed(bytes.NewBufferString(`<foo></foo>`), Encoding), "Should error on corrupt input")
+}
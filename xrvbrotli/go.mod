@@ -0,0 +1,11 @@
+module github.com/mattermost/xml-roundtrip-validator/xrvbrotli
+
+go 1.14
+
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/mattermost/xml-roundtrip-validator v1.0.0
+	github.com/stretchr/testify v1.6.1
+)
+
+replace github.com/mattermost/xml-roundtrip-validator => ../
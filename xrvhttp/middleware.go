@@ -0,0 +1,247 @@
+// Package xrvhttp validates XML request bodies in HTTP servers before they
+// reach the handlers parsing them.
+package xrvhttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// ErrorHandler writes the response to a request whose body failed validation
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// DefaultErrorHandler responds with 400 Bad Request and the validation
+// error, or with 413 Request Entity Too Large for bodies exceeding the
+// validator's size limits or compressed bodies exceeding its decompressed
+// size or ratio limits
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	sizeError := &validator.SizeLimitError{}
+	if errors.Is(err, validator.ErrDecompressedTooLarge) || errors.Is(err, validator.ErrDecompressionRatio) || errors.As(err, &sizeError) {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+type middleware struct {
+	validator    *validator.Validator
+	streaming    bool
+	errorHandler ErrorHandler
+}
+
+// Option configures the middleware
+type Option func(*middleware)
+
+// Streaming makes the middleware validate the request body incrementally as
+// the handler reads it, instead of buffering and validating the whole body
+// before calling the handler. Reads return the first finding that fails
+// validation as soon as the offending token is complete, and the handler's
+// response is replaced by the error handler's as long as the handler hasn't
+// written anything when validation fails.
+//
+// Handlers must read the body to EOF before acting on it; a handler acting
+// on a partially read body only acts on the validated part of it.
+func Streaming() Option {
+	return func(m *middleware) {
+		m.streaming = true
+	}
+}
+
+// WithErrorHandler sets the handler responding to requests whose body failed
+// validation; it defaults to DefaultErrorHandler
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(m *middleware) {
+		m.errorHandler = h
+	}
+}
+
+// Middleware returns HTTP middleware validating request bodies with v.
+// By default the whole body is read and validated before calling the next
+// handler, which then reads it from memory; see Streaming for an
+// alternative that doesn't buffer the body. Buffering stops as soon as the
+// body exceeds the limit set with validator.WithMaxBytes.
+//
+// Bodies with a Content-Encoding supported by v.Decompress are validated
+// and handed to the next handler decompressed, with the Content-Encoding
+// header removed; other encodings are refused with 415 Unsupported Media
+// Type. A charset parameter in the Content-Type header must match the
+// encoding declared by the document, see Validator.ExpectCharset.
+func Middleware(v *validator.Validator, opts ...Option) func(http.Handler) http.Handler {
+	m := &middleware{validator: v, errorHandler: DefaultErrorHandler}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return func(next http.Handler) http.Handler {
+		if m.streaming {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				m.serveStreaming(next, w, r)
+			})
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.serveBuffered(next, w, r)
+		})
+	}
+}
+
+// validatorFor returns the validator for the request, expecting the charset
+// named by its Content-Type header, if any
+func (m *middleware) validatorFor(r *http.Request) *validator.Validator {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || params["charset"] == "" {
+		return m.validator
+	}
+	return m.validator.ExpectCharset(params["charset"])
+}
+
+// decompress returns a reader of the decompressed body, after removing the
+// Content-Encoding header; it responds and returns nil if the encoding isn't
+// supported, with 415 Unsupported Media Type, or the body is corrupt
+func (m *middleware) decompress(w http.ResponseWriter, r *http.Request, body io.Reader) io.ReadCloser {
+	encoding := r.Header.Get("Content-Encoding")
+	decompressed, err := m.validator.Decompress(body, encoding)
+	if errors.Is(err, validator.ErrUnsupportedEncoding) {
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return nil
+	} else if err != nil {
+		m.errorHandler(w, r, err)
+		return nil
+	}
+	if encoding != "" {
+		r.Header.Del("Content-Encoding")
+		r.ContentLength = -1
+	}
+	return decompressed
+}
+
+func (m *middleware) serveBuffered(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	decompressed := m.decompress(w, r, r.Body)
+	if decompressed == nil {
+		return
+	}
+	defer decompressed.Close()
+	// the body is bounded before buffering it, not only when validating it
+	body, err := ioutil.ReadAll(m.validator.LimitReader(decompressed))
+	sizeError := &validator.SizeLimitError{}
+	if errors.Is(err, validator.ErrDecompressedTooLarge) || errors.Is(err, validator.ErrDecompressionRatio) || errors.As(err, &sizeError) {
+		m.errorHandler(w, r, err)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := m.validatorFor(r).Validate(bytes.NewReader(body)); err != nil {
+		m.errorHandler(w, r, err)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	next.ServeHTTP(w, r)
+}
+
+func (m *middleware) serveStreaming(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	// errors reading the body itself are told apart from validation errors,
+	// which include failing to decompress it
+	original := &trackingReader{r: r.Body}
+	decompressed := m.decompress(w, r, original)
+	if decompressed == nil {
+		return
+	}
+	defer decompressed.Close()
+	body := &validatingBody{
+		ReadCloser: m.validatorFor(r).NewValidatingReader(decompressed),
+		original:   original,
+		closer:     r.Body,
+	}
+	defer body.ReadCloser.Close()
+	rw := &responseWriter{ResponseWriter: w, request: r, body: body, errorHandler: m.errorHandler}
+	r.Body = body
+	next.ServeHTTP(rw, r)
+	// the handler may have read the whole body without writing anything
+	rw.intercept()
+}
+
+// trackingReader remembers the error returned by the underlying body, to tell
+// it apart from validation errors
+type trackingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (t *trackingReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		t.err = err
+	}
+	return n, err
+}
+
+// validatingBody is the request body seen by the handler in streaming mode
+type validatingBody struct {
+	io.ReadCloser
+	original *trackingReader
+	closer   io.Closer
+	// failed holds the validation error once validation failed
+	failed error
+}
+
+func (b *validatingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && b.original.err == nil && b.failed == nil {
+		b.failed = err
+	}
+	return n, err
+}
+
+func (b *validatingBody) Close() error {
+	b.ReadCloser.Close()
+	return b.closer.Close()
+}
+
+// responseWriter replaces the handler's response with the error handler's
+// once validation failed, unless the handler already started responding
+type responseWriter struct {
+	http.ResponseWriter
+	request      *http.Request
+	body         *validatingBody
+	errorHandler ErrorHandler
+	// written is set once the response was started, by either handler
+	written bool
+	// rejected is set if the error handler wrote the response
+	rejected bool
+}
+
+// intercept calls the error handler if validation failed and nothing was
+// written yet, and reports whether the handler's output must be discarded
+func (rw *responseWriter) intercept() bool {
+	if rw.rejected {
+		return true
+	}
+	if rw.written || rw.body.failed == nil {
+		return false
+	}
+	rw.written, rw.rejected = true, true
+	rw.errorHandler(rw.ResponseWriter, rw.request, rw.body.failed)
+	return true
+}
+
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	if rw.intercept() {
+		return
+	}
+	rw.written = true
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if rw.intercept() {
+		return 0, rw.body.failed
+	}
+	rw.written = true
+	return rw.ResponseWriter.Write(p)
+}
@@ -0,0 +1,187 @@
+package xrvhttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/stretchr/testify/require"
+)
+
+// echoHandler responds with the request body
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+	w.Write(body)
+})
+
+func serve(handler http.Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"buffered":  nil,
+		"streaming": {Streaming()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := Middleware(validator.New(), opts...)(echoHandler)
+
+			rec := serve(handler, `<Root><Child attr="value">text</Child></Root>`)
+			require.Equal(t, http.StatusOK, rec.Code, "Should pass valid documents")
+			require.Equal(t, `<Root><Child attr="value">text</Child></Root>`, rec.Body.String(), "Should pass the body through unchanged")
+
+			rec = serve(handler, `<Root>]]></Root>`)
+			require.Equal(t, http.StatusBadRequest, rec.Code, "Should reject invalid documents")
+			require.NotContains(t, rec.Body.String(), "<Root>", "Shouldn't write the handler's response")
+
+			rec = serve(Middleware(validator.New(validator.WithMaxBytes(16)), opts...)(echoHandler), `<Root>`+strings.Repeat(`<Child/>`, 10)+`</Root>`)
+			require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "Should reject documents over the size limit")
+		})
+	}
+}
+
+// endlessBody serves the start of a document followed by endless text
+type endlessBody struct {
+	read int
+}
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	if b.read == 0 && len(p) >= len("<Root>") {
+		b.read += copy(p, "<Root>")
+		return len("<Root>"), nil
+	}
+	for i := range p {
+		p[i] = 'a'
+	}
+	b.read += len(p)
+	return len(p), nil
+}
+
+func TestMiddlewareBufferedLimit(t *testing.T) {
+	called := false
+	handler := Middleware(validator.New(validator.WithMaxBytes(1 << 16)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	body := &endlessBody{}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", body))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "Should reject bodies over the size limit")
+	require.False(t, called, "Shouldn't call the handler")
+	require.LessOrEqual(t, body.read, 1<<16+1, "Should stop buffering the body past the limit")
+
+	rec = serve(handler, `<Root>`+strings.Repeat(`<Child/>`, 100)+`</Root>`)
+	require.Equal(t, http.StatusOK, rec.Code, "Should pass bodies within the size limit")
+	require.True(t, called, "Should call the handler")
+}
+
+func TestMiddlewareErrorHandler(t *testing.T) {
+	var handled error
+	handler := Middleware(validator.New(), Streaming(), WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = err
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}))(echoHandler)
+
+	rec := serve(handler, `<Root>]]></Root>`)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code, "Should respond with the error handler")
+	require.Error(t, handled, "Should pass the validation error to the error handler")
+}
+
+func TestMiddlewareStreaming(t *testing.T) {
+	var read string
+	handler := Middleware(validator.New(), Streaming())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		read = string(body)
+		if err != nil {
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := serve(handler, `<Root>`+strings.Repeat(`<Child/>`, 1000)+`]]></Root>`)
+	require.Equal(t, http.StatusBadRequest, rec.Code, "Should replace the handler's response")
+	require.NotContains(t, read, "]]>", "Shouldn't pass the invalid token to the handler")
+	require.Contains(t, read, "<Child/>", "Should pass the valid part of the body to the handler")
+
+	handler = Middleware(validator.New(), Streaming())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	rec = serve(handler, `<Root>]]></Root>`)
+	require.Equal(t, http.StatusAccepted, rec.Code, "Should leave responses alone if the body wasn't read")
+}
+
+func TestMiddlewareContentEncoding(t *testing.T) {
+	gzipped := func(input string) string {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		w.Write([]byte(input))
+		w.Close()
+		return compressed.String()
+	}
+	request := func(encoding, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Encoding", encoding)
+		return r
+	}
+	for name, opts := range map[string][]Option{
+		"buffered":  nil,
+		"streaming": {Streaming()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var encoding string
+			handler := Middleware(validator.New(validator.WithMaxDecompressedSize(1024)), opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				encoding = r.Header.Get("Content-Encoding")
+				echoHandler(w, r)
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, request("gzip", gzipped(`<Root/>`)))
+			require.Equal(t, http.StatusOK, rec.Code, "Should pass valid compressed documents")
+			require.Equal(t, `<Root/>`, rec.Body.String(), "Should pass the body on decompressed")
+			require.Empty(t, encoding, "Should remove the Content-Encoding header")
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, request("gzip", gzipped(`<Root>]]></Root>`)))
+			require.Equal(t, http.StatusBadRequest, rec.Code, "Should reject invalid compressed documents")
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, request("gzip", gzipped(`<Root>`+strings.Repeat(`<Child/>`, 1000)+`</Root>`)))
+			require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, "Should reject documents decompressing past the limit")
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, request("gzip", `<Root/>`))
+			require.Equal(t, http.StatusBadRequest, rec.Code, "Should reject corrupt compressed bodies")
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, request("compress", `<Root/>`))
+			require.Equal(t, http.StatusUnsupportedMediaType, rec.Code, "Should refuse unsupported encodings")
+		})
+	}
+}
+
+func TestMiddlewareCharset(t *testing.T) {
+	handler := Middleware(validator.New(validator.WithFailOn(validator.SeverityWarning)))(echoHandler)
+	for contentType, expected := range map[string]int{
+		"application/xml":                  http.StatusOK,
+		"application/xml; charset=utf-8":   http.StatusOK,
+		"application/xml; charset=UTF8":    http.StatusOK,
+		"application/xml; charset=latin-1": http.StatusBadRequest,
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<?xml version="1.0" encoding="UTF-8"?><Root/>`))
+		r.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		require.Equal(t, expected, rec.Code, "Should compare %q with the declared encoding", contentType)
+	}
+}
@@ -0,0 +1,121 @@
+package xrvhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// WebhookSink is a validator.FindingSink posting every finding as JSON to a
+// webhook URL. Findings are queued and posted by a background goroutine;
+// they are dropped instead of blocking validation while the queue is full,
+// when the webhook can't be reached, and once the sink is closed.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	queue  chan validator.FindingEvent
+	done   chan struct{}
+	// mu guards closed, so findings aren't queued once queue is closed
+	mu      sync.RWMutex
+	closed  bool
+	dropped int64
+}
+
+// WebhookEvent is the JSON payload posted by WebhookSink, described by the
+// event definition of validator.JSONSchema
+type WebhookEvent struct {
+	Time     time.Time         `json:"time"`
+	Rejected bool              `json:"rejected"`
+	Finding  validator.Finding `json:"finding"`
+}
+
+// NewWebhookSink returns a WebhookSink posting to url with client, queueing
+// up to queueSize findings; a nil client uses http.DefaultClient. Close
+// must be called to stop the background goroutine.
+func NewWebhookSink(url string, client *http.Client, queueSize int) *WebhookSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	s := &WebhookSink{
+		url:    url,
+		client: client,
+		queue:  make(chan validator.FindingEvent, queueSize),
+		done:   make(chan struct{}),
+	}
+	go s.deliver()
+	return s
+}
+
+// Publish implements validator.FindingSink
+func (s *WebhookSink) Publish(event validator.FindingEvent) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		atomic.AddInt64(&s.dropped, 1)
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+// Dropped returns the number of findings that were never delivered
+func (s *WebhookSink) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Close delivers the queued findings and stops the background goroutine;
+// findings published after Close are dropped
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+func (s *WebhookSink) deliver() {
+	defer close(s.done)
+	for event := range s.queue {
+		if err := s.post(event); err != nil {
+			atomic.AddInt64(&s.dropped, 1)
+		}
+	}
+}
+
+func (s *WebhookSink) post(event validator.FindingEvent) error {
+	body, err := json.Marshal(NewWebhookEvent(event))
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// NewWebhookEvent converts a finding into the payload posted by WebhookSink;
+// events built without their Finding get it from their Err
+func NewWebhookEvent(event validator.FindingEvent) WebhookEvent {
+	finding := event.Finding
+	if finding.Check == "" && event.Err != nil {
+		finding = validator.FindingOf(event.Err)
+	}
+	return WebhookEvent{Time: event.Time, Rejected: event.Rejected, Finding: finding}
+}
@@ -0,0 +1,81 @@
+package xrvhttp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/mattermost/xml-roundtrip-validator/xrvtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		var event map[string]interface{}
+		if err != nil || json.Unmarshal(body, &event) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, event)
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, server.Client(), 10)
+	v := validator.New(validator.WithFindingSink(sink))
+	err := v.Validate(strings.NewReader(`<Root>
+	<x::Element/>
+</Root>`))
+	require.Error(t, err, "Should error on invalid documents")
+	require.NoError(t, sink.Close(), "Should deliver queued findings on Close")
+
+	require.Len(t, received, 1, "Should post every finding")
+	xrvtest.AssertMatchesSchema(t, "event", bodies[0])
+	finding := received[0]["finding"].(map[string]interface{})
+	require.Equal(t, "syntax", finding["check"], "Should post the check")
+	require.Equal(t, "error", finding["severity"], "Should post the severity")
+	require.Equal(t, true, received[0]["rejected"], "Should post whether the finding rejected the document")
+	require.Equal(t, validator.FindingOf(err).Message, finding["message"], "Should post the message of the finding")
+	require.Equal(t, float64(2), finding["line"], "Should post the line of the finding")
+	require.Zero(t, sink.Dropped(), "Shouldn't drop findings")
+
+	require.NotPanics(t, func() { sink.Publish(validator.FindingEvent{Err: err}) }, "Publishing after Close shouldn't panic")
+	require.Equal(t, int64(1), sink.Dropped(), "Should drop findings published after Close")
+	require.NoError(t, sink.Close(), "Should close more than once")
+
+	sink = NewWebhookSink(server.URL+"/missing", nil, 10)
+	server.Config.Handler = http.NotFoundHandler()
+	validator.New(validator.WithFindingSink(sink)).Validate(strings.NewReader(`<x::Root/>`))
+	require.NoError(t, sink.Close(), "Should close")
+	require.Equal(t, int64(1), sink.Dropped(), "Should count undelivered findings as dropped")
+}
+
+func TestNewWebhookEvent(t *testing.T) {
+	v := validator.New()
+	errs := v.ValidateAll(strings.NewReader("<Root>\n  <!DOCTYPE x SYSTEM \"http://example.com/x.dtd\"></Root>"))
+	require.Len(t, errs, 1, "Should report the external DTD")
+	finding := validator.FindingOf(errs[0])
+	finding.Suggestion = "published finding"
+	event := NewWebhookEvent(validator.FindingEvent{Check: validator.CheckKnownAttacks, Severity: validator.SeverityWarning, Err: errs[0], Finding: finding})
+	require.Equal(t, finding, event.Finding, "Should post the finding of the event")
+	require.False(t, event.Rejected, "Should convert the rejection")
+	body, err := json.Marshal(event)
+	require.NoError(t, err)
+	xrvtest.AssertMatchesSchema(t, "event", body)
+
+	event = NewWebhookEvent(validator.FindingEvent{Check: validator.CheckKnownAttacks, Severity: validator.SeverityWarning, Err: errs[0]})
+	require.Equal(t, int64(2), event.Finding.Line, "Should describe events built without their finding")
+	require.Equal(t, int64(3), event.Finding.Column, "Should describe events built without their finding")
+	require.Equal(t, int64(9), event.Finding.Start, "Should describe events built without their finding")
+}
@@ -0,0 +1,153 @@
+// Package xrvstore persists validation reports, so rejected documents can
+// be looked up after the fact by their digest or by when they were seen.
+package xrvstore
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// Finding is a finding of a Report
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Line and Column are zero if the position of the finding is unknown
+	Line   int64 `json:"line,omitempty"`
+	Column int64 `json:"column,omitempty"`
+}
+
+// Report is the outcome of validating a document
+type Report struct {
+	// Digest identifies the document, see Digest
+	Digest string    `json:"digest"`
+	Time   time.Time `json:"time"`
+	// Source describes where the document came from, such as a file name
+	// or the address of an HTTP client
+	Source   string    `json:"source,omitempty"`
+	Rejected bool      `json:"rejected"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// Query selects reports; zero fields match every report
+type Query struct {
+	Digest string
+	// Since and Until bound the time of the reports, inclusively
+	Since, Until time.Time
+	// Rejected only matches reports of rejected documents
+	Rejected bool
+}
+
+// Store persists reports. Implementations must be safe for concurrent use.
+// FileStore is a reference implementation; databases such as SQLite can
+// implement Store to make larger histories queryable.
+type Store interface {
+	// Put persists a report
+	Put(report Report) error
+	// Query returns the reports matching q, in the order they were put
+	Query(q Query) ([]Report, error)
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// Digest returns the digest identifying a document in reports
+func Digest(document []byte) string {
+	sum := sha256.Sum256(document)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// NewReport builds the report of a document from the findings returned by
+// validating it with v
+func NewReport(digest, source string, v *validator.Validator, findings []error) Report {
+	report := Report{Digest: digest, Time: time.Now().UTC(), Source: source}
+	for _, err := range findings {
+		report.Rejected = report.Rejected || v.Fails(err)
+		f := validator.FindingOf(err)
+		report.Findings = append(report.Findings, Finding{
+			Check:    string(f.Check),
+			Severity: f.Severity.String(),
+			Message:  err.Error(),
+			Line:     f.Line,
+			Column:   f.Column,
+		})
+	}
+	return report
+}
+
+// Matches reports whether the report is selected by q
+func (q Query) Matches(report Report) bool {
+	switch {
+	case q.Digest != "" && report.Digest != q.Digest:
+		return false
+	case !q.Since.IsZero() && report.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && report.Time.After(q.Until):
+		return false
+	case q.Rejected && !report.Rejected:
+		return false
+	}
+	return true
+}
+
+// FileStore is a Store appending reports to a file as JSON lines; queries
+// scan the whole file
+type FileStore struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFileStore opens the file store at filename, creating the file if it
+// doesn't exist
+func OpenFileStore(filename string) (*FileStore, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{file: file}, nil
+}
+
+// Put implements Store
+func (s *FileStore) Put(report Report) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query implements Store
+func (s *FileStore) Query(q Query) ([]Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	reports := []Report{}
+	scanner := bufio.NewScanner(s.file)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		var report Report
+		if err := json.Unmarshal(scanner.Bytes(), &report); err != nil {
+			return nil, err
+		}
+		if q.Matches(report) {
+			reports = append(reports, report)
+		}
+	}
+	return reports, scanner.Err()
+}
+
+// Close implements Store
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
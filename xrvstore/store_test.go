@@ -0,0 +1,70 @@
+package xrvstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReport(t *testing.T) {
+	v := validator.New()
+	report := NewReport(Digest([]byte(`<Root/>`)), "test.xml", v, v.ValidateAll(strings.NewReader(`<Root/>`)))
+	require.False(t, report.Rejected, "Valid documents shouldn't be rejected")
+	require.Empty(t, report.Findings)
+	require.True(t, strings.HasPrefix(report.Digest, "sha256:"), "Digests should name their algorithm")
+
+	warning := `<Root><!DOCTYPE x SYSTEM "http://example.com/x.dtd"></Root>`
+	report = NewReport(Digest([]byte(warning)), "test.xml", v, v.ValidateAll(strings.NewReader(warning)))
+	require.False(t, report.Rejected, "Warnings shouldn't reject documents")
+	require.Len(t, report.Findings, 1)
+	require.Equal(t, "warning", report.Findings[0].Severity)
+	require.NotZero(t, report.Findings[0].Line, "Findings should have a position")
+
+	report = NewReport(Digest([]byte(`<Root><`)), "test.xml", v, v.ValidateAll(strings.NewReader(`<Root><`)))
+	require.True(t, report.Rejected, "Syntax errors should reject documents")
+	require.Equal(t, string(validator.CheckSyntax), report.Findings[0].Check)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xrvstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "reports.jsonl")
+
+	store, err := OpenFileStore(filename)
+	require.NoError(t, err)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, digest := range []string{"a", "b", "a"} {
+		report := Report{Digest: digest, Time: start.Add(time.Duration(i) * time.Hour), Rejected: i > 0}
+		require.NoError(t, store.Put(report))
+	}
+	require.NoError(t, store.Close())
+
+	store, err = OpenFileStore(filename)
+	require.NoError(t, err, "Should reopen existing stores")
+	defer store.Close()
+	require.NoError(t, store.Put(Report{Digest: "c", Time: start.Add(3 * time.Hour)}))
+
+	for q, expected := range map[Query][]string{
+		{}:                            {"a", "b", "a", "c"},
+		{Digest: "a"}:                 {"a", "a"},
+		{Rejected: true}:              {"b", "a"},
+		{Since: start.Add(time.Hour)}: {"b", "a", "c"},
+		{Until: start.Add(time.Hour)}: {"a", "b"},
+		{Digest: "a", Since: start.Add(30 * time.Minute)}: {"a"},
+	} {
+		reports, err := store.Query(q)
+		require.NoError(t, err)
+		digests := []string{}
+		for _, report := range reports {
+			digests = append(digests, report.Digest)
+		}
+		require.Equal(t, expected, digests, "Query %+v should match the reports put in order", q)
+	}
+}
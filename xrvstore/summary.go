@@ -0,0 +1,86 @@
+package xrvstore
+
+import (
+	"sort"
+	"time"
+)
+
+// Summary summarizes the reports of a corpus of documents since a point in
+// time, comparing each document's latest report with its latest report
+// from before then. Documents are told apart by their report's Source.
+type Summary struct {
+	Since time.Time
+	// Documents is the number of documents reported on since Since
+	Documents int
+	// Rejected is the number of those documents whose latest report
+	// rejected them
+	Rejected int
+	// Checks counts the findings of each check in the latest reports
+	Checks map[string]int
+	// Changes lists the documents whose latest report has findings of
+	// different checks than their previous one, sorted by source
+	Changes []Change
+}
+
+// Change describes how the findings of a document changed
+type Change struct {
+	Source string
+	// New and Resolved hold the checks reported on the document only by
+	// its latest report and only by its previous one, respectively; all
+	// checks are new for documents without a previous report
+	New, Resolved []string
+}
+
+// Summarize builds the summary of reports since the given time; reports
+// are expected in the order they were put, as returned by Store.Query
+func Summarize(reports []Report, since time.Time) Summary {
+	latest := map[string]Report{}
+	previous := map[string]Report{}
+	for _, report := range reports {
+		if report.Time.Before(since) {
+			previous[report.Source] = report
+		} else {
+			latest[report.Source] = report
+		}
+	}
+
+	summary := Summary{Since: since, Documents: len(latest), Checks: map[string]int{}}
+	for source, report := range latest {
+		if report.Rejected {
+			summary.Rejected++
+		}
+		for _, finding := range report.Findings {
+			summary.Checks[finding.Check]++
+		}
+		current, before := report.checks(), previous[source].checks()
+		change := Change{Source: source}
+		for check := range current {
+			if !before[check] {
+				change.New = append(change.New, check)
+			}
+		}
+		for check := range before {
+			if !current[check] {
+				change.Resolved = append(change.Resolved, check)
+			}
+		}
+		if len(change.New) > 0 || len(change.Resolved) > 0 {
+			sort.Strings(change.New)
+			sort.Strings(change.Resolved)
+			summary.Changes = append(summary.Changes, change)
+		}
+	}
+	sort.Slice(summary.Changes, func(i, j int) bool {
+		return summary.Changes[i].Source < summary.Changes[j].Source
+	})
+	return summary
+}
+
+// checks returns the set of checks with findings in the report
+func (report Report) checks() map[string]bool {
+	checks := map[string]bool{}
+	for _, finding := range report.Findings {
+		checks[finding.Check] = true
+	}
+	return checks
+}
@@ -0,0 +1,43 @@
+package xrvstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := func(hours int, source string, rejected bool, checks ...string) Report {
+		r := Report{Time: start.Add(time.Duration(hours) * time.Hour), Source: source, Rejected: rejected}
+		for _, check := range checks {
+			r.Findings = append(r.Findings, Finding{Check: check})
+		}
+		return r
+	}
+	reports := []Report{
+		report(0, "a.xml", true, "roundtrip"),
+		report(0, "b.xml", false),
+		report(1, "c.xml", false, "cdata"),
+		report(2, "a.xml", false, "cdata"),
+		report(2, "b.xml", true, "roundtrip", "roundtrip"),
+		report(2, "c.xml", false, "cdata"),
+		report(3, "d.xml", false, "attribute-order"),
+		report(3, "b.xml", false, "cdata"),
+	}
+
+	summary := Summarize(reports, start.Add(2*time.Hour))
+	require.Equal(t, 4, summary.Documents, "Should count the documents reported since the given time")
+	require.Equal(t, 0, summary.Rejected, "Should only count the latest reports of documents")
+	require.Equal(t, map[string]int{"cdata": 3, "attribute-order": 1}, summary.Checks)
+	require.Equal(t, []Change{
+		{Source: "a.xml", New: []string{"cdata"}, Resolved: []string{"roundtrip"}},
+		{Source: "b.xml", New: []string{"cdata"}},
+		{Source: "d.xml", New: []string{"attribute-order"}},
+	}, summary.Changes, "Should compare the latest reports with the ones before the given time")
+
+	summary = Summarize(reports, start.Add(4*time.Hour))
+	require.Equal(t, 0, summary.Documents)
+	require.Empty(t, summary.Changes)
+}
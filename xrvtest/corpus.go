@@ -0,0 +1,144 @@
+package xrvtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+const (
+	// CaseInput is the name of the document file in a case directory
+	CaseInput = "input.xml"
+	// CaseManifest is the name of the expectations file in a case directory
+	CaseManifest = "manifest.json"
+)
+
+// ExpectedFinding describes a finding a case expects; zero Line and Column
+// values match any position
+type ExpectedFinding struct {
+	Check  validator.CheckID `json:"check"`
+	Line   int64             `json:"line,omitempty"`
+	Column int64             `json:"column,omitempty"`
+}
+
+// Manifest holds the expectations of a case
+type Manifest struct {
+	Description string            `json:"description,omitempty"`
+	Findings    []ExpectedFinding `json:"findings"`
+}
+
+// Case is a single document of a corpus along with its expected findings
+type Case struct {
+	Name     string
+	Input    []byte
+	Manifest Manifest
+}
+
+// LoadCorpus loads every case from the subdirectories of dir; each of them
+// holds an input.xml document and a manifest.json file describing the
+// findings expected when validating it
+func LoadCorpus(dir string) ([]Case, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	cases := []Case{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c, err := LoadCase(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// LoadCase loads a single case directory
+func LoadCase(dir string) (Case, error) {
+	c := Case{Name: filepath.Base(dir)}
+	var err error
+	if c.Input, err = ioutil.ReadFile(filepath.Join(dir, CaseInput)); err != nil {
+		return c, err
+	}
+	manifest, err := os.Open(filepath.Join(dir, CaseManifest))
+	if err != nil {
+		return c, err
+	}
+	defer manifest.Close()
+	decoder := json.NewDecoder(manifest)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c.Manifest); err != nil {
+		return c, fmt.Errorf("%s: %w", filepath.Join(dir, CaseManifest), err)
+	}
+	return c, nil
+}
+
+// Check validates the case's document and returns an error describing any
+// difference between the observed and expected findings
+func (c Case) Check(v *validator.Validator) error {
+	errs := v.ValidateAll(bytes.NewReader(c.Input))
+	var mismatches []string
+	for i := 0; i < len(errs) || i < len(c.Manifest.Findings); i++ {
+		switch {
+		case i >= len(errs):
+			mismatches = append(mismatches, fmt.Sprintf("missing finding %+v", c.Manifest.Findings[i]))
+		case i >= len(c.Manifest.Findings):
+			mismatches = append(mismatches, fmt.Sprintf("unexpected finding: %v", errs[i]))
+		case !c.Manifest.Findings[i].matches(errs[i]):
+			mismatches = append(mismatches, fmt.Sprintf("expected finding %+v, observed: %v", c.Manifest.Findings[i], errs[i]))
+		}
+	}
+	if len(mismatches) > 0 {
+		return errors.New(strings.Join(mismatches, "\n"))
+	}
+	return nil
+}
+
+func (f ExpectedFinding) matches(err error) bool {
+	if CheckOf(err) != f.Check {
+		return false
+	}
+	validationError := validator.XMLValidationError{}
+	if errors.As(err, &validationError) {
+		return (f.Line == 0 || f.Line == validationError.Line) &&
+			(f.Column == 0 || f.Column == validationError.Column)
+	}
+	// syntax errors only carry a line number
+	syntaxError := &xml.SyntaxError{}
+	if errors.As(err, &syntaxError) {
+		return f.Line == 0 || f.Line == int64(syntaxError.Line)
+	}
+	return true
+}
+
+// RunCorpus runs every case in dir as a subtest, validating the documents
+// with v; see LoadCorpus for the layout of the directory
+func RunCorpus(t *testing.T, dir string, v *validator.Validator) {
+	t.Helper()
+	cases, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatalf("loading corpus: %v", err)
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Check(v); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
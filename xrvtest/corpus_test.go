@@ -0,0 +1,28 @@
+package xrvtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+func TestCorpus(t *testing.T) {
+	RunCorpus(t, "testdata/corpus", validator.New())
+}
+
+func TestCaseCheck(t *testing.T) {
+	c := Case{
+		Name:     "mismatch",
+		Input:    []byte(`<Root>]]></Root>`),
+		Manifest: Manifest{Findings: []ExpectedFinding{{Check: validator.CheckSyntax, Line: 2}}},
+	}
+	require.Error(t, c.Check(validator.New()), "Should error when the position doesn't match")
+
+	c.Manifest.Findings[0].Line = 1
+	require.NoError(t, c.Check(validator.New()), "Should pass when the findings match")
+
+	c.Manifest.Findings = nil
+	require.Error(t, c.Check(validator.New()), "Should error on unexpected findings")
+}
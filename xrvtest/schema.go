@@ -0,0 +1,113 @@
+package xrvtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// AssertMatchesSchema asserts that data is JSON matching the given
+// definition of validator.JSONSchema, such as "finding" or "result"
+func AssertMatchesSchema(t TestingT, definition string, data []byte) bool {
+	t.Helper()
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(validator.JSONSchema), &schema); err != nil {
+		t.Errorf("invalid schema: %v", err)
+		return false
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Errorf("invalid JSON %s: %v", data, err)
+		return false
+	}
+	s := schemaChecker{schema}
+	if err := s.check(map[string]interface{}{"$ref": "#/$defs/" + definition}, value, ""); err != nil {
+		t.Errorf("%s doesn't match the %s schema: %v", data, definition, err)
+		return false
+	}
+	return true
+}
+
+// schemaChecker checks values against the subset of JSON Schema that
+// validator.JSONSchema uses
+type schemaChecker struct {
+	root map[string]interface{}
+}
+
+func (s schemaChecker) check(schema map[string]interface{}, value interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		definition, ok := s.root["$defs"].(map[string]interface{})[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unknown reference %s", ref)
+		}
+		return s.check(definition, value, path)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		for _, allowed := range enum {
+			if allowed == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not an object", path, value)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unexpected property %s", path, name)
+				}
+				continue
+			}
+			if err := s.check(property, object[name], path+"/"+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: %v is not an array", path, value)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range array {
+			if err := s.check(items, item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: %v is not a string", path, value)
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			return fmt.Errorf("%s: %v is not an integer", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: %v is not a boolean", path, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package xrvtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+func TestAssertMatchesSchema(t *testing.T) {
+	v := validator.New(validator.WithSnippets(8), validator.WithUndeclaredPrefixCheck(validator.UndeclaredPrefixConfig{}))
+	for _, doc := range []string{
+		"<Root>\n<!DOCTYPE x SYSTEM \"http://example.com/x.dtd\"></Root>",
+		`<Root><x:Child/></Root>`,
+		`<Root>]]></Root>`,
+	} {
+		findings, err := v.ValidateAllDetailed(strings.NewReader(doc))
+		require.NoError(t, err)
+		require.NotEmpty(t, findings)
+		for _, finding := range findings {
+			data, err := json.Marshal(finding)
+			require.NoError(t, err)
+			require.True(t, AssertMatchesSchema(t, "finding", data), "Findings should match the schema")
+		}
+	}
+
+	data, err := json.Marshal(validator.XMLRoundtripError{Expected: xml.Directive("x"), Observed: xml.Directive("y"), Overflow: []byte("z")})
+	require.NoError(t, err)
+	require.True(t, AssertMatchesSchema(t, "roundtrip", data), "Round trip errors should match the schema")
+
+	for data, message := range map[string]string{
+		`{"check": "roundtrip", "severity": "error"}`:                             "missing required property message",
+		`{"check": "roundtrip", "severity": "fatal", "message": ""}`:              "fatal is not one of",
+		`{"check": "roundtrip", "severity": "error", "message": "", "line": 1.5}`: "1.5 is not an integer",
+		`{"check": "roundtrip", "severity": "error", "message": "", "offset": 1}`: "unexpected property offset",
+		`{"valid": true, "findings": [{}]}`:                                       "/findings/0: missing required property check",
+	} {
+		ft := &fakeT{}
+		definition := "finding"
+		if strings.HasPrefix(data, `{"valid"`) {
+			definition = "result"
+		}
+		require.False(t, AssertMatchesSchema(ft, definition, []byte(data)), "Should fail on %s", data)
+		require.Len(t, ft.errors, 1)
+		require.Contains(t, ft.errors[0], message, "Should describe the mismatch")
+	}
+}
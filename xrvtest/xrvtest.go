@@ -0,0 +1,81 @@
+// Package xrvtest provides assertions for checking the round-trip stability
+// of XML documents in tests
+package xrvtest
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// TestingT is the subset of testing.TB used by the assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertRoundtripStable asserts that the document passes validation with
+// the given options, reporting every failing finding otherwise
+func AssertRoundtripStable(t TestingT, doc string, opts ...validator.Option) bool {
+	t.Helper()
+	v := validator.New(opts...)
+	ok := true
+	for _, err := range v.ValidateAll(strings.NewReader(doc)) {
+		if v.Fails(err) {
+			t.Errorf("document isn't round-trip stable: %v", err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AssertFindings asserts that validating the document with the default
+// options reports findings from exactly the given checks, in document order
+func AssertFindings(t TestingT, doc string, checks ...validator.CheckID) bool {
+	t.Helper()
+	return AssertValidatorFindings(t, validator.New(), doc, checks...)
+}
+
+// AssertValidatorFindings is like AssertFindings, but validates the
+// document with the given Validator
+func AssertValidatorFindings(t TestingT, v *validator.Validator, doc string, checks ...validator.CheckID) bool {
+	t.Helper()
+	errs := v.ValidateAll(strings.NewReader(doc))
+	observed := make([]validator.CheckID, len(errs))
+	for i, err := range errs {
+		observed[i] = CheckOf(err)
+	}
+	if !equalChecks(checks, observed) {
+		t.Errorf("unexpected findings:\nexpected: %v\nobserved: %v\nerrors: %v", checks, observed, errs)
+		return false
+	}
+	return true
+}
+
+// CheckOf returns the ID of the check that reported err; syntax errors
+// are attributed to validator.CheckSyntax
+func CheckOf(err error) validator.CheckID {
+	validationError := validator.XMLValidationError{}
+	if errors.As(err, &validationError) {
+		return validationError.Check
+	}
+	syntaxError := &xml.SyntaxError{}
+	if errors.As(err, &syntaxError) {
+		return validator.CheckSyntax
+	}
+	return ""
+}
+
+func equalChecks(expected, observed []validator.CheckID) bool {
+	if len(expected) != len(observed) {
+		return false
+	}
+	for i := range expected {
+		if expected[i] != observed[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package xrvtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertRoundtripStable(t *testing.T) {
+	ft := &fakeT{}
+	require.True(t, AssertRoundtripStable(ft, `<Root></Root>`), "Should pass on valid XML documents")
+	require.Empty(t, ft.errors, "Shouldn't report errors on valid XML documents")
+
+	require.False(t, AssertRoundtripStable(ft, `<Root>]]></Root>`), "Should fail on unparseable XML documents")
+	require.Len(t, ft.errors, 1, "Should report exactly one error")
+}
+
+func TestAssertFindings(t *testing.T) {
+	ft := &fakeT{}
+	require.True(t, AssertFindings(ft, `<Root></Root>`), "Should pass when no findings are expected")
+	require.True(t, AssertFindings(ft, `<Root>]]></Root>`, validator.CheckSyntax), "Should pass when findings match")
+	require.Empty(t, ft.errors, "Shouldn't report errors when findings match")
+
+	require.False(t, AssertFindings(ft, `<Root></Root>`, validator.CheckSyntax), "Should fail when findings are missing")
+	require.False(t, AssertFindings(ft, `<Root>]]></Root>`), "Should fail on unexpected findings")
+	require.Len(t, ft.errors, 2, "Should report every mismatch")
+
+	ft = &fakeT{}
+	v := validator.New(validator.WithCheck(validator.CheckRoundtrip, validator.CheckConfig{Disabled: true}))
+	require.True(t, AssertValidatorFindings(ft, v, `<Root></Root>`), "Should validate with the given Validator")
+}
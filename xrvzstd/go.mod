@@ -0,0 +1,17 @@
+module github.com/mattermost/xml-roundtrip-validator/xrvzstd
+
+go 1.21
+
+require (
+	github.com/klauspost/compress v1.17.11
+	github.com/mattermost/xml-roundtrip-validator v1.0.0
+	github.com/stretchr/testify v1.6.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
+
+replace github.com/mattermost/xml-roundtrip-validator => ../
@@ -0,0 +1,29 @@
+// Package xrvzstd adds the zstd content coding to Validator.ValidateCompressed.
+// It is a separate module so the validator itself stays free of
+// dependencies; import it for its side effect:
+//
+//	import _ "github.com/mattermost/xml-roundtrip-validator/xrvzstd"
+package xrvzstd
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	validator "github.com/mattermost/xml-roundtrip-validator"
+)
+
+// Encoding is the name of the content coding, as used in HTTP
+const Encoding = "zstd"
+
+func init() {
+	validator.RegisterDecompressor(Encoding, Decompress)
+}
+
+// Decompress returns a reader of the decompressed content of r
+func Decompress(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder.IOReadCloser(), nil
+}